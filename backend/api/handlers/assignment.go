@@ -0,0 +1,441 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+	"zhlg/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Reliability penalties applied when a worker quits an assignment
+const (
+	quitPenaltyPoints      = 10
+	quitNoProgressPenalty  = 15
+	quitPenaltyGracePeriod = 24 * time.Hour
+	maxQuitProgressPercent = 100
+)
+
+// QuitTaskRequest represents the request body for a worker quitting a task
+type QuitTaskRequest struct {
+	Reason          string `json:"reason" binding:"required"`
+	ProgressPercent uint8  `json:"progress_percent" binding:"max=100"`
+}
+
+// SettleAssignmentRequest represents the request body for settling a quit assignment
+type SettleAssignmentRequest struct {
+	PayoutPercent *int `json:"payout_percent" binding:"omitempty,min=0,max=100"`
+}
+
+// QuitTask handles a worker dropping out of a task they were assigned to
+// @Summary Quit an assigned task
+// @Description Worker quits the task; the vacancy is refilled from the waitlist or reopened
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Task UUID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/tasks/{uuid}/quit [put]
+func QuitTask(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	taskUUID := c.Param("uuid")
+	if taskUUID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "任务ID不能为空"})
+		return
+	}
+
+	var req QuitTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	var task models.Task
+	if err := db.DB.Where("uuid = ?", taskUUID).First(&task).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+			return
+		}
+		log.Printf("[QuitTask] 查询任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务失败"})
+		return
+	}
+
+	if task.Status != models.TaskStatusInProgress {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "任务不在进行中状态，无法退出"})
+		return
+	}
+
+	var assignment models.TaskAssignment
+	if err := db.DB.Where("task_id = ? AND worker_id = ? AND worker_status = ?", task.ID, userID, models.WorkerStatusWorking).First(&assignment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的执行者或已提交工作"})
+			return
+		}
+		log.Printf("[QuitTask] 查询任务分配失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务分配失败"})
+		return
+	}
+
+	tx := services.BeginTx(db.DB)
+
+	assignment.QuitTask(req.Reason, req.ProgressPercent)
+	if err := tx.Save(&assignment).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[QuitTask] 更新任务分配失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务分配状态失败"})
+		return
+	}
+
+	var worker models.User
+	if err := tx.First(&worker, assignment.WorkerID).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[QuitTask] 查询零工失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询用户失败"})
+		return
+	}
+	worker.ApplyQuitPenalty(quitPenalty(&task, &assignment))
+	if err := tx.Model(&worker).Updates(map[string]interface{}{
+		"reliability_score": worker.ReliabilityScore,
+		"quit_count":        worker.QuitCount,
+	}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[QuitTask] 更新信用分失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新用户信用失败"})
+		return
+	}
+
+	vacancy, replacement, err := fillVacancy(tx, &task)
+	if err != nil {
+		tx.Rollback()
+		log.Printf("[QuitTask] 处理空缺失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "处理任务空缺失败"})
+		return
+	}
+
+	description := fmt.Sprintf("零工退出了任务：%s", task.Title)
+	if task.BiddingEnabled {
+		description += "，请从报价中选择替补零工"
+	}
+	services.RecordActivity(tx, worker.ID, task.EmployerID, "task_assignment", assignment.UUID, "assignment_quit",
+		description, map[string]interface{}{
+			"task_uuid":        task.UUID,
+			"reason":           req.Reason,
+			"progress_percent": req.ProgressPercent,
+			"vacancy":          vacancy,
+		})
+	if replacement != nil {
+		services.RecordActivity(tx, task.EmployerID, replacement.WorkerID, "task_assignment", replacement.UUID, "application_accepted",
+			fmt.Sprintf("您已从候补名单中递补为任务执行者：%s", task.Title), map[string]interface{}{
+				"task_uuid": task.UUID,
+			})
+	}
+
+	if err := services.CommitTx(tx); err != nil {
+		log.Printf("[QuitTask] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "退出任务失败"})
+		return
+	}
+
+	response := gin.H{
+		"message": "已退出任务",
+		"task": gin.H{
+			"uuid":   task.UUID,
+			"status": task.Status,
+		},
+		"assignment": gin.H{
+			"uuid":             assignment.UUID,
+			"worker_status":    assignment.WorkerStatus,
+			"employer_status":  assignment.EmployerStatus,
+			"progress_percent": assignment.QuitProgress,
+		},
+		"reliability_score": worker.ReliabilityScore,
+		"vacancy":           vacancy,
+	}
+	if replacement != nil {
+		response["replacement_assignment_uuid"] = replacement.UUID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// SettleQuitAssignment handles the employer paying a worker who quit for the work already done
+// @Summary Settle a quit assignment
+// @Description Employer pays the prorated amount for a worker who quit part way through
+// @Tags assignments
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Assignment UUID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/assignments/{uuid}/settle [put]
+func SettleQuitAssignment(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	assignmentUUID := c.Param("uuid")
+	if assignmentUUID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少任务分配ID"})
+		return
+	}
+
+	var req SettleAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	var assignment models.TaskAssignment
+	if err := db.DB.Preload("Task").Where("uuid = ?", assignmentUUID).First(&assignment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务分配不存在"})
+			return
+		}
+		log.Printf("[SettleQuitAssignment] 查询任务分配失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务分配失败"})
+		return
+	}
+
+	task := assignment.Task
+	if task.EmployerID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的雇主"})
+		return
+	}
+
+	if assignment.WorkerStatus != models.WorkerStatusQuit || assignment.EmployerStatus != models.EmployerStatusPaymentPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该任务分配无需结算"})
+		return
+	}
+
+	payoutPercent := int(assignment.QuitProgress)
+	if req.PayoutPercent != nil {
+		payoutPercent = *req.PayoutPercent
+	}
+	amount := fixedPayout(&task, &assignment) * float64(payoutPercent) / maxQuitProgressPercent
+
+	// 里程碑任务扣除该零工已领取的里程碑报酬
+	paidMilestones, err := paidMilestoneAmount(assignment.ID)
+	if err != nil {
+		log.Printf("[SettleQuitAssignment] 统计已付款里程碑失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "计算报酬失败"})
+		return
+	}
+	amount = math.Max(amount-paidMilestones, 0)
+
+	// 按小时/按天计费的任务直接按已批准的工时结算
+	if isTimeTracked(&task) {
+		var err error
+		if _, amount, err = timesheetPayout(db.DB, &task, &assignment); err != nil {
+			log.Printf("[SettleQuitAssignment] 计算工时报酬失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "计算报酬失败"})
+			return
+		}
+	}
+
+	tx := services.BeginTx(db.DB)
+
+	// Only the request that moves the assignment out of payment_pending pays the worker
+	now := time.Now()
+	assignment.EmployerStatus = models.EmployerStatusCompleted
+	assignment.CompletedAt = &now
+	result := tx.Model(&assignment).Where("employer_status = ?", models.EmployerStatusPaymentPending).Updates(map[string]interface{}{
+		"employer_status": assignment.EmployerStatus,
+		"completed_at":    assignment.CompletedAt,
+	})
+	if result.Error != nil {
+		tx.Rollback()
+		log.Printf("[SettleQuitAssignment] 更新任务分配失败: %v", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务分配状态失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "该任务分配已结算"})
+		return
+	}
+
+	if amount > 0 {
+		paymentTransaction := models.Transaction{
+			UUID:             uuid.New().String(),
+			UserID:           assignment.WorkerID,
+			TaskAssignmentID: &assignment.ID,
+			Type:             models.TransactionTypeEarning,
+			Amount:           amount,
+			Currency:         task.Currency,
+			Status:           models.TransactionStatusCompleted,
+			Title:            "任务部分完成报酬",
+			Description:      stringPtr(fmt.Sprintf("退出任务按 %d%% 结算：%s", payoutPercent, task.Title)),
+			ReferenceID:      &task.ID,
+			ReferenceType:    models.ReferenceTypeTask,
+			ReferenceUUID:    &task.UUID,
+			CompletedAt:      &now,
+		}
+		if err := tx.Create(&paymentTransaction).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[SettleQuitAssignment] 创建交易失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建支付记录失败"})
+			return
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", assignment.WorkerID).
+			Update("balance", gorm.Expr("balance + ?", amount)).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[SettleQuitAssignment] 更新零工余额失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新余额失败"})
+			return
+		}
+	}
+
+	services.RecordActivity(tx, task.EmployerID, assignment.WorkerID, "task_assignment", assignment.UUID, "assignment_settled",
+		fmt.Sprintf("雇主已结算您退出的任务：%s", task.Title), map[string]interface{}{
+			"task_uuid":      task.UUID,
+			"payout_percent": payoutPercent,
+			"amount":         amount,
+		})
+
+	if err := services.CommitTx(tx); err != nil {
+		log.Printf("[SettleQuitAssignment] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "结算失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "结算完成",
+		"assignment": gin.H{
+			"uuid":            assignment.UUID,
+			"employer_status": assignment.EmployerStatus,
+			"payout_percent":  payoutPercent,
+			"amount":          amount,
+		},
+	})
+}
+
+// quitPenalty works out how many reliability points a quit costs. Dropping out close to
+// the start date or without having done any work hurts the employer most.
+func quitPenalty(task *models.Task, assignment *models.TaskAssignment) int {
+	if assignment.QuitProgress == 0 && time.Until(task.StartDate) < quitPenaltyGracePeriod {
+		return quitNoProgressPenalty
+	}
+	return quitPenaltyPoints
+}
+
+// applicationContract works out the contract a worker is hired on: the task's default
+// terms, with an accepted bid replacing the total on fixed-price tasks or the rate otherwise
+func applicationContract(task *models.Task, application *models.TaskApplication) (*float64, *float64) {
+	amount, rate := task.DefaultContract()
+	if application.BidAmount != nil {
+		bid := *application.BidAmount
+		if isTimeTracked(task) {
+			rate = &bid
+		} else {
+			amount = &bid
+		}
+	}
+	return amount, rate
+}
+
+// assignmentContract returns the contracted amount and rate of an assignment, falling back
+// to the task's default terms for assignments that have none
+func assignmentContract(task *models.Task, assignment *models.TaskAssignment) (*float64, *float64) {
+	if assignment.ContractedAmount == nil && assignment.ContractedRate == nil {
+		return task.DefaultContract()
+	}
+	return assignment.ContractedAmount, assignment.ContractedRate
+}
+
+// fixedPayout returns the total owed to a worker on a fixed-price task under their contract
+func fixedPayout(task *models.Task, assignment *models.TaskAssignment) float64 {
+	amount, _ := assignmentContract(task, assignment)
+	if amount == nil {
+		return 0
+	}
+	return *amount
+}
+
+// countActiveAssignments counts workers still occupying a slot on the task
+func countActiveAssignments(tx *gorm.DB, taskID uint) (int64, error) {
+	var count int64
+	err := tx.Model(&models.TaskAssignment{}).
+		Where("task_id = ? AND worker_status IN ?", taskID, []models.WorkerStatus{models.WorkerStatusWorking, models.WorkerStatusSubmitted}).
+		Count(&count).Error
+	return count, err
+}
+
+// assignApplication accepts a pending application and creates the matching assignment on
+// the given contract
+func assignApplication(tx *gorm.DB, application *models.TaskApplication, amount, rate *float64) (*models.TaskAssignment, error) {
+	application.Accept()
+	if err := tx.Save(application).Error; err != nil {
+		return nil, err
+	}
+
+	assignment := models.TaskAssignment{
+		UUID:              uuid.New().String(),
+		TaskApplicationID: &application.ID,
+		TaskID:            application.TaskID,
+		WorkerID:          application.WorkerID,
+		AssignedAt:        time.Now(),
+		WorkerStatus:      models.WorkerStatusWorking,
+		EmployerStatus:    models.EmployerStatusInProgress,
+		ContractedAmount:  amount,
+		ContractedRate:    rate,
+	}
+	if err := tx.Create(&assignment).Error; err != nil {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+// fillVacancy is called after a worker leaves a task. The oldest pending application acts
+// as the waitlist and is promoted first; if nobody is waiting and no one else is working on
+// the task, it goes back to recruiting. Bidding tasks never promote a bid the employer has
+// not accepted, the slot is left open for them instead. The returned string describes what
+// happened.
+func fillVacancy(tx *gorm.DB, task *models.Task) (string, *models.TaskAssignment, error) {
+	if !task.BiddingEnabled {
+		var next models.TaskApplication
+		err := tx.Where("task_id = ? AND status = ?", task.ID, models.ApplicationStatusPending).
+			Order("applied_at ASC").
+			First(&next).Error
+		if err == nil {
+			amount, rate := applicationContract(task, &next)
+			replacement, err := assignApplication(tx, &next, amount, rate)
+			if err != nil {
+				return "", nil, err
+			}
+			return "promoted", replacement, nil
+		}
+		if err != gorm.ErrRecordNotFound {
+			return "", nil, err
+		}
+	}
+
+	active, err := countActiveAssignments(tx, task.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	if active > 0 {
+		return "open", nil, nil
+	}
+
+	task.Status = models.TaskStatusRecruiting
+	if err := tx.Save(task).Error; err != nil {
+		return "", nil, err
+	}
+	return "reopened", nil, nil
+}
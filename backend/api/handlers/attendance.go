@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+	"zhlg/backend/services"
+	"zhlg/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+)
+
+// mysqlDuplicateEntry is the MySQL error number for a unique key violation
+const mysqlDuplicateEntry = 1062
+
+// isDuplicateKey reports whether err is a unique key violation
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}
+
+// defaultCheckInRadius is the geofence radius in meters used when neither the task nor the
+// CHECKIN_RADIUS_METERS environment variable sets one
+const defaultCheckInRadius = 500
+
+// AttendanceLocationRequest represents the device location sent with a check-in or check-out
+type AttendanceLocationRequest struct {
+	Latitude  *float64 `json:"latitude" binding:"required,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"required,min=-180,max=180"`
+}
+
+// checkInRadius returns the geofence radius in meters that applies to the task
+func checkInRadius(task *models.Task) float64 {
+	if task.CheckInRadius > 0 {
+		return float64(task.CheckInRadius)
+	}
+	return float64(utils.EnvInt("CHECKIN_RADIUS_METERS", defaultCheckInRadius))
+}
+
+// distanceToTask returns the distance in meters from the given point to the task site,
+// rounded to centimeters for storage
+func distanceToTask(task *models.Task, lat, lng float64) float64 {
+	d := utils.DistanceMeters(*task.Latitude, *task.Longitude, lat, lng)
+	return math.Round(d*100) / 100
+}
+
+// workDate truncates a time to the local calendar day used for attendance records
+func workDate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// formatAttendanceRecord converts an attendance record into its API representation
+func formatAttendanceRecord(a models.AttendanceRecord) gin.H {
+	item := gin.H{
+		"uuid":                   a.UUID,
+		"work_date":              a.WorkDate.Format("2006-01-02"),
+		"status":                 a.Status,
+		"check_in_at":            nil,
+		"check_in_distance":      a.CheckInDistance,
+		"check_out_at":           nil,
+		"check_out_distance":     a.CheckOutDistance,
+		"check_out_within_fence": a.CheckOutWithinFence,
+	}
+	if a.CheckInAt != nil {
+		item["check_in_at"] = a.CheckInAt.Format(time.RFC3339)
+	}
+	if a.CheckOutAt != nil {
+		item["check_out_at"] = a.CheckOutAt.Format(time.RFC3339)
+	}
+	return item
+}
+
+// CheckIn handles a worker checking in on site for an offline task
+// @Summary Check in on site
+// @Description Worker checks in with device GPS; the location must be within the task's geofence
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Task UUID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/tasks/{uuid}/check-in [post]
+func CheckIn(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req AttendanceLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	task, assignment, ok := loadWorkingAssignment(c, userID)
+	if !ok {
+		return
+	}
+
+	if !task.IsGeofenced() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该任务未设置现场坐标，无需签到"})
+		return
+	}
+
+	now := time.Now()
+	if now.Before(workDate(task.StartDate)) || now.After(workDate(task.EndDate).AddDate(0, 0, 1)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不在任务工作日期内"})
+		return
+	}
+
+	radius := checkInRadius(task)
+	distance := distanceToTask(task, *req.Latitude, *req.Longitude)
+	if distance > radius {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    fmt.Sprintf("您距离工作地点%.0f米，超出签到范围（%.0f米）", distance, radius),
+			"distance": distance,
+			"radius":   radius,
+		})
+		return
+	}
+
+	today := workDate(now)
+	var record models.AttendanceRecord
+	found := db.DB.Where("task_assignment_id = ? AND work_date = ?", assignment.ID, today).First(&record).Error == nil
+	if found && record.Status != models.AttendanceStatusNoShow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "您今天已经签到过了"})
+		return
+	}
+
+	tx := services.BeginTx(db.DB)
+
+	// 按小时或按天计费的任务，签到即开始计工时
+	var entry *models.TimesheetEntry
+	if isTimeTracked(task) {
+		entry = &models.TimesheetEntry{
+			UUID:             uuid.New().String(),
+			TaskID:           task.ID,
+			TaskAssignmentID: assignment.ID,
+			WorkerID:         assignment.WorkerID,
+			Source:           models.TimesheetSourceAttendance,
+			StartedAt:        now,
+			Status:           models.TimesheetStatusOpen,
+		}
+		if err := tx.Create(entry).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[CheckIn] 创建工时记录失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "签到失败"})
+			return
+		}
+	}
+
+	record.Status = models.AttendanceStatusCheckedIn
+	record.CheckInAt = &now
+	record.CheckInLatitude = req.Latitude
+	record.CheckInLongitude = req.Longitude
+	record.CheckInDistance = &distance
+	if entry != nil {
+		record.TimesheetEntryID = &entry.ID
+	}
+
+	// 已被标记缺勤的迟到签到，更新原记录；同一天只有一次签到能生效
+	if found {
+		result := tx.Model(&record).Where("status = ?", models.AttendanceStatusNoShow).Updates(map[string]interface{}{
+			"status":             record.Status,
+			"check_in_at":        record.CheckInAt,
+			"check_in_latitude":  record.CheckInLatitude,
+			"check_in_longitude": record.CheckInLongitude,
+			"check_in_distance":  record.CheckInDistance,
+			"timesheet_entry_id": record.TimesheetEntryID,
+		})
+		if result.Error != nil {
+			tx.Rollback()
+			log.Printf("[CheckIn] 更新考勤记录失败: %v", result.Error)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "签到失败"})
+			return
+		}
+		if result.RowsAffected == 0 {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "您今天已经签到过了"})
+			return
+		}
+	} else {
+		record.UUID = uuid.New().String()
+		record.TaskID = task.ID
+		record.TaskAssignmentID = assignment.ID
+		record.WorkerID = assignment.WorkerID
+		record.WorkDate = today
+		if err := tx.Create(&record).Error; err != nil {
+			tx.Rollback()
+			if isDuplicateKey(err) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "您今天已经签到过了"})
+				return
+			}
+			log.Printf("[CheckIn] 创建考勤记录失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "签到失败"})
+			return
+		}
+	}
+
+	if err := services.CommitTx(tx); err != nil {
+		log.Printf("[CheckIn] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "签到失败"})
+		return
+	}
+
+	response := gin.H{
+		"message":    "签到成功",
+		"attendance": formatAttendanceRecord(record),
+	}
+	if entry != nil {
+		response["entry"] = formatTimesheetEntry(*entry)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CheckOut handles a worker checking out of an offline task. Checking out away from the site
+// is allowed but flagged so the employer can take it into account when approving hours.
+// @Summary Check out on site
+// @Description Worker checks out with device GPS and closes the attendance timesheet entry
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Task UUID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/tasks/{uuid}/check-out [post]
+func CheckOut(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req AttendanceLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	// 其他零工已提交时任务进入待付款，仍允许签退
+	task, assignment, ok := loadWorkingAssignment(c, userID, models.TaskStatusPaymentPending)
+	if !ok {
+		return
+	}
+
+	if !task.IsGeofenced() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该任务未设置现场坐标，无需签退"})
+		return
+	}
+
+	var record models.AttendanceRecord
+	if err := db.DB.Where("task_assignment_id = ? AND status = ?", assignment.ID, models.AttendanceStatusCheckedIn).
+		Order("work_date DESC").First(&record).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "您尚未签到"})
+		return
+	}
+
+	distance := distanceToTask(task, *req.Latitude, *req.Longitude)
+	withinFence := distance <= checkInRadius(task)
+
+	tx := services.BeginTx(db.DB)
+
+	record.CheckOut(*req.Latitude, *req.Longitude, distance, withinFence)
+	if err := tx.Model(&record).Updates(map[string]interface{}{
+		"status":                 record.Status,
+		"check_out_at":           record.CheckOutAt,
+		"check_out_latitude":     record.CheckOutLatitude,
+		"check_out_longitude":    record.CheckOutLongitude,
+		"check_out_distance":     record.CheckOutDistance,
+		"check_out_within_fence": record.CheckOutWithinFence,
+	}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[CheckOut] 更新考勤记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "签退失败"})
+		return
+	}
+
+	var entry *models.TimesheetEntry
+	if record.TimesheetEntryID != nil {
+		var e models.TimesheetEntry
+		if err := tx.Where("id = ? AND status = ?", *record.TimesheetEntryID, models.TimesheetStatusOpen).First(&e).Error; err == nil {
+			end := *record.CheckOutAt
+			if end.Sub(e.StartedAt).Hours() > maxTimesheetEntryHours {
+				end = e.StartedAt.Add(maxTimesheetEntryHours * time.Hour)
+			}
+			e.ClockOut(end)
+			if !withinFence {
+				note := fmt.Sprintf("签退位置距离工作地点%.0f米，超出范围", distance)
+				e.Description = &note
+			}
+			if err := tx.Model(&e).Updates(map[string]interface{}{
+				"ended_at":    e.EndedAt,
+				"hours":       e.Hours,
+				"status":      e.Status,
+				"description": e.Description,
+			}).Error; err != nil {
+				tx.Rollback()
+				log.Printf("[CheckOut] 更新工时记录失败: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "签退失败"})
+				return
+			}
+			entry = &e
+		}
+	}
+
+	if !withinFence {
+		services.RecordActivity(tx, assignment.WorkerID, task.EmployerID, "attendance_record", record.UUID, "check_out_outside_fence",
+			fmt.Sprintf("零工在工作地点范围外签退：%s", task.Title), map[string]interface{}{
+				"task_uuid": task.UUID,
+				"distance":  distance,
+			})
+	}
+
+	if err := services.CommitTx(tx); err != nil {
+		log.Printf("[CheckOut] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "签退失败"})
+		return
+	}
+
+	message := "签退成功"
+	if !withinFence {
+		message = "签退成功，但您不在工作地点范围内，雇主审批工时时会看到此记录"
+	}
+	response := gin.H{
+		"message":    message,
+		"attendance": formatAttendanceRecord(record),
+	}
+	if entry != nil {
+		response["entry"] = formatTimesheetEntry(*entry)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetTaskAttendance handles listing attendance records of a task. The employer sees every
+// worker's records, an assigned worker only sees their own.
+func GetTaskAttendance(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var task models.Task
+	if err := db.DB.Where("uuid = ?", c.Param("uuid")).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	if !canViewTaskWork(&task, userID.(uint)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该任务的考勤"})
+		return
+	}
+
+	var records []models.AttendanceRecord
+	query := db.DB.Preload("Worker").Preload("TaskAssignment").Where("task_id = ?", task.ID)
+	if task.EmployerID != userID.(uint) {
+		query = query.Where("worker_id = ?", userID)
+	}
+	if err := query.Order("work_date DESC, id DESC").Find(&records).Error; err != nil {
+		log.Printf("[GetTaskAttendance] 查询考勤记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询考勤记录失败"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(records))
+	noShows := 0
+	for _, r := range records {
+		if r.Status == models.AttendanceStatusNoShow {
+			noShows++
+		}
+		item := formatAttendanceRecord(r)
+		item["assignment_uuid"] = r.TaskAssignment.UUID
+		item["worker"] = gin.H{
+			"uuid":       r.Worker.UUID,
+			"name":       r.Worker.Name,
+			"avatar_url": r.Worker.AvatarURL,
+		}
+		items = append(items, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"latitude":        task.Latitude,
+			"longitude":       task.Longitude,
+			"check_in_radius": checkInRadius(&task),
+			"records":         items,
+			"no_show_count":   noShows,
+		},
+	})
+}
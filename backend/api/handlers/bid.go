@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bidSortOrders maps the sort options of the bid comparison to their ORDER BY clauses
+var bidSortOrders = map[string]string{
+	"amount":      "task_applications.bid_amount ASC, task_applications.applied_at ASC",
+	"days":        "task_applications.bid_days IS NULL, task_applications.bid_days ASC, task_applications.bid_amount ASC",
+	"reliability": "users.reliability_score DESC, task_applications.bid_amount ASC",
+	"applied_at":  "task_applications.applied_at ASC",
+}
+
+// GetTaskBids handles listing the bids on one of the employer's bidding tasks side by side,
+// with the lowest, highest and average quote. Only pending bids are listed unless status
+// is given; sort is one of amount (default), days, reliability and applied_at.
+func GetTaskBids(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var task models.Task
+	if err := db.DB.Where("uuid = ?", c.Param("uuid")).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	if task.EmployerID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的发布者"})
+		return
+	}
+	if !task.BiddingEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该任务不是报价任务"})
+		return
+	}
+
+	order, ok := bidSortOrders[c.DefaultQuery("sort", "amount")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的排序方式"})
+		return
+	}
+	status := c.DefaultQuery("status", string(models.ApplicationStatusPending))
+
+	var applications []models.TaskApplication
+	if err := db.DB.Preload("Worker").
+		Joins("JOIN users ON users.id = task_applications.worker_id").
+		Where("task_applications.task_id = ? AND task_applications.status = ? AND task_applications.bid_amount IS NOT NULL", task.ID, status).
+		Order(order).Find(&applications).Error; err != nil {
+		log.Printf("[GetTaskBids] 查询报价失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询报价失败"})
+		return
+	}
+
+	bids := make([]gin.H, 0, len(applications))
+	var lowest, highest, total float64
+	for i, app := range applications {
+		amount := *app.BidAmount
+		if i == 0 || amount < lowest {
+			lowest = amount
+		}
+		if amount > highest {
+			highest = amount
+		}
+		total += amount
+
+		bids = append(bids, gin.H{
+			"application_uuid": app.UUID,
+			"worker": gin.H{
+				"uuid":              app.Worker.UUID,
+				"name":              app.Worker.Name,
+				"avatar_url":        app.Worker.AvatarURL,
+				"reliability_score": app.Worker.ReliabilityScore,
+			},
+			"bid_amount":        amount,
+			"bid_days":          app.BidDays,
+			"budget_difference": math.Round((amount-task.BudgetAmount)*100) / 100,
+			"cover_letter":      app.CoverLetter,
+			"status":            app.Status,
+			"applied_at":        app.AppliedAt.Format(time.RFC3339),
+		})
+	}
+
+	summary := gin.H{
+		"count":         len(bids),
+		"budget_amount": task.BudgetAmount,
+		"payment_type":  task.PaymentType,
+		"lowest":        nil,
+		"highest":       nil,
+		"average":       nil,
+	}
+	if len(bids) > 0 {
+		summary["lowest"] = lowest
+		summary["highest"] = highest
+		summary["average"] = math.Round(total/float64(len(bids))*100) / 100
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"summary": summary,
+		"bids":    bids,
+	})
+}
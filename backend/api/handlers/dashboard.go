@@ -266,14 +266,28 @@ func GetDashboardData(c *gin.Context) {
 		})
 	}
 
+	// 未读消息数
+	unreadMessages, err := unreadMessageCount(userID.(uint))
+	if err != nil {
+		log.Printf("[GetDashboardData] 查询未读消息失败: %v", err)
+	}
+
+	// 未读通知数
+	unreadNotifications, err := unreadNotificationCount(userID.(uint))
+	if err != nil {
+		log.Printf("[GetDashboardData] 查询未读通知失败: %v", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"activeTasks":   activeTasks,
-		"monthlyIncome": monthlyIncome,
-		"workHours":     workHours,
-		"rating":        rating,
-		"reviewCount":   reviewCount,
-		"recentTasks":   formattedTasks,
-		"activities":    formattedActivities,
+		"activeTasks":         activeTasks,
+		"monthlyIncome":       monthlyIncome,
+		"workHours":           workHours,
+		"rating":              rating,
+		"reviewCount":         reviewCount,
+		"unreadMessages":      unreadMessages,
+		"unreadNotifications": unreadNotifications,
+		"recentTasks":         formattedTasks,
+		"activities":          formattedActivities,
 	})
 }
 
@@ -0,0 +1,526 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+	"zhlg/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// maxDraftsPerEmployer caps how many drafts one employer can keep
+const maxDraftsPerEmployer = 50
+
+// maxScheduleAheadDays is how far ahead a draft can be scheduled for publishing
+const maxScheduleAheadDays = 90
+
+// ScheduleTaskRequest represents the request body for scheduling a draft to publish
+type ScheduleTaskRequest struct {
+	PublishAt string `json:"publish_at" binding:"required"`
+}
+
+// bindDraftRequest reads a create request without enforcing required fields, which are
+// only checked when the draft is published
+func bindDraftRequest(c *gin.Context) (*CreateTaskRequest, error) {
+	data, err := c.GetRawData()
+	if err != nil {
+		return nil, err
+	}
+	var req CreateTaskRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+	if len(req.Title) > 255 {
+		return nil, fmt.Errorf("标题不能超过255个字符")
+	}
+	return &req, nil
+}
+
+// draftMissingFields lists the required fields a draft has not filled in yet
+func draftMissingFields(req *CreateTaskRequest) []string {
+	missing := make([]string, 0)
+	if strings.TrimSpace(req.Title) == "" {
+		missing = append(missing, "title")
+	}
+	if strings.TrimSpace(req.Description) == "" {
+		missing = append(missing, "description")
+	}
+	if req.LocationType == "" {
+		missing = append(missing, "location_type")
+	}
+	if req.StartDate == "" {
+		missing = append(missing, "start_date")
+	}
+	if req.EndDate == "" {
+		missing = append(missing, "end_date")
+	}
+	if req.PaymentType == "" {
+		missing = append(missing, "payment_type")
+	}
+	if req.BudgetAmount <= 0 {
+		missing = append(missing, "budget_amount")
+	}
+	if req.Headcount <= 0 {
+		missing = append(missing, "headcount")
+	}
+	if len(req.Skills) == 0 {
+		missing = append(missing, "skills")
+	}
+	return missing
+}
+
+// applyDraftRequest copies whatever the draft has filled in onto the task. Fields that are
+// missing or invalid get placeholders so the row can be stored; the request itself is
+// kept in DraftData and validated at publish.
+func applyDraftRequest(task *models.Task, req *CreateTaskRequest) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	task.DraftData = datatypes.JSON(data)
+
+	task.Title = req.Title
+	task.Description = req.Description
+	task.LocationType = models.LocationTypeOnline
+	if req.LocationType == string(models.LocationTypeOffline) {
+		task.LocationType = models.LocationTypeOffline
+	}
+	task.LocationDetails = nil
+	if task.LocationType == models.LocationTypeOffline && req.LocationDetails != "" {
+		locationDetails := req.LocationDetails
+		task.LocationDetails = &locationDetails
+	}
+
+	today := time.Now().Truncate(24 * time.Hour)
+	task.StartDate, task.EndDate = today, today
+	if start, err := time.Parse("2006-01-02", req.StartDate); err == nil {
+		task.StartDate, task.EndDate = start, start
+	}
+	if end, err := time.Parse("2006-01-02", req.EndDate); err == nil && !end.Before(task.StartDate) {
+		task.EndDate = end
+	}
+
+	task.PaymentType = models.PaymentTypeFixed
+	switch models.PaymentType(req.PaymentType) {
+	case models.PaymentTypeHourly, models.PaymentTypeDaily:
+		task.PaymentType = models.PaymentType(req.PaymentType)
+	}
+	task.BudgetAmount = 0
+	if req.BudgetAmount > 0 {
+		task.BudgetAmount = req.BudgetAmount
+	}
+	task.Headcount = 1
+	if req.Headcount > 0 {
+		task.Headcount = uint(req.Headcount)
+	}
+	task.EstimatedHours = 0
+	if req.EstimatedHours > 0 {
+		task.EstimatedHours = req.EstimatedHours
+	}
+	task.BudgetCeiling = req.BudgetCeiling
+	task.IsPublic = req.IsPublic
+	task.IsUrgent = req.IsUrgent
+	task.BiddingEnabled = req.BiddingEnabled
+	return nil
+}
+
+// draftRequest returns the contents of a draft as a create request. Complete drafts, such
+// as clones, are rebuilt from the task, which must have its Skills, Milestones and
+// ScreeningQuestions loaded.
+func draftRequest(task *models.Task) CreateTaskRequest {
+	var req CreateTaskRequest
+	if len(task.DraftData) > 0 {
+		if err := json.Unmarshal(task.DraftData, &req); err != nil {
+			log.Printf("[draftRequest] 解析草稿失败: task=%s, err=%v", task.UUID, err)
+		}
+		return req
+	}
+
+	req = CreateTaskRequest{
+		Title:          task.Title,
+		Description:    task.Description,
+		LocationType:   string(task.LocationType),
+		Latitude:       task.Latitude,
+		Longitude:      task.Longitude,
+		CheckInRadius:  task.CheckInRadius,
+		StartDate:      task.StartDate.Format("2006-01-02"),
+		EndDate:        task.EndDate.Format("2006-01-02"),
+		PaymentType:    string(task.PaymentType),
+		BudgetAmount:   task.BudgetAmount,
+		EstimatedHours: task.EstimatedHours,
+		Headcount:      int(task.Headcount),
+		BudgetCeiling:  task.BudgetCeiling,
+		IsPublic:       task.IsPublic,
+		IsUrgent:       task.IsUrgent,
+		BiddingEnabled: task.BiddingEnabled,
+	}
+	if task.LocationDetails != nil {
+		req.LocationDetails = *task.LocationDetails
+	}
+	if task.RecruitingDeadline != nil {
+		req.RecruitingDeadline = task.RecruitingDeadline.Format(time.RFC3339)
+	}
+	for _, s := range task.Skills {
+		req.Skills = append(req.Skills, s.Name)
+	}
+	if len(task.ScreeningQuestions) > 0 {
+		req.ScreeningQuestions = screeningQuestionRequests(task.ScreeningQuestions)
+	}
+	for _, m := range task.Milestones {
+		milestone := MilestoneRequest{Title: m.Title, Amount: m.Amount}
+		if m.Description != nil {
+			milestone.Description = *m.Description
+		}
+		if m.DueDate != nil {
+			milestone.DueDate = m.DueDate.Format("2006-01-02")
+		}
+		req.Milestones = append(req.Milestones, milestone)
+	}
+	return req
+}
+
+// formatDraft converts a draft into its API representation with the fields still missing
+func formatDraft(task *models.Task) gin.H {
+	req := draftRequest(task)
+	item := gin.H{
+		"uuid":                 task.UUID,
+		"title":                task.Title,
+		"status":               task.Status,
+		"draft":                req,
+		"missing_fields":       draftMissingFields(&req),
+		"scheduled_publish_at": nil,
+		"created_at":           task.CreatedAt.Format(time.RFC3339),
+		"updated_at":           task.UpdatedAt.Format(time.RFC3339),
+	}
+	if task.ScheduledPublishAt != nil {
+		item["scheduled_publish_at"] = task.ScheduledPublishAt.Format(time.RFC3339)
+	}
+	return item
+}
+
+// loadOwnDraft finds one of the current employer's drafts, writing the error response when
+// it does not exist, belongs to someone else or is already published
+func loadOwnDraft(c *gin.Context, employerID uint) (*models.Task, bool) {
+	var task models.Task
+	if err := db.DB.Preload("Skills").Preload("Milestones").Preload("ScreeningQuestions").Where("uuid = ?", c.Param("uuid")).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return nil, false
+	}
+	if task.EmployerID != employerID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的发布者"})
+		return nil, false
+	}
+	if task.Status != models.TaskStatusDraft {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "只有草稿可以修改"})
+		return nil, false
+	}
+	return &task, true
+}
+
+// finalizeDraft validates a draft saved with DraftData like a new task and replaces the
+// task's fields, milestones, skills and screening questions with it. The task stays a draft. It writes the
+// error response and returns false when the draft is incomplete or invalid.
+func finalizeDraft(c *gin.Context, task *models.Task) bool {
+	if len(task.DraftData) == 0 {
+		return true
+	}
+
+	var req CreateTaskRequest
+	if err := json.Unmarshal(task.DraftData, &req); err != nil {
+		log.Printf("[finalizeDraft] 解析草稿失败: task=%s, err=%v", task.UUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解析草稿失败"})
+		return false
+	}
+	if missing := draftMissingFields(&req); len(missing) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "草稿信息不完整", "missing_fields": missing})
+		return false
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return false
+	}
+	built, milestones, err := buildTask(task.EmployerID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return false
+	}
+	skills, err := services.ResolveSkills(db.DB, req.Skills)
+	if err != nil {
+		log.Printf("[finalizeDraft] 处理技能失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存草稿失败"})
+		return false
+	}
+
+	built.ID = task.ID
+	built.UUID = task.UUID
+	built.Status = models.TaskStatusDraft
+	built.PublishedAt = nil
+	built.ScheduledPublishAt = task.ScheduledPublishAt
+	built.RecurrenceID = task.RecurrenceID
+	built.Currency = task.Currency
+	built.DraftData = nil
+	built.CreatedAt = task.CreatedAt
+
+	tx := db.DB.Begin()
+
+	if err := tx.Omit("Skills", "Milestones", "ScreeningQuestions").Save(&built).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[finalizeDraft] 更新任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存草稿失败"})
+		return false
+	}
+	if err := tx.Where("task_id = ?", task.ID).Delete(&models.TaskMilestone{}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[finalizeDraft] 删除旧里程碑失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存草稿失败"})
+		return false
+	}
+	if len(milestones) > 0 {
+		for i := range milestones {
+			milestones[i].TaskID = task.ID
+		}
+		if err := tx.Create(&milestones).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[finalizeDraft] 创建里程碑失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存草稿失败"})
+			return false
+		}
+	}
+	if err := tx.Where("task_id = ?", task.ID).Delete(&models.TaskScreeningQuestion{}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[finalizeDraft] 删除旧筛选问题失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存草稿失败"})
+		return false
+	}
+	if len(built.ScreeningQuestions) > 0 {
+		for i := range built.ScreeningQuestions {
+			built.ScreeningQuestions[i].TaskID = task.ID
+		}
+		if err := tx.Create(&built.ScreeningQuestions).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[finalizeDraft] 创建筛选问题失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存草稿失败"})
+			return false
+		}
+	}
+	if err := tx.Model(&built).Association("Skills").Replace(&skills); err != nil {
+		tx.Rollback()
+		log.Printf("[finalizeDraft] 更新任务技能失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存草稿失败"})
+		return false
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("[finalizeDraft] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存草稿失败"})
+		return false
+	}
+
+	built.Skills = skills
+	built.Milestones = milestones
+	*task = built
+	return true
+}
+
+// GetTaskDrafts handles listing the current employer's drafts, most recently edited first
+func GetTaskDrafts(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var tasks []models.Task
+	if err := db.DB.Preload("Skills").Preload("Milestones").Preload("ScreeningQuestions").
+		Where("employer_id = ? AND status = ?", userID, models.TaskStatusDraft).
+		Order("updated_at DESC").Find(&tasks).Error; err != nil {
+		log.Printf("[GetTaskDrafts] 查询草稿失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询草稿失败"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(tasks))
+	for i := range tasks {
+		items = append(items, formatDraft(&tasks[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"drafts":  items,
+	})
+}
+
+// CreateTaskDraft handles saving a task as a draft. Any subset of the create task fields
+// may be given; required fields are only enforced when the draft is published.
+func CreateTaskDraft(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	req, err := bindDraftRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	var count int64
+	db.DB.Model(&models.Task{}).Where("employer_id = ? AND status = ?", userID, models.TaskStatusDraft).Count(&count)
+	if count >= maxDraftsPerEmployer {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("最多保存%d个草稿", maxDraftsPerEmployer)})
+		return
+	}
+
+	task := models.Task{
+		UUID:       uuid.New().String(),
+		EmployerID: userID.(uint),
+		Status:     models.TaskStatusDraft,
+	}
+	if err := applyDraftRequest(&task, req); err != nil {
+		log.Printf("[CreateTaskDraft] 序列化草稿失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存草稿失败"})
+		return
+	}
+	if err := db.DB.Create(&task).Error; err != nil {
+		log.Printf("[CreateTaskDraft] 保存草稿失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存草稿失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "草稿已保存",
+		"draft":   formatDraft(&task),
+	})
+}
+
+// UpdateTaskDraft handles replacing the contents of a draft. A scheduled publish is
+// cancelled because the new contents have not been validated.
+func UpdateTaskDraft(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	task, ok := loadOwnDraft(c, userID.(uint))
+	if !ok {
+		return
+	}
+	req, err := bindDraftRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	if err := applyDraftRequest(task, req); err != nil {
+		log.Printf("[UpdateTaskDraft] 序列化草稿失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存草稿失败"})
+		return
+	}
+	task.ScheduledPublishAt = nil
+	if err := db.DB.Omit("Skills", "Milestones", "ScreeningQuestions").Save(task).Error; err != nil {
+		log.Printf("[UpdateTaskDraft] 保存草稿失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存草稿失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "草稿已保存",
+		"draft":   formatDraft(task),
+	})
+}
+
+// ScheduleTaskPublish handles scheduling a draft to be published automatically. The draft
+// is validated now so that the scheduled publish does not fail on missing fields.
+func ScheduleTaskPublish(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	task, ok := loadOwnDraft(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	var req ScheduleTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+	publishAt, err := time.Parse(time.RFC3339, req.PublishAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "发布时间格式不正确"})
+		return
+	}
+	now := time.Now()
+	if !publishAt.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "发布时间必须晚于当前时间"})
+		return
+	}
+	if publishAt.After(now.AddDate(0, 0, maxScheduleAheadDays)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("最多只能提前%d天安排发布", maxScheduleAheadDays)})
+		return
+	}
+
+	if !finalizeDraft(c, task) {
+		return
+	}
+	if !publishAt.Before(task.StartDate.AddDate(0, 0, 1)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "发布时间不能晚于任务开始日期"})
+		return
+	}
+
+	task.ScheduledPublishAt = &publishAt
+	if err := db.DB.Model(task).Update("scheduled_publish_at", publishAt).Error; err != nil {
+		log.Printf("[ScheduleTaskPublish] 保存定时发布失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存定时发布失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已安排定时发布",
+		"draft":   formatDraft(task),
+	})
+}
+
+// CancelScheduledPublish handles cancelling a draft's scheduled publish
+func CancelScheduledPublish(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	task, ok := loadOwnDraft(c, userID.(uint))
+	if !ok {
+		return
+	}
+	if task.ScheduledPublishAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该草稿没有安排定时发布"})
+		return
+	}
+
+	if err := db.DB.Model(task).Update("scheduled_publish_at", nil).Error; err != nil {
+		log.Printf("[CancelScheduledPublish] 取消定时发布失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "取消定时发布失败"})
+		return
+	}
+	task.ScheduledPublishAt = nil
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已取消定时发布",
+		"draft":   formatDraft(task),
+	})
+}
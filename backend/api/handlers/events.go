@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"zhlg/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventStreamHeartbeat is how often an idle event stream sends a ping so proxies keep the
+// connection open
+const eventStreamHeartbeat = 25 * time.Second
+
+// StreamEvents handles the current user's real-time event stream over Server-Sent Events.
+// Every event recorded for the user, such as a new application, an accepted application,
+// a submission, a payment or a message, is pushed as it happens; the event name is the
+// activity type. Browsers authenticate with the auth_token cookie since EventSource
+// cannot send headers.
+func StreamEvents(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	events, unsubscribe := services.SubscribePush(userID.(uint))
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.SSEvent("ready", gin.H{"connected_at": time.Now().Format(time.RFC3339)})
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case e, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(e.Type, e)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", time.Now().Unix())
+			return true
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// FavoriteQueryParams represents the query parameters for listing favorite tasks
+type FavoriteQueryParams struct {
+	Page  int `form:"page,default=1"`
+	Limit int `form:"limit,default=10"`
+}
+
+// favoritedTaskIDs returns which of the given tasks the user has favorited
+func favoritedTaskIDs(userID uint, taskIDs []uint) map[uint]bool {
+	favorited := make(map[uint]bool)
+	if len(taskIDs) == 0 {
+		return favorited
+	}
+	var ids []uint
+	if err := db.DB.Model(&models.UserFavorite{}).Where("user_id = ? AND task_id IN ?", userID, taskIDs).
+		Pluck("task_id", &ids).Error; err != nil {
+		log.Printf("[favoritedTaskIDs] 查询收藏失败: %v", err)
+		return favorited
+	}
+	for _, id := range ids {
+		favorited[id] = true
+	}
+	return favorited
+}
+
+// taskFavoriteCounts returns how many users favorited each of the given tasks
+func taskFavoriteCounts(taskIDs []uint) map[uint]int64 {
+	counts := make(map[uint]int64)
+	if len(taskIDs) == 0 {
+		return counts
+	}
+	var rows []struct {
+		TaskID uint
+		Count  int64
+	}
+	if err := db.DB.Model(&models.UserFavorite{}).Select("task_id, COUNT(*) AS count").
+		Where("task_id IN ?", taskIDs).Group("task_id").Scan(&rows).Error; err != nil {
+		log.Printf("[taskFavoriteCounts] 统计收藏数失败: %v", err)
+		return counts
+	}
+	for _, r := range rows {
+		counts[r.TaskID] = r.Count
+	}
+	return counts
+}
+
+// FavoriteTask handles adding a task to the current user's favorites. Favoriting a task
+// twice is not an error.
+func FavoriteTask(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var task models.Task
+	if err := db.DB.Where("uuid = ? AND status <> ?", c.Param("uuid"), models.TaskStatusDraft).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	if task.EmployerID == userID.(uint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能收藏自己发布的任务"})
+		return
+	}
+
+	favorite := models.UserFavorite{UserID: userID.(uint), TaskID: task.ID}
+	if err := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&favorite).Error; err != nil {
+		log.Printf("[FavoriteTask] 收藏任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "收藏失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "收藏成功",
+		"is_favorited": true,
+	})
+}
+
+// UnfavoriteTask handles removing a task from the current user's favorites
+func UnfavoriteTask(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var task models.Task
+	if err := db.DB.Where("uuid = ?", c.Param("uuid")).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	if err := db.DB.Where("user_id = ? AND task_id = ?", userID, task.ID).Delete(&models.UserFavorite{}).Error; err != nil {
+		log.Printf("[UnfavoriteTask] 取消收藏失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "取消收藏失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "已取消收藏",
+		"is_favorited": false,
+	})
+}
+
+// GetFavoriteTasks handles listing the current user's favorite tasks, most recently
+// favorited first
+func GetFavoriteTasks(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var params FavoriteQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的筛选参数", "details": err.Error()})
+		return
+	}
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.Limit < 1 || params.Limit > 50 {
+		params.Limit = 10
+	}
+
+	query := db.DB.Model(&models.Task{}).
+		Joins("JOIN user_favorites ON user_favorites.task_id = tasks.id").
+		Where("user_favorites.user_id = ?", userID)
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		log.Printf("[GetFavoriteTasks] 统计收藏失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询收藏失败"})
+		return
+	}
+
+	var tasks []models.Task
+	if err := query.Preload("Employer").Preload("Skills").
+		Order("user_favorites.created_at DESC").
+		Offset((params.Page - 1) * params.Limit).Limit(params.Limit).
+		Find(&tasks).Error; err != nil {
+		log.Printf("[GetFavoriteTasks] 查询收藏失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询收藏失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tasks": formatTaskList(tasks, userID.(uint)),
+		"pagination": gin.H{
+			"current_page":   params.Page,
+			"total_pages":    (totalCount + int64(params.Limit) - 1) / int64(params.Limit),
+			"total_items":    totalCount,
+			"items_per_page": params.Limit,
+		},
+	})
+}
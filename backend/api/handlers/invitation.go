@@ -0,0 +1,611 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+	"zhlg/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Invitation limits. Each task may send a few invitations per open slot and each employer
+// has a daily cap, so invitations cannot be used to spam the worker directory.
+const (
+	minInvitationsPerTask          = 20
+	invitationsPerHeadcount        = 3
+	maxDailyInvitationsPerEmployer = 50
+	defaultInvitationExpiryDays    = 3
+	maxInvitationExpiryDays        = 14
+)
+
+// CreateInvitationRequest represents the request body for inviting a worker to a task
+type CreateInvitationRequest struct {
+	WorkerUUID    string `json:"worker_uuid" binding:"required"`
+	Message       string `json:"message" binding:"max=1000"`
+	ExpiresInDays int    `json:"expires_in_days" binding:"omitempty,min=1,max=14"`
+	DirectHire    bool   `json:"direct_hire"`
+}
+
+// DeclineInvitationRequest represents the optional request body for declining an invitation
+type DeclineInvitationRequest struct {
+	Reason string `json:"reason" binding:"max=255"`
+}
+
+// taskHasOpenSlot reports whether another worker can be assigned to the task
+func taskHasOpenSlot(tx *gorm.DB, task *models.Task) (bool, error) {
+	if task.Status != models.TaskStatusRecruiting && task.Status != models.TaskStatusInProgress {
+		return false, nil
+	}
+	active, err := countActiveAssignments(tx, task.ID)
+	if err != nil {
+		return false, err
+	}
+	return active < int64(task.Headcount), nil
+}
+
+// formatInvitation converts an invitation into its API representation
+func formatInvitation(inv models.TaskInvitation) gin.H {
+	item := gin.H{
+		"uuid":           inv.UUID,
+		"message":        inv.Message,
+		"direct_hire":    inv.DirectHire,
+		"status":         inv.Status,
+		"decline_reason": inv.DeclineReason,
+		"expires_at":     inv.ExpiresAt.Format(time.RFC3339),
+		"responded_at":   nil,
+		"created_at":     inv.CreatedAt.Format(time.RFC3339),
+	}
+	if inv.RespondedAt != nil {
+		item["responded_at"] = inv.RespondedAt.Format(time.RFC3339)
+	}
+	if inv.Task.ID != 0 {
+		item["task"] = gin.H{
+			"uuid":           inv.Task.UUID,
+			"title":          inv.Task.Title,
+			"status":         inv.Task.Status,
+			"budget_display": inv.Task.BudgetDisplay(),
+			"start_date":     inv.Task.StartDate.Format("2006-01-02"),
+			"end_date":       inv.Task.EndDate.Format("2006-01-02"),
+		}
+	}
+	if inv.Employer.ID != 0 {
+		item["employer"] = gin.H{
+			"uuid":       inv.Employer.UUID,
+			"name":       inv.Employer.Name,
+			"avatar_url": inv.Employer.AvatarURL,
+		}
+	}
+	if inv.Worker.ID != 0 {
+		item["worker"] = gin.H{
+			"uuid":       inv.Worker.UUID,
+			"name":       inv.Worker.Name,
+			"avatar_url": inv.Worker.AvatarURL,
+		}
+	}
+	return item
+}
+
+// loadPendingInvitation finds the current worker's invitation, expiring it if its time has
+// passed, and writes an error response when it cannot be answered
+func loadPendingInvitation(c *gin.Context, workerID uint) (*models.TaskInvitation, bool) {
+	var invitation models.TaskInvitation
+	if err := db.DB.Where("uuid = ? AND worker_id = ?", c.Param("uuid"), workerID).First(&invitation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "邀请不存在"})
+		return nil, false
+	}
+
+	if invitation.IsExpired(time.Now()) {
+		invitation.Status = models.InvitationStatusExpired
+		if err := db.DB.Model(&invitation).Update("status", invitation.Status).Error; err != nil {
+			log.Printf("[loadPendingInvitation] 更新邀请状态失败: %v", err)
+		}
+	}
+	switch invitation.Status {
+	case models.InvitationStatusPending:
+		return &invitation, true
+	case models.InvitationStatusExpired:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "邀请已过期"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该邀请已处理"})
+	}
+	return nil, false
+}
+
+// CreateTaskInvitation handles an employer inviting a worker to apply to their task
+// @Summary Invite a worker
+// @Description Employer invites a worker to a task, optionally as a direct hire
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Task UUID"
+// @Success 201 {object} map[string]interface{}
+// @Router /api/tasks/{uuid}/invitations [post]
+func CreateTaskInvitation(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	var task models.Task
+	if err := db.DB.Where("uuid = ?", c.Param("uuid")).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	if task.EmployerID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的发布者"})
+		return
+	}
+
+	open, err := taskHasOpenSlot(db.DB, &task)
+	if err != nil {
+		log.Printf("[CreateTaskInvitation] 查询任务分配失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务分配失败"})
+		return
+	}
+	if !open {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "任务不在招募阶段或名额已满"})
+		return
+	}
+
+	var worker models.User
+	if err := db.DB.Where("uuid = ? AND user_type = ?", req.WorkerUUID, models.UserTypeWorker).First(&worker).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "零工不存在"})
+		return
+	}
+	if !worker.PrivacySettings.ProfileVisibility {
+		c.JSON(http.StatusNotFound, gin.H{"error": "零工不存在"})
+		return
+	}
+
+	// 已申请或已在执行的零工不需要邀请
+	var application models.TaskApplication
+	if err := db.DB.Where("task_id = ? AND worker_id = ? AND status IN ?", task.ID, worker.ID,
+		[]models.ApplicationStatus{models.ApplicationStatusPending, models.ApplicationStatusAccepted}).
+		First(&application).Error; err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该零工已申请此任务"})
+		return
+	}
+
+	// 每个零工对同一任务只邀请一次，被拒绝后不再打扰
+	var previous int64
+	db.DB.Model(&models.TaskInvitation{}).
+		Where("task_id = ? AND worker_id = ? AND status IN ?", task.ID, worker.ID,
+			[]models.InvitationStatus{models.InvitationStatusPending, models.InvitationStatusAccepted, models.InvitationStatusDeclined}).
+		Count(&previous)
+	if previous > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "已邀请过该零工"})
+		return
+	}
+
+	taskLimit := int64(task.Headcount) * invitationsPerHeadcount
+	if taskLimit < minInvitationsPerTask {
+		taskLimit = minInvitationsPerTask
+	}
+	var taskCount int64
+	db.DB.Model(&models.TaskInvitation{}).Where("task_id = ?", task.ID).Count(&taskCount)
+	if taskCount >= taskLimit {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("每个任务最多发送%d个邀请", taskLimit)})
+		return
+	}
+
+	var dailyCount int64
+	db.DB.Model(&models.TaskInvitation{}).
+		Where("employer_id = ? AND created_at >= ?", task.EmployerID, time.Now().Add(-24*time.Hour)).
+		Count(&dailyCount)
+	if dailyCount >= maxDailyInvitationsPerEmployer {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("每天最多发送%d个邀请，请明天再试", maxDailyInvitationsPerEmployer)})
+		return
+	}
+
+	expiresInDays := req.ExpiresInDays
+	if expiresInDays == 0 {
+		expiresInDays = defaultInvitationExpiryDays
+	}
+
+	invitation := models.TaskInvitation{
+		UUID:       uuid.New().String(),
+		TaskID:     task.ID,
+		EmployerID: task.EmployerID,
+		WorkerID:   worker.ID,
+		DirectHire: req.DirectHire,
+		Status:     models.InvitationStatusPending,
+		ExpiresAt:  time.Now().AddDate(0, 0, expiresInDays),
+	}
+	if req.Message != "" {
+		invitation.Message = &req.Message
+	}
+
+	tx := services.BeginTx(db.DB)
+
+	if err := tx.Create(&invitation).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[CreateTaskInvitation] 创建邀请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发送邀请失败"})
+		return
+	}
+
+	services.RecordActivity(tx, task.EmployerID, worker.ID, "task_invitation", invitation.UUID, "invitation_received",
+		fmt.Sprintf("雇主邀请您参与任务：%s", task.Title), map[string]interface{}{
+			"task_uuid":   task.UUID,
+			"direct_hire": invitation.DirectHire,
+			"expires_at":  invitation.ExpiresAt.Format(time.RFC3339),
+		})
+
+	if err := services.CommitTx(tx); err != nil {
+		log.Printf("[CreateTaskInvitation] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发送邀请失败"})
+		return
+	}
+
+	invitation.Worker = worker
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "邀请已发送",
+		"invitation": formatInvitation(invitation),
+	})
+}
+
+// GetTaskInvitations handles an employer listing the invitations sent for a task
+func GetTaskInvitations(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var task models.Task
+	if err := db.DB.Where("uuid = ?", c.Param("uuid")).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	if task.EmployerID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的发布者"})
+		return
+	}
+
+	var invitations []models.TaskInvitation
+	if err := db.DB.Preload("Worker").Where("task_id = ?", task.ID).Order("created_at DESC").Find(&invitations).Error; err != nil {
+		log.Printf("[GetTaskInvitations] 查询邀请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询邀请失败"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(invitations))
+	now := time.Now()
+	for _, inv := range invitations {
+		if inv.IsExpired(now) {
+			inv.Status = models.InvitationStatusExpired
+		}
+		items = append(items, formatInvitation(inv))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"invitations": items,
+	})
+}
+
+// GetMyInvitations handles a worker listing the invitations they received
+func GetMyInvitations(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	query := db.DB.Preload("Task").Preload("Employer").Where("worker_id = ?", userID)
+	switch status := c.Query("status"); status {
+	case "":
+	case string(models.InvitationStatusPending):
+		query = query.Where("status = ? AND expires_at > ?", status, time.Now())
+	case string(models.InvitationStatusAccepted), string(models.InvitationStatusDeclined), string(models.InvitationStatusExpired):
+		query = query.Where("status = ?", status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的筛选参数", "details": status})
+		return
+	}
+
+	var invitations []models.TaskInvitation
+	if err := query.Order("created_at DESC").Find(&invitations).Error; err != nil {
+		log.Printf("[GetMyInvitations] 查询邀请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询邀请失败"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(invitations))
+	now := time.Now()
+	for _, inv := range invitations {
+		if inv.IsExpired(now) {
+			inv.Status = models.InvitationStatusExpired
+		}
+		items = append(items, formatInvitation(inv))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"invitations": items,
+	})
+}
+
+// AcceptInvitation handles a worker accepting an invitation. A regular invitation files an
+// application for the employer to review; a direct hire assigns the worker immediately.
+// @Summary Accept an invitation
+// @Tags invitations
+// @Produce json
+// @Param uuid path string true "Invitation UUID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/invitations/{uuid}/accept [put]
+func AcceptInvitation(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		return
+	}
+	if user.IDCard == nil || *user.IDCard == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "请先完成实名认证后再接受邀请", "require_verification": true})
+		return
+	}
+
+	invitation, ok := loadPendingInvitation(c, user.ID)
+	if !ok {
+		return
+	}
+
+	// 请求体可选，任务设置了筛选问题时需要回答
+	var req TaskApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	tx := services.BeginTx(db.DB)
+
+	// 锁定任务行，同时接受的直接录用邀请依次检查名额
+	var task models.Task
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&task, invitation.TaskID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	open, err := taskHasOpenSlot(tx, &task)
+	if err != nil {
+		tx.Rollback()
+		log.Printf("[AcceptInvitation] 查询任务分配失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务分配失败"})
+		return
+	}
+	if !open {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "任务已关闭或名额已满"})
+		return
+	}
+
+	if err := tx.Where("task_id = ?", task.ID).Order("sort_order ASC").Find(&task.ScreeningQuestions).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[AcceptInvitation] 查询筛选问题失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "接受邀请失败"})
+		return
+	}
+
+	var application models.TaskApplication
+	err = tx.Where("task_id = ? AND worker_id = ?", task.ID, user.ID).First(&application).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		tx.Rollback()
+		log.Printf("[AcceptInvitation] 查询申请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "接受邀请失败"})
+		return
+	}
+	if err == nil && application.Status == models.ApplicationStatusAccepted {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "您已在执行该任务"})
+		return
+	}
+
+	// 沿用待处理的申请，新申请和被拒绝或撤回过的申请按本次回答重新筛选
+	knockedOut := false
+	if err == gorm.ErrRecordNotFound || application.Status != models.ApplicationStatusPending {
+		if err := validateApplicationBid(&task, &req); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var answers []models.ScreeningAnswer
+		answers, knockedOut, err = buildScreeningAnswers(task.ScreeningQuestions, req.Answers)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+			return
+		}
+		if err := fileInvitationApplication(tx, &application, &task, user.ID, &req, answers, knockedOut); err != nil {
+			tx.Rollback()
+			log.Printf("[AcceptInvitation] 创建申请失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "接受邀请失败"})
+			return
+		}
+	}
+
+	// 命中淘汰答案时不会直接录用
+	var assignment *models.TaskAssignment
+	if invitation.DirectHire && !knockedOut {
+		amount, rate := applicationContract(&task, &application)
+		if assignment, err = assignApplication(tx, &application, amount, rate); err != nil {
+			tx.Rollback()
+			log.Printf("[AcceptInvitation] 创建任务分配失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建任务分配失败"})
+			return
+		}
+		if task.Status != models.TaskStatusInProgress {
+			task.Status = models.TaskStatusInProgress
+			if err := tx.Model(&task).Update("status", task.Status).Error; err != nil {
+				tx.Rollback()
+				log.Printf("[AcceptInvitation] 更新任务状态失败: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务状态失败"})
+				return
+			}
+		}
+	}
+
+	invitation.Accept(application.ID)
+	if err := tx.Model(invitation).Updates(map[string]interface{}{
+		"status":              invitation.Status,
+		"task_application_id": invitation.TaskApplicationID,
+		"responded_at":        invitation.RespondedAt,
+	}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[AcceptInvitation] 更新邀请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "接受邀请失败"})
+		return
+	}
+
+	description := fmt.Sprintf("零工接受了您的邀请并提交了申请：%s", task.Title)
+	switch {
+	case assignment != nil:
+		description = fmt.Sprintf("零工接受了您的邀请并已开始工作：%s", task.Title)
+	case knockedOut:
+		description = fmt.Sprintf("零工接受了您的邀请，但回答不符合筛选要求：%s", task.Title)
+	}
+	services.RecordActivity(tx, user.ID, task.EmployerID, "task_invitation", invitation.UUID, "invitation_accepted",
+		description, map[string]interface{}{
+			"task_uuid":        task.UUID,
+			"application_uuid": application.UUID,
+			"direct_hire":      invitation.DirectHire,
+		})
+
+	if err := services.CommitTx(tx); err != nil {
+		log.Printf("[AcceptInvitation] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "接受邀请失败"})
+		return
+	}
+
+	response := gin.H{
+		"message":          "已接受邀请，申请已提交",
+		"invitation":       formatInvitation(*invitation),
+		"application_uuid": application.UUID,
+		"task": gin.H{
+			"uuid":   task.UUID,
+			"status": task.Status,
+		},
+	}
+	switch {
+	case assignment != nil:
+		response["message"] = "已接受邀请，您已被直接录用"
+		response["assignment_uuid"] = assignment.UUID
+	case knockedOut:
+		response["message"] = "很抱歉，您的回答不符合该任务的要求，申请未通过"
+	}
+	response["status"] = application.Status
+	c.JSON(http.StatusOK, response)
+}
+
+// fileInvitationApplication files the application for an accepted invitation, reopening a
+// rejected or withdrawn one with the new screening answers and bid. An application hitting a
+// knockout answer is rejected straight away, as in ApplyToTask.
+func fileInvitationApplication(tx *gorm.DB, application *models.TaskApplication, task *models.Task, workerID uint,
+	req *TaskApplicationRequest, answers []models.ScreeningAnswer, knockedOut bool) error {
+	now := time.Now()
+	if application.ID == 0 {
+		*application = models.TaskApplication{
+			UUID:     uuid.New().String(),
+			TaskID:   task.ID,
+			WorkerID: workerID,
+		}
+	} else if err := tx.Where("application_id = ?", application.ID).Delete(&models.ScreeningAnswer{}).Error; err != nil {
+		return err
+	}
+
+	application.Status = models.ApplicationStatusPending
+	application.KnockedOut = false
+	application.AppliedAt = now
+	application.UpdatedAt = now
+	application.ScreeningAnswers = answers
+	application.BidAmount = req.BidAmount
+	application.BidDays = req.BidDays
+	if req.CoverLetter != "" {
+		coverLetter := req.CoverLetter
+		application.CoverLetter = &coverLetter
+	}
+	if knockedOut {
+		application.Reject()
+		application.KnockedOut = true
+	}
+	return tx.Save(application).Error
+}
+
+// DeclineInvitation handles a worker declining an invitation
+func DeclineInvitation(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req DeclineInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	invitation, ok := loadPendingInvitation(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	var task models.Task
+	if err := db.DB.First(&task, invitation.TaskID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	tx := services.BeginTx(db.DB)
+
+	invitation.Decline(req.Reason)
+	if err := tx.Model(invitation).Updates(map[string]interface{}{
+		"status":         invitation.Status,
+		"decline_reason": invitation.DeclineReason,
+		"responded_at":   invitation.RespondedAt,
+	}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[DeclineInvitation] 更新邀请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "拒绝邀请失败"})
+		return
+	}
+
+	services.RecordActivity(tx, invitation.WorkerID, task.EmployerID, "task_invitation", invitation.UUID, "invitation_declined",
+		fmt.Sprintf("零工婉拒了您的邀请：%s", task.Title), map[string]interface{}{
+			"task_uuid": task.UUID,
+			"reason":    req.Reason,
+		})
+
+	if err := services.CommitTx(tx); err != nil {
+		log.Printf("[DeclineInvitation] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "拒绝邀请失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "已拒绝邀请",
+		"invitation": formatInvitation(*invitation),
+	})
+}
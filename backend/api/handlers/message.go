@@ -0,0 +1,607 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+	"zhlg/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Limits for messages and their uploads
+const (
+	maxMessageLength   = 5000
+	maxMessageFiles    = 5
+	maxMessageFileSize = 20 * 1024 * 1024
+	messageUploadDir   = "uploads/messages"
+	messagePageSize    = 50
+)
+
+// StartConversationRequest represents the request body for opening a conversation. Either
+// ApplicationUUID, or TaskUUID plus WorkerUUID for the employer, identifies it; a worker
+// only needs TaskUUID.
+type StartConversationRequest struct {
+	ApplicationUUID string `json:"application_uuid"`
+	TaskUUID        string `json:"task_uuid"`
+	WorkerUUID      string `json:"worker_uuid"`
+}
+
+// SendMessageRequest represents the text of a message. It can be sent as JSON or as
+// multipart form data with files under the "files" field.
+type SendMessageRequest struct {
+	Body string `json:"body" form:"body"`
+}
+
+// currentUserIsAdmin reports whether the authenticated user is an admin
+func currentUserIsAdmin(c *gin.Context) bool {
+	user, exists := c.Get("user")
+	if !exists {
+		return false
+	}
+	u, ok := user.(*models.User)
+	return ok && u.UserType == models.UserTypeAdmin
+}
+
+// workerInvolvedInTask reports whether the worker has applied to, been invited to or been
+// assigned to the task, which is what allows the two sides to message each other
+func workerInvolvedInTask(tx *gorm.DB, taskID, workerID uint) (bool, error) {
+	for _, model := range []interface{}{&models.TaskApplication{}, &models.TaskInvitation{}, &models.TaskAssignment{}} {
+		var count int64
+		if err := tx.Model(model).Where("task_id = ? AND worker_id = ?", taskID, workerID).Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// loadConversation finds the conversation in the uuid path parameter. Only its participants
+// can open it, and admins when allowAdmin is set. It writes an error response and returns
+// false otherwise.
+func loadConversation(c *gin.Context, userID uint, allowAdmin bool) (*models.Conversation, bool) {
+	var conversation models.Conversation
+	if err := db.DB.Preload("Task").Preload("Employer").Preload("Worker").
+		Where("uuid = ?", c.Param("uuid")).First(&conversation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "会话不存在"})
+		return nil, false
+	}
+	if !conversation.HasParticipant(userID) && !(allowAdmin && currentUserIsAdmin(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您不是该会话的参与者"})
+		return nil, false
+	}
+	return &conversation, true
+}
+
+// parseMessagePayload reads the text and uploaded files of a message. Files are saved under
+// uploads/messages/<conversation uuid>/ like deliverables are under uploads/submissions, and
+// removed again when saving one of them fails.
+func parseMessagePayload(c *gin.Context, conversationUUID string) (string, []models.MessageAttachment, error) {
+	var req SendMessageRequest
+	attachments := make([]models.MessageAttachment, 0)
+
+	if !strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return "", nil, err
+		}
+		return strings.TrimSpace(req.Body), attachments, nil
+	}
+
+	if err := c.ShouldBind(&req); err != nil {
+		return "", nil, err
+	}
+	form, err := c.MultipartForm()
+	if err != nil {
+		return "", nil, err
+	}
+	files := form.File["files"]
+	if len(files) > maxMessageFiles {
+		return "", nil, fmt.Errorf("每条消息最多上传%d个文件", maxMessageFiles)
+	}
+	uploadDir := filepath.Join(messageUploadDir, conversationUUID)
+	if len(files) > 0 {
+		if err := os.MkdirAll(uploadDir, 0755); err != nil {
+			return "", nil, fmt.Errorf("上传失败")
+		}
+	}
+	for _, file := range files {
+		if file.Size > maxMessageFileSize {
+			return "", nil, fmt.Errorf("文件%s过大，最大支持20MB", file.Filename)
+		}
+	}
+	for _, file := range files {
+		filename := uuid.New().String() + filepath.Ext(file.Filename)
+		if err := c.SaveUploadedFile(file, filepath.Join(uploadDir, filename)); err != nil {
+			removeMessageFiles(attachments)
+			return "", nil, fmt.Errorf("上传失败")
+		}
+		attachments = append(attachments, models.MessageAttachment{
+			Name: file.Filename,
+			URL:  "/" + messageUploadDir + "/" + conversationUUID + "/" + filename,
+			Size: file.Size,
+		})
+	}
+	return strings.TrimSpace(req.Body), attachments, nil
+}
+
+// removeMessageFiles deletes the uploaded files of a message that was never stored
+func removeMessageFiles(attachments []models.MessageAttachment) {
+	for _, a := range attachments {
+		removeUpload(a.URL)
+	}
+}
+
+// formatMessage converts a message into its API representation
+func formatMessage(m *models.Message) gin.H {
+	attachments := make([]models.MessageAttachment, 0)
+	if len(m.Attachments) > 0 {
+		if err := json.Unmarshal(m.Attachments, &attachments); err != nil {
+			log.Printf("[formatMessage] 解析附件失败: message=%s, err=%v", m.UUID, err)
+		}
+	}
+	item := gin.H{
+		"uuid": m.UUID,
+		"sender": gin.H{
+			"uuid":       m.Sender.UUID,
+			"name":       m.Sender.Name,
+			"avatar_url": m.Sender.AvatarURL,
+		},
+		"body":        m.Body,
+		"attachments": attachments,
+		"read_at":     nil,
+		"created_at":  m.CreatedAt.Format(time.RFC3339),
+	}
+	if m.ReadAt != nil {
+		item["read_at"] = m.ReadAt.Format(time.RFC3339)
+	}
+	return item
+}
+
+// formatConversation converts a conversation into its API representation
+func formatConversation(conv *models.Conversation) gin.H {
+	item := gin.H{
+		"uuid": conv.UUID,
+		"task": gin.H{
+			"uuid":   conv.Task.UUID,
+			"title":  conv.Task.Title,
+			"status": conv.Task.Status,
+		},
+		"employer": gin.H{
+			"uuid":       conv.Employer.UUID,
+			"name":       conv.Employer.Name,
+			"avatar_url": conv.Employer.AvatarURL,
+		},
+		"worker": gin.H{
+			"uuid":       conv.Worker.UUID,
+			"name":       conv.Worker.Name,
+			"avatar_url": conv.Worker.AvatarURL,
+		},
+		"last_message_at": nil,
+		"created_at":      conv.CreatedAt.Format(time.RFC3339),
+	}
+	if conv.LastMessageAt != nil {
+		item["last_message_at"] = conv.LastMessageAt.Format(time.RFC3339)
+	}
+	return item
+}
+
+// unreadMessageCount counts the messages sent to the user that they have not read yet
+func unreadMessageCount(userID uint) (int64, error) {
+	var count int64
+	err := db.DB.Model(&models.Message{}).
+		Joins("JOIN conversations ON conversations.id = messages.conversation_id").
+		Where("(conversations.employer_id = ? OR conversations.worker_id = ?) AND messages.sender_id <> ? AND messages.read_at IS NULL",
+			userID, userID, userID).
+		Count(&count).Error
+	return count, err
+}
+
+// markConversationRead sets the read receipt on every message the user has received in the
+// conversation and returns how many were marked
+func markConversationRead(conversationID, userID uint) (int64, error) {
+	result := db.DB.Model(&models.Message{}).
+		Where("conversation_id = ? AND sender_id <> ? AND read_at IS NULL", conversationID, userID).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// StartConversation handles opening the conversation between a task's employer and a worker
+// who applied to, was invited to or works on the task. An existing conversation is returned
+// instead of creating a second one.
+func StartConversation(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	currentUserID := userID.(uint)
+
+	var req StartConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	var task models.Task
+	var workerID uint
+	var applicationID *uint
+	switch {
+	case req.ApplicationUUID != "":
+		var application models.TaskApplication
+		if err := db.DB.Preload("Task").Where("uuid = ?", req.ApplicationUUID).First(&application).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "申请不存在"})
+			return
+		}
+		task = application.Task
+		workerID = application.WorkerID
+		applicationID = &application.ID
+	case req.TaskUUID != "":
+		if err := db.DB.Where("uuid = ?", req.TaskUUID).First(&task).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+			return
+		}
+		if task.EmployerID != currentUserID {
+			workerID = currentUserID
+		} else {
+			var worker models.User
+			if req.WorkerUUID == "" || db.DB.Where("uuid = ?", req.WorkerUUID).First(&worker).Error != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "请指定要联系的零工"})
+				return
+			}
+			workerID = worker.ID
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定任务或申请"})
+		return
+	}
+
+	if task.EmployerID != currentUserID && workerID != currentUserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的参与者"})
+		return
+	}
+	involved, err := workerInvolvedInTask(db.DB, task.ID, workerID)
+	if err != nil {
+		log.Printf("[StartConversation] 查询任务参与情况失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建会话失败"})
+		return
+	}
+	if !involved {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能与申请、受邀或执行该任务的零工沟通"})
+		return
+	}
+	if applicationID == nil {
+		var application models.TaskApplication
+		if db.DB.Where("task_id = ? AND worker_id = ?", task.ID, workerID).First(&application).Error == nil {
+			applicationID = &application.ID
+		}
+	}
+
+	conversation := models.Conversation{
+		TaskID:            task.ID,
+		TaskApplicationID: applicationID,
+		EmployerID:        task.EmployerID,
+		WorkerID:          workerID,
+	}
+	status := http.StatusCreated
+	result := db.DB.Where("task_id = ? AND worker_id = ?", task.ID, workerID).FirstOrCreate(&conversation)
+	if result.Error != nil {
+		log.Printf("[StartConversation] 创建会话失败: %v", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建会话失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		status = http.StatusOK
+	}
+
+	db.DB.Preload("Task").Preload("Employer").Preload("Worker").First(&conversation, conversation.ID)
+	c.JSON(status, gin.H{
+		"success":      true,
+		"conversation": formatConversation(&conversation),
+	})
+}
+
+// GetConversations handles listing the current user's conversations, most recent first,
+// with the last message and the number of unread messages in each
+func GetConversations(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	currentUserID := userID.(uint)
+
+	query := db.DB.Preload("Task").Preload("Employer").Preload("Worker").
+		Where("(employer_id = ? OR worker_id = ?)", currentUserID, currentUserID)
+	if taskUUID := c.Query("task_uuid"); taskUUID != "" {
+		query = query.Where("task_id = (SELECT id FROM tasks WHERE uuid = ?)", taskUUID)
+	}
+	var conversations []models.Conversation
+	if err := query.Order("last_message_at IS NULL, last_message_at DESC, created_at DESC").Find(&conversations).Error; err != nil {
+		log.Printf("[GetConversations] 查询会话失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询会话失败"})
+		return
+	}
+
+	ids := make([]uint, 0, len(conversations))
+	for _, conv := range conversations {
+		ids = append(ids, conv.ID)
+	}
+	unread := make(map[uint]int64, len(conversations))
+	lastMessages := make(map[uint]*models.Message, len(conversations))
+	if len(ids) > 0 {
+		var counts []struct {
+			ConversationID uint
+			Count          int64
+		}
+		if err := db.DB.Model(&models.Message{}).
+			Select("conversation_id, COUNT(*) AS count").
+			Where("conversation_id IN ? AND sender_id <> ? AND read_at IS NULL", ids, currentUserID).
+			Group("conversation_id").Scan(&counts).Error; err != nil {
+			log.Printf("[GetConversations] 查询未读消息失败: %v", err)
+		}
+		for _, row := range counts {
+			unread[row.ConversationID] = row.Count
+		}
+
+		var messages []models.Message
+		if err := db.DB.Preload("Sender").
+			Where("id IN (SELECT MAX(id) FROM messages WHERE conversation_id IN ? GROUP BY conversation_id)", ids).
+			Find(&messages).Error; err != nil {
+			log.Printf("[GetConversations] 查询最新消息失败: %v", err)
+		}
+		for i := range messages {
+			lastMessages[messages[i].ConversationID] = &messages[i]
+		}
+	}
+
+	items := make([]gin.H, 0, len(conversations))
+	for i := range conversations {
+		conv := &conversations[i]
+		item := formatConversation(conv)
+		item["unread_count"] = unread[conv.ID]
+		item["last_message"] = nil
+		if m, ok := lastMessages[conv.ID]; ok {
+			item["last_message"] = formatMessage(m)
+		}
+		items = append(items, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"conversations": items,
+	})
+}
+
+// GetUnreadMessageCount handles returning the number of unread messages across all of the
+// current user's conversations
+func GetUnreadMessageCount(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	count, err := unreadMessageCount(userID.(uint))
+	if err != nil {
+		log.Printf("[GetUnreadMessageCount] 查询未读消息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询未读消息失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"unread_count": count,
+	})
+}
+
+// GetConversationMessages handles listing the messages of a conversation, newest first in
+// pages of 50. Pass before=<message uuid> for older messages. Opening the conversation
+// marks the messages received by the current user as read; admins reviewing a dispute can
+// read any conversation without changing its read receipts.
+func GetConversationMessages(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	currentUserID := userID.(uint)
+
+	conversation, ok := loadConversation(c, currentUserID, true)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(messagePageSize)))
+	if err != nil || limit <= 0 || limit > messagePageSize {
+		limit = messagePageSize
+	}
+	query := db.DB.Preload("Sender").Where("conversation_id = ?", conversation.ID)
+	if before := c.Query("before"); before != "" {
+		var cursor models.Message
+		if err := db.DB.Where("uuid = ? AND conversation_id = ?", before, conversation.ID).First(&cursor).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "消息不存在"})
+			return
+		}
+		query = query.Where("id < ?", cursor.ID)
+	}
+	var messages []models.Message
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&messages).Error; err != nil {
+		log.Printf("[GetConversationMessages] 查询消息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询消息失败"})
+		return
+	}
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	if conversation.HasParticipant(currentUserID) {
+		if _, err := markConversationRead(conversation.ID, currentUserID); err != nil {
+			log.Printf("[GetConversationMessages] 标记已读失败: %v", err)
+		}
+	} else {
+		log.Printf("[GetConversationMessages] 管理员 %d 查看会话 %s", currentUserID, conversation.UUID)
+	}
+
+	items := make([]gin.H, 0, len(messages))
+	for i := range messages {
+		items = append(items, formatMessage(&messages[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"conversation": formatConversation(conversation),
+		"messages":     items,
+		"has_more":     hasMore,
+	})
+}
+
+// SendMessage handles sending a text message, optionally with files, in a conversation
+func SendMessage(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	currentUserID := userID.(uint)
+
+	conversation, ok := loadConversation(c, currentUserID, false)
+	if !ok {
+		return
+	}
+
+	body, attachments, err := parseMessagePayload(c, conversation.UUID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	// 消息未保存时删除已上传的文件
+	stored := false
+	defer func() {
+		if !stored {
+			removeMessageFiles(attachments)
+		}
+	}()
+	if body == "" && len(attachments) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "消息内容不能为空"})
+		return
+	}
+	if utf8.RuneCountInString(body) > maxMessageLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("消息不能超过%d个字符", maxMessageLength)})
+		return
+	}
+
+	attachmentsJSON, err := json.Marshal(attachments)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发送消息失败"})
+		return
+	}
+	message := models.Message{
+		ConversationID: conversation.ID,
+		SenderID:       currentUserID,
+		Body:           body,
+		Attachments:    datatypes.JSON(attachmentsJSON),
+		CreatedAt:      time.Now(),
+	}
+
+	tx := services.BeginTx(db.DB)
+	if err := tx.Create(&message).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[SendMessage] 创建消息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发送消息失败"})
+		return
+	}
+	if err := tx.Model(conversation).Update("last_message_at", message.CreatedAt).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[SendMessage] 更新会话失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发送消息失败"})
+		return
+	}
+	services.RecordActivity(tx, currentUserID, conversation.OtherParticipant(currentUserID), "conversation", conversation.UUID, "message_received",
+		fmt.Sprintf("您收到一条关于任务「%s」的新消息", conversation.Task.Title), map[string]interface{}{
+			"task_uuid":    conversation.Task.UUID,
+			"message_uuid": message.UUID,
+		})
+	if err := services.CommitTx(tx); err != nil {
+		log.Printf("[SendMessage] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发送消息失败"})
+		return
+	}
+	stored = true
+
+	db.DB.First(&message.Sender, currentUserID)
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"message": formatMessage(&message),
+	})
+}
+
+// MarkConversationRead handles marking every message the current user has received in a
+// conversation as read
+func MarkConversationRead(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	conversation, ok := loadConversation(c, userID.(uint), false)
+	if !ok {
+		return
+	}
+	marked, err := markConversationRead(conversation.ID, userID.(uint))
+	if err != nil {
+		log.Printf("[MarkConversationRead] 标记已读失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "标记已读失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"marked":  marked,
+	})
+}
+
+// AdminGetConversations handles listing conversations for dispute resolution, filtered by
+// task or by a participating user
+func AdminGetConversations(c *gin.Context) {
+	query := db.DB.Preload("Task").Preload("Employer").Preload("Worker")
+	if taskUUID := c.Query("task_uuid"); taskUUID != "" {
+		query = query.Where("task_id = (SELECT id FROM tasks WHERE uuid = ?)", taskUUID)
+	}
+	if userUUID := c.Query("user_uuid"); userUUID != "" {
+		query = query.Where("(employer_id = (SELECT id FROM users WHERE uuid = ?) OR worker_id = (SELECT id FROM users WHERE uuid = ?))", userUUID, userUUID)
+	}
+	if c.Query("disputed") == "true" {
+		query = query.Where("EXISTS (SELECT 1 FROM task_assignments WHERE task_assignments.task_id = conversations.task_id AND task_assignments.worker_id = conversations.worker_id AND task_assignments.employer_status = ?)", models.EmployerStatusDisputed)
+	}
+
+	var conversations []models.Conversation
+	if err := query.Order("last_message_at DESC").Limit(200).Find(&conversations).Error; err != nil {
+		log.Printf("[AdminGetConversations] 查询会话失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询会话失败"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(conversations))
+	for i := range conversations {
+		items = append(items, formatConversation(&conversations[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"conversations": items,
+	})
+}
@@ -0,0 +1,514 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+	"zhlg/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MilestoneRequest represents a single milestone in a create request
+type MilestoneRequest struct {
+	Title       string  `json:"title" binding:"required"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount" binding:"required,gt=0"`
+	DueDate     string  `json:"due_date"`
+}
+
+// CreateMilestonesRequest represents the request body for adding milestones to a task
+type CreateMilestonesRequest struct {
+	Milestones []MilestoneRequest `json:"milestones" binding:"required,min=1,dive"`
+}
+
+// MilestoneSubmitRequest represents the request body for submitting a milestone
+type MilestoneSubmitRequest struct {
+	Note string `json:"note"`
+}
+
+// MilestoneReviewRequest represents the request body for rejecting a milestone
+type MilestoneReviewRequest struct {
+	Feedback string `json:"feedback"`
+}
+
+// buildMilestones validates milestone requests against the task and turns them into models.
+// existingTotal is the amount already allocated to earlier milestones of the task.
+func buildMilestones(task *models.Task, reqs []MilestoneRequest, existingTotal float64, startOrder uint) ([]models.TaskMilestone, error) {
+	if task.PaymentType != models.PaymentTypeFixed {
+		return nil, fmt.Errorf("只有固定价格任务可以设置里程碑")
+	}
+
+	total := existingTotal
+	milestones := make([]models.TaskMilestone, 0, len(reqs))
+	for i, req := range reqs {
+		milestone := models.TaskMilestone{
+			UUID:      uuid.New().String(),
+			TaskID:    task.ID,
+			Title:     req.Title,
+			Amount:    req.Amount,
+			SortOrder: startOrder + uint(i),
+			Status:    models.MilestoneStatusPending,
+		}
+		if req.Description != "" {
+			description := req.Description
+			milestone.Description = &description
+		}
+		if req.DueDate != "" {
+			dueDate, err := time.Parse("2006-01-02", req.DueDate)
+			if err != nil {
+				return nil, fmt.Errorf("里程碑「%s」截止日期格式不正确", req.Title)
+			}
+			if dueDate.Before(task.StartDate) || dueDate.After(task.EndDate) {
+				return nil, fmt.Errorf("里程碑「%s」截止日期必须在任务周期内", req.Title)
+			}
+			milestone.DueDate = &dueDate
+		}
+		total += req.Amount
+		milestones = append(milestones, milestone)
+	}
+
+	if total > task.BudgetAmount {
+		return nil, fmt.Errorf("里程碑总金额 %.2f 超出任务预算 %.2f", total, task.BudgetAmount)
+	}
+	return milestones, nil
+}
+
+// formatMilestone converts a milestone into its API representation
+func formatMilestone(m models.TaskMilestone) gin.H {
+	item := gin.H{
+		"uuid":            m.UUID,
+		"title":           m.Title,
+		"description":     m.Description,
+		"amount":          m.Amount,
+		"status":          m.Status,
+		"sort_order":      m.SortOrder,
+		"submission_note": m.SubmissionNote,
+		"feedback_note":   m.FeedbackNote,
+		"due_date":        nil,
+		"submitted_at":    nil,
+		"paid_at":         nil,
+	}
+	if m.DueDate != nil {
+		item["due_date"] = m.DueDate.Format("2006-01-02")
+	}
+	if m.SubmittedAt != nil {
+		item["submitted_at"] = m.SubmittedAt.Format(time.RFC3339)
+	}
+	if m.PaidAt != nil {
+		item["paid_at"] = m.PaidAt.Format(time.RFC3339)
+	}
+	return item
+}
+
+// canViewTaskWork reports whether the user is the task's employer or one of its assigned workers
+func canViewTaskWork(task *models.Task, userID uint) bool {
+	if task.EmployerID == userID {
+		return true
+	}
+	var count int64
+	db.DB.Model(&models.TaskAssignment{}).Where("task_id = ? AND worker_id = ?", task.ID, userID).Count(&count)
+	return count > 0
+}
+
+// GetTaskMilestones handles listing the milestones of a task
+func GetTaskMilestones(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var task models.Task
+	if err := db.DB.Where("uuid = ?", c.Param("uuid")).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	if !canViewTaskWork(&task, userID.(uint)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该任务的里程碑"})
+		return
+	}
+
+	var milestones []models.TaskMilestone
+	if err := db.DB.Where("task_id = ?", task.ID).Order("sort_order ASC").Find(&milestones).Error; err != nil {
+		log.Printf("[GetTaskMilestones] 查询里程碑失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询里程碑失败"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(milestones))
+	for _, m := range milestones {
+		items = append(items, formatMilestone(m))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"milestones": items,
+	})
+}
+
+// CreateTaskMilestones handles an employer adding milestones to an existing fixed-price task
+func CreateTaskMilestones(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req CreateMilestonesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	var task models.Task
+	if err := db.DB.Where("uuid = ?", c.Param("uuid")).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	if task.EmployerID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的雇主"})
+		return
+	}
+
+	// 里程碑只能在交付之前添加，之后新增的里程碑既无法提交也会阻止任务付款
+	switch task.Status {
+	case models.TaskStatusRecruiting:
+	case models.TaskStatusInProgress:
+		var delivered int64
+		if err := db.DB.Model(&models.TaskAssignment{}).
+			Where("task_id = ? AND worker_status IN ?", task.ID,
+				[]models.WorkerStatus{models.WorkerStatusSubmitted, models.WorkerStatusCompleted}).
+			Count(&delivered).Error; err != nil {
+			log.Printf("[CreateTaskMilestones] 查询任务分配失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务分配失败"})
+			return
+		}
+		if delivered > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "已有零工提交任务，无法再添加里程碑"})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "只有招募中或进行中的任务可以添加里程碑"})
+		return
+	}
+
+	var existing struct {
+		Total float64
+		Count uint
+	}
+	db.DB.Model(&models.TaskMilestone{}).
+		Select("COALESCE(SUM(amount), 0) as total, COUNT(*) as count").
+		Where("task_id = ?", task.ID).
+		Scan(&existing)
+
+	milestones, err := buildMilestones(&task, req.Milestones, existing.Total, existing.Count)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	if err := db.DB.Create(&milestones).Error; err != nil {
+		log.Printf("[CreateTaskMilestones] 创建里程碑失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建里程碑失败"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(milestones))
+	for _, m := range milestones {
+		items = append(items, formatMilestone(m))
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "里程碑添加成功",
+		"milestones": items,
+	})
+}
+
+// SubmitMilestone handles an assigned worker submitting a milestone for approval
+func SubmitMilestone(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req MilestoneSubmitRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	var milestone models.TaskMilestone
+	if err := db.DB.Preload("Task").Where("uuid = ?", c.Param("uuid")).First(&milestone).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "里程碑不存在"})
+		return
+	}
+
+	if milestone.Task.Status != models.TaskStatusInProgress {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "任务不在进行中状态，无法提交里程碑"})
+		return
+	}
+
+	var assignment models.TaskAssignment
+	if err := db.DB.Where("task_id = ? AND worker_id = ? AND worker_status = ?", milestone.TaskID, userID, models.WorkerStatusWorking).First(&assignment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的执行者"})
+			return
+		}
+		log.Printf("[SubmitMilestone] 查询任务分配失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务分配失败"})
+		return
+	}
+
+	if milestone.Status != models.MilestoneStatusPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该里程碑已提交或已付款"})
+		return
+	}
+
+	milestone.Submit(assignment.ID, req.Note)
+	if err := db.DB.Model(&milestone).Updates(map[string]interface{}{
+		"status":             milestone.Status,
+		"task_assignment_id": milestone.TaskAssignmentID,
+		"submission_note":    milestone.SubmissionNote,
+		"feedback_note":      milestone.FeedbackNote,
+		"submitted_at":       milestone.SubmittedAt,
+	}).Error; err != nil {
+		log.Printf("[SubmitMilestone] 更新里程碑失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交里程碑失败"})
+		return
+	}
+
+	services.RecordActivity(db.DB, assignment.WorkerID, milestone.Task.EmployerID, "task_milestone", milestone.UUID, "milestone_submitted",
+		fmt.Sprintf("零工提交了里程碑「%s」：%s", milestone.Title, milestone.Task.Title), map[string]interface{}{
+			"task_uuid": milestone.Task.UUID,
+		})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "里程碑已提交，等待雇主确认",
+		"milestone": formatMilestone(milestone),
+	})
+}
+
+// RejectMilestone handles an employer sending a submitted milestone back to the worker
+func RejectMilestone(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req MilestoneReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	var milestone models.TaskMilestone
+	if err := db.DB.Preload("Task").Where("uuid = ?", c.Param("uuid")).First(&milestone).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "里程碑不存在"})
+		return
+	}
+
+	if milestone.Task.EmployerID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的雇主"})
+		return
+	}
+
+	if milestone.Status != models.MilestoneStatusSubmitted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该里程碑不在待确认状态"})
+		return
+	}
+
+	milestone.Reject(req.Feedback)
+	if err := db.DB.Model(&milestone).Updates(map[string]interface{}{
+		"status":        milestone.Status,
+		"feedback_note": milestone.FeedbackNote,
+		"submitted_at":  nil,
+	}).Error; err != nil {
+		log.Printf("[RejectMilestone] 更新里程碑失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "退回里程碑失败"})
+		return
+	}
+
+	if milestone.TaskAssignmentID != nil {
+		var assignment models.TaskAssignment
+		if err := db.DB.First(&assignment, *milestone.TaskAssignmentID).Error; err == nil {
+			services.RecordActivity(db.DB, milestone.Task.EmployerID, assignment.WorkerID, "task_milestone", milestone.UUID, "milestone_rejected",
+				fmt.Sprintf("雇主退回了里程碑「%s」：%s", milestone.Title, milestone.Task.Title), map[string]interface{}{
+					"task_uuid": milestone.Task.UUID,
+					"feedback":  req.Feedback,
+				})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "里程碑已退回",
+		"milestone": formatMilestone(milestone),
+	})
+}
+
+// ApproveMilestone handles an employer approving a submitted milestone, which pays the
+// worker who submitted it. The task completes once every milestone has been paid.
+func ApproveMilestone(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var milestone models.TaskMilestone
+	if err := db.DB.Preload("Task").Preload("TaskAssignment").Where("uuid = ?", c.Param("uuid")).First(&milestone).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "里程碑不存在"})
+		return
+	}
+
+	task := milestone.Task
+	if task.EmployerID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的雇主"})
+		return
+	}
+
+	if milestone.Status != models.MilestoneStatusSubmitted || milestone.TaskAssignment == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该里程碑不在待确认状态"})
+		return
+	}
+	assignment := *milestone.TaskAssignment
+
+	tx := services.BeginTx(db.DB)
+
+	// Only the request that moves the milestone out of submitted pays it
+	milestone.MarkAsPaid()
+	result := tx.Model(&milestone).Where("status = ?", models.MilestoneStatusSubmitted).Updates(map[string]interface{}{
+		"status":  milestone.Status,
+		"paid_at": milestone.PaidAt,
+	})
+	if result.Error != nil {
+		tx.Rollback()
+		log.Printf("[ApproveMilestone] 更新里程碑失败: %v", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新里程碑状态失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "该里程碑已被处理"})
+		return
+	}
+
+	paymentTransaction := models.Transaction{
+		UUID:             uuid.New().String(),
+		UserID:           assignment.WorkerID,
+		TaskAssignmentID: &assignment.ID,
+		Type:             models.TransactionTypeEarning,
+		Amount:           milestone.Amount,
+		Currency:         task.Currency,
+		Status:           models.TransactionStatusCompleted,
+		Title:            "里程碑报酬",
+		Description:      stringPtr(fmt.Sprintf("完成里程碑「%s」：%s", milestone.Title, task.Title)),
+		ReferenceID:      &task.ID,
+		ReferenceType:    models.ReferenceTypeTask,
+		ReferenceUUID:    &task.UUID,
+		CompletedAt:      milestone.PaidAt,
+	}
+	if err := tx.Create(&paymentTransaction).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[ApproveMilestone] 创建交易失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建支付记录失败"})
+		return
+	}
+
+	if err := tx.Model(&models.User{}).Where("id = ?", assignment.WorkerID).
+		Update("balance", gorm.Expr("balance + ?", milestone.Amount)).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[ApproveMilestone] 更新零工余额失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新余额失败"})
+		return
+	}
+
+	var unpaid int64
+	if err := tx.Model(&models.TaskMilestone{}).
+		Where("task_id = ? AND status <> ?", task.ID, models.MilestoneStatusPaid).
+		Count(&unpaid).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[ApproveMilestone] 统计未付款里程碑失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "确认里程碑失败"})
+		return
+	}
+
+	if unpaid == 0 {
+		if err := completeMilestoneTask(tx, &task); err != nil {
+			tx.Rollback()
+			log.Printf("[ApproveMilestone] 完成任务失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务状态失败"})
+			return
+		}
+	}
+
+	services.RecordActivity(tx, task.EmployerID, assignment.WorkerID, "task_milestone", milestone.UUID, "milestone_paid",
+		fmt.Sprintf("里程碑「%s」已确认并付款：%s", milestone.Title, task.Title), map[string]interface{}{
+			"task_uuid": task.UUID,
+			"amount":    milestone.Amount,
+		})
+
+	if err := services.CommitTx(tx); err != nil {
+		log.Printf("[ApproveMilestone] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "确认里程碑失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "里程碑已确认，报酬已支付给工作者",
+		"milestone": formatMilestone(milestone),
+		"task": gin.H{
+			"uuid":   task.UUID,
+			"status": task.Status,
+		},
+	})
+}
+
+// completeMilestoneTask closes out a task whose milestones have all been paid
+func completeMilestoneTask(tx *gorm.DB, task *models.Task) error {
+	task.Status = models.TaskStatusCompleted
+	if err := tx.Model(task).Update("status", task.Status).Error; err != nil {
+		return err
+	}
+
+	var assignments []models.TaskAssignment
+	if err := tx.Where("task_id = ? AND worker_status IN ?", task.ID,
+		[]models.WorkerStatus{models.WorkerStatusWorking, models.WorkerStatusSubmitted}).
+		Find(&assignments).Error; err != nil {
+		return err
+	}
+	for i := range assignments {
+		assignments[i].MarkAsPaid()
+		if err := tx.Save(&assignments[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// taskHasMilestones reports whether the task is paid through milestones
+func taskHasMilestones(taskID uint) bool {
+	var count int64
+	db.DB.Model(&models.TaskMilestone{}).Where("task_id = ?", taskID).Count(&count)
+	return count > 0
+}
+
+// paidMilestoneAmount sums the milestones already paid to an assignment
+func paidMilestoneAmount(assignmentID uint) (float64, error) {
+	var total float64
+	err := db.DB.Model(&models.TaskMilestone{}).
+		Where("task_assignment_id = ? AND status = ?", assignmentID, models.MilestoneStatusPaid).
+		Select("COALESCE(SUM(amount), 0)").Scan(&total).Error
+	return total, err
+}
@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationQueryParams represents the filters of the notification list
+type NotificationQueryParams struct {
+	Unread   bool   `form:"unread"`
+	Type     string `form:"type"`
+	Category string `form:"category"`
+	Page     int    `form:"page"`
+	Limit    int    `form:"limit"`
+}
+
+// MarkAllNotificationsReadRequest represents the optional category to mark as read
+type MarkAllNotificationsReadRequest struct {
+	Category string `json:"category"`
+}
+
+// NotificationPreferencesRequest represents notification types switched on or off
+type NotificationPreferencesRequest struct {
+	Preferences map[string]bool `json:"preferences" binding:"required"`
+}
+
+// notificationTypesIn returns the notification types of a category
+func notificationTypesIn(category string) []string {
+	types := make([]string, 0)
+	for _, t := range models.NotificationTypes {
+		if string(t.Category) == category {
+			types = append(types, t.Type)
+		}
+	}
+	return types
+}
+
+// formatNotification converts a notification into its API representation
+func formatNotification(n *models.Notification) gin.H {
+	payload := map[string]interface{}{}
+	if len(n.Payload) > 0 {
+		if err := json.Unmarshal(n.Payload, &payload); err != nil {
+			log.Printf("[formatNotification] 解析通知内容失败: notification=%s, err=%v", n.UUID, err)
+		}
+	}
+	item := gin.H{
+		"uuid":        n.UUID,
+		"type":        n.Type,
+		"category":    nil,
+		"message":     n.Message,
+		"entity_type": n.EntityType,
+		"entity_uuid": n.EntityUUID,
+		"payload":     payload,
+		"actor":       nil,
+		"is_read":     n.ReadAt != nil,
+		"read_at":     nil,
+		"created_at":  n.CreatedAt.Format(time.RFC3339),
+	}
+	if t, ok := models.LookupNotificationType(n.Type); ok {
+		item["category"] = t.Category
+	}
+	if n.ActorID != nil {
+		item["actor"] = gin.H{
+			"uuid":       n.Actor.UUID,
+			"name":       n.Actor.Name,
+			"avatar_url": n.Actor.AvatarURL,
+		}
+	}
+	if n.ReadAt != nil {
+		item["read_at"] = n.ReadAt.Format(time.RFC3339)
+	}
+	return item
+}
+
+// unreadNotificationCount counts the user's unread notifications
+func unreadNotificationCount(userID uint) (int64, error) {
+	var count int64
+	err := db.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// GetNotifications handles listing the current user's notifications, newest first. Filter
+// with unread=true, type or category.
+func GetNotifications(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var params NotificationQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的筛选参数", "details": err.Error()})
+		return
+	}
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.Limit < 1 || params.Limit > 50 {
+		params.Limit = 20
+	}
+
+	query := db.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if params.Unread {
+		query = query.Where("read_at IS NULL")
+	}
+	if params.Type != "" {
+		query = query.Where("type = ?", params.Type)
+	}
+	if params.Category != "" {
+		query = query.Where("type IN ?", notificationTypesIn(params.Category))
+	}
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		log.Printf("[GetNotifications] 统计通知失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询通知失败"})
+		return
+	}
+	var notifications []models.Notification
+	if err := query.Preload("Actor").Order("created_at DESC, id DESC").
+		Offset((params.Page - 1) * params.Limit).Limit(params.Limit).
+		Find(&notifications).Error; err != nil {
+		log.Printf("[GetNotifications] 查询通知失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询通知失败"})
+		return
+	}
+
+	unread, err := unreadNotificationCount(userID.(uint))
+	if err != nil {
+		log.Printf("[GetNotifications] 统计未读通知失败: %v", err)
+	}
+
+	items := make([]gin.H, 0, len(notifications))
+	for i := range notifications {
+		items = append(items, formatNotification(&notifications[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": items,
+		"unread_count":  unread,
+		"pagination": gin.H{
+			"current_page":   params.Page,
+			"total_pages":    (totalCount + int64(params.Limit) - 1) / int64(params.Limit),
+			"total_items":    totalCount,
+			"items_per_page": params.Limit,
+		},
+	})
+}
+
+// GetUnreadNotificationCount handles returning the number of unread notifications
+func GetUnreadNotificationCount(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	count, err := unreadNotificationCount(userID.(uint))
+	if err != nil {
+		log.Printf("[GetUnreadNotificationCount] 统计未读通知失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询通知失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"unread_count": count,
+	})
+}
+
+// MarkNotificationRead handles marking one of the current user's notifications as read
+func MarkNotificationRead(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var notification models.Notification
+	if err := db.DB.Preload("Actor").Where("uuid = ? AND user_id = ?", c.Param("uuid"), userID).First(&notification).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "通知不存在"})
+		} else {
+			log.Printf("[MarkNotificationRead] 查询通知失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询通知失败"})
+		}
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		notification.ReadAt = &now
+		if err := db.DB.Model(&notification).Update("read_at", now).Error; err != nil {
+			log.Printf("[MarkNotificationRead] 更新通知失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "标记已读失败"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"notification": formatNotification(&notification),
+	})
+}
+
+// MarkAllNotificationsRead handles marking all of the current user's notifications as
+// read, or only those of one category
+func MarkAllNotificationsRead(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	// 请求体可选
+	var req MarkAllNotificationsReadRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+			return
+		}
+	}
+
+	query := db.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if req.Category != "" {
+		query = query.Where("type IN ?", notificationTypesIn(req.Category))
+	}
+	result := query.Update("read_at", time.Now())
+	if result.Error != nil {
+		log.Printf("[MarkAllNotificationsRead] 更新通知失败: %v", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "标记已读失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"marked":  result.RowsAffected,
+	})
+}
+
+// GetNotificationPreferences handles listing every notification type with whether the
+// current user receives it
+func GetNotificationPreferences(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var prefs []models.NotificationPreference
+	if err := db.DB.Where("user_id = ?", userID).Find(&prefs).Error; err != nil {
+		log.Printf("[GetNotificationPreferences] 查询通知设置失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询通知设置失败"})
+		return
+	}
+	disabled := make(map[string]bool, len(prefs))
+	for _, p := range prefs {
+		disabled[p.Type] = !p.Enabled
+	}
+
+	items := make([]gin.H, 0, len(models.NotificationTypes))
+	for _, t := range models.NotificationTypes {
+		items = append(items, gin.H{
+			"type":     t.Type,
+			"category": t.Category,
+			"label":    t.Label,
+			"enabled":  !disabled[t.Type],
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"preferences": items,
+	})
+}
+
+// UpdateNotificationPreferences handles switching notification types on or off for the
+// current user. Types not in the request keep their setting.
+func UpdateNotificationPreferences(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req NotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	prefs := make([]models.NotificationPreference, 0, len(req.Preferences))
+	for notificationType, enabled := range req.Preferences {
+		if _, ok := models.LookupNotificationType(notificationType); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的通知类型", "details": notificationType})
+			return
+		}
+		prefs = append(prefs, models.NotificationPreference{
+			UserID:    userID.(uint),
+			Type:      notificationType,
+			Enabled:   enabled,
+			UpdatedAt: time.Now(),
+		})
+	}
+
+	if len(prefs) > 0 {
+		if err := db.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+			DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+		}).Create(&prefs).Error; err != nil {
+			log.Printf("[UpdateNotificationPreferences] 保存通知设置失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存通知设置失败"})
+			return
+		}
+	}
+
+	GetNotificationPreferences(c)
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 	"zhlg/backend/db"
 	"zhlg/backend/models"
+	"zhlg/backend/services"
 
 	"math"
 
@@ -531,7 +532,7 @@ func RequestWithdrawal(c *gin.Context) {
 	}
 
 	// 开始数据库事务
-	tx := db.DB.Begin()
+	tx := services.BeginTx(db.DB)
 	if tx.Error != nil {
 		log.Printf("[RequestWithdrawal] 开启事务失败: %v", tx.Error)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "处理提现请求失败"})
@@ -569,8 +570,13 @@ func RequestWithdrawal(c *gin.Context) {
 		return
 	}
 
+	services.RecordActivity(tx, 0, uint(user.ID), "transaction", withdrawalUUID, "withdrawal_requested",
+		fmt.Sprintf("提现申请已提交：%.2f元到支付宝账户%s", amount, req.AlipayAccount), map[string]interface{}{
+			"amount": amount,
+		})
+
 	// 提交事务
-	if err := tx.Commit().Error; err != nil {
+	if err := services.CommitTx(tx); err != nil {
 		log.Printf("[RequestWithdrawal] 提交事务失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "提现处理失败"})
 		return
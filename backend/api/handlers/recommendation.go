@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+	"zhlg/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recommendationMaxAge is how old a precomputed feed may be before it is rebuilt on read
+const recommendationMaxAge = 2 * time.Hour
+
+// RecommendationQueryParams represents the query parameters for fetching recommendations
+type RecommendationQueryParams struct {
+	Limit   int  `form:"limit,default=20"`
+	Refresh bool `form:"refresh"`
+}
+
+// loadRecommendationFeed returns the worker's stored feed, skipping tasks that are no
+// longer open or that the worker has applied to since the feed was computed
+func loadRecommendationFeed(workerID uint, limit int) ([]models.TaskRecommendation, error) {
+	var feed []models.TaskRecommendation
+	err := db.DB.Preload("Task.Employer").Preload("Task.Skills").
+		Joins("JOIN tasks ON tasks.id = task_recommendations.task_id").
+		Where("task_recommendations.worker_id = ? AND tasks.status = ?", workerID, models.TaskStatusRecruiting).
+		Where("task_recommendations.task_id NOT IN (SELECT task_id FROM task_applications WHERE worker_id = ?)", workerID).
+		Order("task_recommendations.score DESC").Limit(limit).
+		Find(&feed).Error
+	return feed, err
+}
+
+// GetRecommendedTasks handles fetching tasks recommended for the current worker. Feeds are
+// precomputed by a background job and rebuilt here when missing, stale or on request.
+// @Summary Recommended tasks
+// @Description Open tasks ranked by skill overlap, pay, distance, employer rating and application history
+// @Tags users
+// @Produce json
+// @Param limit query int false "Number of tasks (max 50)"
+// @Param refresh query bool false "Recompute the feed now"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/users/recommendations [get]
+func GetRecommendedTasks(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var params RecommendationQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的筛选参数", "details": err.Error()})
+		return
+	}
+	if params.Limit < 1 || params.Limit > services.RecommendationFeedSize {
+		params.Limit = 20
+	}
+
+	workerID := userID.(uint)
+	feed, err := loadRecommendationFeed(workerID, params.Limit)
+	if err != nil {
+		log.Printf("[GetRecommendedTasks] 查询推荐失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取推荐任务失败"})
+		return
+	}
+
+	stale := len(feed) == 0 || time.Since(feed[0].ComputedAt) > recommendationMaxAge
+	if params.Refresh || stale {
+		if err := services.RefreshRecommendations(db.DB, workerID); err != nil {
+			log.Printf("[GetRecommendedTasks] 计算推荐失败: worker=%d, err=%v", workerID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取推荐任务失败"})
+			return
+		}
+		if feed, err = loadRecommendationFeed(workerID, params.Limit); err != nil {
+			log.Printf("[GetRecommendedTasks] 查询推荐失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取推荐任务失败"})
+			return
+		}
+	}
+
+	tasks := make([]models.Task, 0, len(feed))
+	for _, r := range feed {
+		tasks = append(tasks, r.Task)
+	}
+	items := formatTaskList(tasks, workerID)
+	for i, r := range feed {
+		reasons := make([]string, 0)
+		if len(r.Reasons) > 0 {
+			if err := json.Unmarshal(r.Reasons, &reasons); err != nil {
+				log.Printf("[GetRecommendedTasks] 解析推荐理由失败: %v", err)
+			}
+		}
+		items[i]["score"] = r.Score
+		items[i]["reasons"] = reasons
+	}
+
+	var computedAt interface{}
+	if len(feed) > 0 {
+		computedAt = feed[0].ComputedAt.Format(time.RFC3339)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"tasks":       items,
+		"computed_at": computedAt,
+	})
+}
@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+	"zhlg/backend/services"
+	"zhlg/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// recurrencePreviewCount is how many upcoming dates are shown for a series
+const recurrencePreviewCount = 5
+
+// CreateRecurrenceRequest represents the request body for setting up a recurring task.
+// Exactly one of TemplateUUID and TaskUUID names what each occurrence is copied from.
+type CreateRecurrenceRequest struct {
+	TemplateUUID   string `json:"template_uuid"`
+	TaskUUID       string `json:"task_uuid"`
+	RRule          string `json:"rrule" binding:"required"`
+	StartDate      string `json:"start_date" binding:"required"`
+	LeadDays       *int   `json:"lead_days" binding:"omitempty,min=1,max=30"`
+	MaxOccurrences int    `json:"max_occurrences" binding:"min=0,max=500"`
+	AutoInvite     bool   `json:"auto_invite"`
+}
+
+// nextOccurrences lists the dates the series will still create tasks for, at most n
+func nextOccurrences(series *models.TaskRecurrence, n int) []string {
+	dates := make([]string, 0, n)
+	if series.Status == models.RecurrenceStatusEnded {
+		return dates
+	}
+	rule, err := utils.ParseRRule(series.RRule)
+	if err != nil {
+		return dates
+	}
+
+	remaining := -1
+	if series.MaxOccurrences > 0 {
+		remaining = series.MaxOccurrences - series.OccurrencesCreated
+	}
+	today := time.Now().Truncate(24 * time.Hour)
+	for _, date := range rule.Occurrences(series.StartDate, today.AddDate(1, 0, 0)) {
+		if len(dates) >= n || remaining == 0 {
+			break
+		}
+		if date.Before(today) || (series.LastOccurrenceDate != nil && !date.After(*series.LastOccurrenceDate)) {
+			continue
+		}
+		dates = append(dates, date.Format("2006-01-02"))
+		remaining--
+	}
+	return dates
+}
+
+// formatRecurrence converts a recurring series into its API representation
+func formatRecurrence(series *models.TaskRecurrence) gin.H {
+	item := gin.H{
+		"uuid":                 series.UUID,
+		"rrule":                series.RRule,
+		"start_date":           series.StartDate.Format("2006-01-02"),
+		"duration_days":        series.DurationDays,
+		"lead_days":            series.LeadDays,
+		"max_occurrences":      series.MaxOccurrences,
+		"occurrences_created":  series.OccurrencesCreated,
+		"last_occurrence_date": nil,
+		"auto_invite":          series.AutoInvite,
+		"status":               series.Status,
+		"template":             nil,
+		"source_task":          nil,
+		"next_occurrences":     nextOccurrences(series, recurrencePreviewCount),
+		"created_at":           series.CreatedAt.Format(time.RFC3339),
+	}
+	if series.LastOccurrenceDate != nil {
+		item["last_occurrence_date"] = series.LastOccurrenceDate.Format("2006-01-02")
+	}
+	if series.Template != nil {
+		item["template"] = gin.H{"uuid": series.Template.UUID, "name": series.Template.Name}
+	}
+	if series.SourceTask != nil {
+		item["source_task"] = gin.H{"uuid": series.SourceTask.UUID, "title": series.SourceTask.Title}
+	}
+	return item
+}
+
+// loadOwnRecurrence finds one of the current employer's recurring series, writing a 404
+// when it does not exist or belongs to someone else
+func loadOwnRecurrence(c *gin.Context, employerID uint) (*models.TaskRecurrence, bool) {
+	var series models.TaskRecurrence
+	if err := db.DB.Preload("Template").Preload("SourceTask").
+		Where("uuid = ? AND employer_id = ?", c.Param("uuid"), employerID).First(&series).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "重复任务不存在"})
+		return nil, false
+	}
+	return &series, true
+}
+
+// GetTaskRecurrences handles listing the current employer's recurring task series
+func GetTaskRecurrences(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	query := db.DB.Preload("Template").Preload("SourceTask").Where("employer_id = ?", userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var series []models.TaskRecurrence
+	if err := query.Order("created_at DESC").Find(&series).Error; err != nil {
+		log.Printf("[GetTaskRecurrences] 查询重复任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询重复任务失败"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(series))
+	for i := range series {
+		items = append(items, formatRecurrence(&series[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"recurrences": items,
+	})
+}
+
+// GetTaskRecurrence handles retrieving a recurring series together with the tasks it has
+// created so far
+func GetTaskRecurrence(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	series, ok := loadOwnRecurrence(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	var tasks []models.Task
+	if err := db.DB.Where("recurrence_id = ?", series.ID).Order("start_date ASC").Find(&tasks).Error; err != nil {
+		log.Printf("[GetTaskRecurrence] 查询重复任务列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询重复任务失败"})
+		return
+	}
+	taskItems := make([]gin.H, 0, len(tasks))
+	for _, t := range tasks {
+		taskItems = append(taskItems, gin.H{
+			"uuid":       t.UUID,
+			"title":      t.Title,
+			"status":     t.Status,
+			"start_date": t.StartDate.Format("2006-01-02"),
+			"end_date":   t.EndDate.Format("2006-01-02"),
+		})
+	}
+
+	item := formatRecurrence(series)
+	item["tasks"] = taskItems
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"recurrence": item,
+	})
+}
+
+// CreateTaskRecurrence handles setting up a task that is posted again on a schedule. Each
+// occurrence is created lead_days before it starts, copied from a template or an existing
+// task. Occurrences already inside the lead window are created right away.
+func CreateTaskRecurrence(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req CreateRecurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+	if (req.TemplateUUID == "") == (req.TaskUUID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定模板或任务其中之一"})
+		return
+	}
+	if _, err := utils.ParseRRule(req.RRule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "重复规则不正确", "details": err.Error()})
+		return
+	}
+	start, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "开始日期格式不正确"})
+		return
+	}
+	if start.Before(time.Now().Truncate(24 * time.Hour)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "开始日期不能早于今天"})
+		return
+	}
+
+	series := models.TaskRecurrence{
+		UUID:           uuid.New().String(),
+		EmployerID:     userID.(uint),
+		RRule:          req.RRule,
+		StartDate:      start,
+		LeadDays:       7,
+		MaxOccurrences: req.MaxOccurrences,
+		AutoInvite:     req.AutoInvite,
+		Status:         models.RecurrenceStatusActive,
+	}
+	if req.LeadDays != nil {
+		series.LeadDays = *req.LeadDays
+	}
+
+	var source models.Task
+	if req.TemplateUUID != "" {
+		var tmpl models.TaskTemplate
+		if err := db.DB.Where("uuid = ? AND employer_id = ?", req.TemplateUUID, userID).First(&tmpl).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "模板不存在"})
+			return
+		}
+		series.TemplateID = &tmpl.ID
+		series.DurationDays = tmpl.DurationDays
+		series.Template = &tmpl
+	} else {
+		if err := db.DB.Where("uuid = ?", req.TaskUUID).First(&source).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+			return
+		}
+		if source.EmployerID != series.EmployerID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的发布者"})
+			return
+		}
+		if len(source.DraftData) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "草稿尚未填写完整，无法设置重复"})
+			return
+		}
+		if source.RecurrenceID != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "该任务已属于一个重复任务"})
+			return
+		}
+		if !start.After(source.StartDate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "首期开始日期必须晚于源任务的开始日期"})
+			return
+		}
+		series.SourceTaskID = &source.ID
+		series.DurationDays = int(source.EndDate.Sub(source.StartDate).Hours() / 24)
+		series.SourceTask = &source
+	}
+
+	tx := db.DB.Begin()
+
+	if err := tx.Omit("Template", "SourceTask").Create(&series).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[CreateTaskRecurrence] 创建重复任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建重复任务失败"})
+		return
+	}
+	// 源任务算作系列的第一期，以便自动邀请其零工参与下一期
+	if series.SourceTaskID != nil {
+		if err := tx.Model(&source).Update("recurrence_id", series.ID).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[CreateTaskRecurrence] 关联源任务失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "创建重复任务失败"})
+			return
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("[CreateTaskRecurrence] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建重复任务失败"})
+		return
+	}
+
+	if err := services.MaterializeRecurrence(db.DB, &series, time.Now()); err != nil {
+		log.Printf("[CreateTaskRecurrence] 生成首批任务失败: recurrence=%s, err=%v", series.UUID, err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "重复任务已创建",
+		"recurrence": formatRecurrence(&series),
+	})
+}
+
+// PauseTaskRecurrence handles pausing a series. No tasks are created while it is paused.
+func PauseTaskRecurrence(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	series, ok := loadOwnRecurrence(c, userID.(uint))
+	if !ok {
+		return
+	}
+	if series.Status != models.RecurrenceStatusActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "只能暂停进行中的重复任务"})
+		return
+	}
+
+	series.Status = models.RecurrenceStatusPaused
+	if err := db.DB.Model(series).Update("status", series.Status).Error; err != nil {
+		log.Printf("[PauseTaskRecurrence] 暂停重复任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "暂停重复任务失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "重复任务已暂停",
+		"recurrence": formatRecurrence(series),
+	})
+}
+
+// ResumeTaskRecurrence handles resuming a paused series. Dates that passed while it was
+// paused are skipped rather than posted late.
+func ResumeTaskRecurrence(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	series, ok := loadOwnRecurrence(c, userID.(uint))
+	if !ok {
+		return
+	}
+	if series.Status != models.RecurrenceStatusPaused {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "只能恢复已暂停的重复任务"})
+		return
+	}
+
+	series.Status = models.RecurrenceStatusActive
+	if err := db.DB.Model(series).Update("status", series.Status).Error; err != nil {
+		log.Printf("[ResumeTaskRecurrence] 恢复重复任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "恢复重复任务失败"})
+		return
+	}
+	if err := services.MaterializeRecurrence(db.DB, series, time.Now()); err != nil {
+		log.Printf("[ResumeTaskRecurrence] 生成任务失败: recurrence=%s, err=%v", series.UUID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "重复任务已恢复",
+		"recurrence": formatRecurrence(series),
+	})
+}
+
+// EndTaskRecurrence handles stopping a series for good. Tasks it already created are kept.
+func EndTaskRecurrence(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	series, ok := loadOwnRecurrence(c, userID.(uint))
+	if !ok {
+		return
+	}
+	if series.Status == models.RecurrenceStatusEnded {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "重复任务已结束"})
+		return
+	}
+
+	if err := db.DB.Model(series).Update("status", models.RecurrenceStatusEnded).Error; err != nil {
+		log.Printf("[EndTaskRecurrence] 结束重复任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "结束重复任务失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "重复任务已结束，已生成的任务不受影响"})
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 	"zhlg/backend/db"
 	"zhlg/backend/models"
+	"zhlg/backend/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -262,6 +263,12 @@ func CreateReview(c *gin.Context) {
 		log.Printf("[CreateReview] 查询评价人失败: %v", err)
 	}
 
+	services.RecordActivity(db.DB, review.ReviewerID, reviewee.ID, "review", review.UUID, "review_received",
+		fmt.Sprintf("%s评价了您在任务《%s》中的表现：%d星", reviewer.DisplayName(), task.Title, review.Rating), map[string]interface{}{
+			"task_uuid": task.UUID,
+			"rating":    review.Rating,
+		})
+
 	// 返回成功响应
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxSavedSearchesPerUser caps how many searches one user can save
+const maxSavedSearchesPerUser = 20
+
+// SavedSearchRequest represents the request body for creating or updating a saved search.
+// The filter fields mirror the query parameters of GET /tasks.
+type SavedSearchRequest struct {
+	Name         string  `json:"name" binding:"required,max=100"`
+	SearchQuery  string  `json:"search_query" binding:"max=255"`
+	Skills       string  `json:"skills" binding:"max=500"`
+	LocationType string  `json:"location_type" binding:"omitempty,oneof=online offline"`
+	Near         string  `json:"near" binding:"max=100"`
+	Radius       float64 `json:"radius"`
+	Frequency    string  `json:"frequency" binding:"omitempty,oneof=instant daily never"`
+	NotifyEmail  bool    `json:"notify_email"`
+}
+
+// applySavedSearchRequest validates the request and copies it onto the saved search,
+// resolving the near filter to coordinates
+func applySavedSearchRequest(c *gin.Context, search *models.SavedSearch, req *SavedSearchRequest) error {
+	search.Name = strings.TrimSpace(req.Name)
+	search.SearchQuery = strings.TrimSpace(req.SearchQuery)
+	search.LocationType = req.LocationType
+	search.NotifyEmail = req.NotifyEmail
+
+	skills := make([]string, 0)
+	for _, s := range strings.Split(req.Skills, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			skills = append(skills, s)
+		}
+	}
+	search.Skills = strings.Join(skills, ",")
+
+	search.Frequency = models.AlertFrequencyInstant
+	if req.Frequency != "" {
+		search.Frequency = models.AlertFrequency(req.Frequency)
+	}
+
+	search.Near = strings.TrimSpace(req.Near)
+	search.NearLatitude, search.NearLongitude, search.Radius = nil, nil, 0
+	if search.Near != "" {
+		lat, lng, _, err := resolveNear(c, search.Near)
+		if err != nil {
+			return err
+		}
+		radius := req.Radius
+		if radius == 0 {
+			radius = defaultSearchRadiusKm
+		}
+		if radius < 0 || radius > maxSearchRadiusKm {
+			return fmt.Errorf("搜索半径需在0到%d公里之间", maxSearchRadiusKm)
+		}
+		search.NearLatitude, search.NearLongitude, search.Radius = &lat, &lng, radius
+	}
+	return nil
+}
+
+// formatSavedSearch converts a saved search into its API representation. "params" can be
+// passed to GET /tasks as-is to run the search.
+func formatSavedSearch(search models.SavedSearch, pendingMatches int64) gin.H {
+	params := gin.H{}
+	if search.SearchQuery != "" {
+		params["search_query"] = search.SearchQuery
+	}
+	if search.Skills != "" {
+		params["skills"] = search.Skills
+	}
+	if search.LocationType != "" {
+		params["location_type"] = search.LocationType
+	}
+	if search.HasNear() {
+		params["near"] = fmt.Sprintf("%.6f,%.6f", *search.NearLatitude, *search.NearLongitude)
+		params["radius"] = search.Radius
+	}
+
+	item := gin.H{
+		"uuid":             search.UUID,
+		"name":             search.Name,
+		"params":           params,
+		"near_name":        search.Near,
+		"frequency":        search.Frequency,
+		"notify_email":     search.NotifyEmail,
+		"pending_matches":  pendingMatches,
+		"last_notified_at": nil,
+		"created_at":       search.CreatedAt.Format(time.RFC3339),
+	}
+	if search.LastNotifiedAt != nil {
+		item["last_notified_at"] = search.LastNotifiedAt.Format(time.RFC3339)
+	}
+	return item
+}
+
+// GetSavedSearches handles listing the current user's saved searches
+func GetSavedSearches(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var searches []models.SavedSearch
+	if err := db.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&searches).Error; err != nil {
+		log.Printf("[GetSavedSearches] 查询保存的搜索失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询保存的搜索失败"})
+		return
+	}
+
+	pending := make(map[uint]int64)
+	if len(searches) > 0 {
+		ids := make([]uint, 0, len(searches))
+		for _, s := range searches {
+			ids = append(ids, s.ID)
+		}
+		var rows []struct {
+			SavedSearchID uint
+			Count         int64
+		}
+		if err := db.DB.Model(&models.SavedSearchMatch{}).Select("saved_search_id, COUNT(*) AS count").
+			Where("saved_search_id IN ? AND notified_at IS NULL", ids).Group("saved_search_id").Scan(&rows).Error; err != nil {
+			log.Printf("[GetSavedSearches] 统计待推送任务失败: %v", err)
+		}
+		for _, r := range rows {
+			pending[r.SavedSearchID] = r.Count
+		}
+	}
+
+	items := make([]gin.H, 0, len(searches))
+	for _, s := range searches {
+		items = append(items, formatSavedSearch(s, pending[s.ID]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"saved_searches": items,
+	})
+}
+
+// CreateSavedSearch handles saving a task search for new-task alerts
+func CreateSavedSearch(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req SavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	var count int64
+	db.DB.Model(&models.SavedSearch{}).Where("user_id = ?", userID).Count(&count)
+	if count >= maxSavedSearchesPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("最多保存%d个搜索", maxSavedSearchesPerUser)})
+		return
+	}
+
+	search := models.SavedSearch{
+		UUID:   uuid.New().String(),
+		UserID: userID.(uint),
+	}
+	if err := applySavedSearchRequest(c, &search, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	if err := db.DB.Create(&search).Error; err != nil {
+		log.Printf("[CreateSavedSearch] 保存搜索失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存搜索失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "搜索已保存",
+		"saved_search": formatSavedSearch(search, 0),
+	})
+}
+
+// UpdateSavedSearch handles replacing the filters and alert settings of a saved search
+func UpdateSavedSearch(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var search models.SavedSearch
+	if err := db.DB.Where("uuid = ? AND user_id = ?", c.Param("uuid"), userID).First(&search).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "保存的搜索不存在"})
+		return
+	}
+
+	var req SavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+	if err := applySavedSearchRequest(c, &search, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	tx := db.DB.Begin()
+
+	if err := tx.Save(&search).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[UpdateSavedSearch] 更新保存的搜索失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新保存的搜索失败"})
+		return
+	}
+
+	// 筛选条件已变化，旧的待推送任务不再推送
+	if err := tx.Model(&models.SavedSearchMatch{}).Where("saved_search_id = ? AND notified_at IS NULL", search.ID).
+		Update("notified_at", time.Now()).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[UpdateSavedSearch] 清理待推送任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新保存的搜索失败"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("[UpdateSavedSearch] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新保存的搜索失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "保存的搜索已更新",
+		"saved_search": formatSavedSearch(search, 0),
+	})
+}
+
+// DeleteSavedSearch handles deleting a saved search and its pending matches
+func DeleteSavedSearch(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var search models.SavedSearch
+	if err := db.DB.Where("uuid = ? AND user_id = ?", c.Param("uuid"), userID).First(&search).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "保存的搜索不存在"})
+		return
+	}
+
+	tx := db.DB.Begin()
+
+	if err := tx.Where("saved_search_id = ?", search.ID).Delete(&models.SavedSearchMatch{}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[DeleteSavedSearch] 删除匹配记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除保存的搜索失败"})
+		return
+	}
+	if err := tx.Delete(&search).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[DeleteSavedSearch] 删除保存的搜索失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除保存的搜索失败"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("[DeleteSavedSearch] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除保存的搜索失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "保存的搜索已删除"})
+}
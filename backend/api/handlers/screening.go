@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"zhlg/backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// maxScreeningOptions caps how many choices a screening question can offer
+const maxScreeningOptions = 20
+
+// maxScreeningAnswerLength caps the length of a text answer, in characters
+const maxScreeningAnswerLength = 2000
+
+// ScreeningQuestionRequest represents a screening question in a create task request.
+// KnockoutAnswers must be among Options; choosing one rejects the application.
+type ScreeningQuestionRequest struct {
+	Question        string   `json:"question" binding:"required,max=500"`
+	Type            string   `json:"type" binding:"required,oneof=text single_choice multiple_choice"`
+	Options         []string `json:"options"`
+	Required        bool     `json:"required"`
+	KnockoutAnswers []string `json:"knockout_answers"`
+}
+
+// ScreeningAnswerRequest represents an applicant's answer to a screening question. Text
+// questions are answered with Text, choice questions with Choices.
+type ScreeningAnswerRequest struct {
+	QuestionUUID string   `json:"question_uuid" binding:"required"`
+	Text         string   `json:"text"`
+	Choices      []string `json:"choices"`
+}
+
+// buildScreeningQuestions validates the screening questions of a create request and turns
+// them into unsaved questions in the given order
+func buildScreeningQuestions(reqs []ScreeningQuestionRequest) ([]models.TaskScreeningQuestion, error) {
+	questions := make([]models.TaskScreeningQuestion, 0, len(reqs))
+	for i, r := range reqs {
+		text := strings.TrimSpace(r.Question)
+		if text == "" {
+			return nil, fmt.Errorf("第%d个筛选问题内容不能为空", i+1)
+		}
+		questionType := models.ScreeningQuestionType(r.Type)
+
+		options := make([]string, 0, len(r.Options))
+		knockouts := make([]string, 0, len(r.KnockoutAnswers))
+		if questionType == models.ScreeningQuestionText {
+			if len(r.Options) > 0 || len(r.KnockoutAnswers) > 0 {
+				return nil, fmt.Errorf("文本问题「%s」不能设置选项或淘汰答案", text)
+			}
+		} else {
+			seen := make(map[string]bool, len(r.Options))
+			for _, o := range r.Options {
+				o = strings.TrimSpace(o)
+				if o == "" || utf8.RuneCountInString(o) > 100 {
+					return nil, fmt.Errorf("问题「%s」的选项不能为空且不能超过100个字符", text)
+				}
+				if seen[o] {
+					return nil, fmt.Errorf("问题「%s」的选项「%s」重复", text, o)
+				}
+				seen[o] = true
+				options = append(options, o)
+			}
+			if len(options) < 2 || len(options) > maxScreeningOptions {
+				return nil, fmt.Errorf("选择题「%s」需要2到%d个选项", text, maxScreeningOptions)
+			}
+			for _, k := range r.KnockoutAnswers {
+				k = strings.TrimSpace(k)
+				if !seen[k] {
+					return nil, fmt.Errorf("问题「%s」的淘汰答案「%s」不在选项中", text, k)
+				}
+				knockouts = append(knockouts, k)
+			}
+			if len(knockouts) >= len(options) {
+				return nil, fmt.Errorf("问题「%s」至少要有一个不被淘汰的选项", text)
+			}
+		}
+
+		optionsJSON, err := json.Marshal(options)
+		if err != nil {
+			return nil, err
+		}
+		knockoutsJSON, err := json.Marshal(knockouts)
+		if err != nil {
+			return nil, err
+		}
+		questions = append(questions, models.TaskScreeningQuestion{
+			UUID:            uuid.New().String(),
+			Question:        text,
+			Type:            questionType,
+			Options:         datatypes.JSON(optionsJSON),
+			Required:        r.Required,
+			KnockoutAnswers: datatypes.JSON(knockoutsJSON),
+			SortOrder:       uint(i),
+		})
+	}
+	return questions, nil
+}
+
+// screeningQuestionRequests turns saved screening questions back into request form
+func screeningQuestionRequests(questions []models.TaskScreeningQuestion) []ScreeningQuestionRequest {
+	reqs := make([]ScreeningQuestionRequest, 0, len(questions))
+	for i := range questions {
+		q := &questions[i]
+		r := ScreeningQuestionRequest{
+			Question: q.Question,
+			Type:     string(q.Type),
+			Required: q.Required,
+		}
+		if q.Type != models.ScreeningQuestionText {
+			r.Options = q.OptionList()
+			r.KnockoutAnswers = q.KnockoutList()
+		}
+		reqs = append(reqs, r)
+	}
+	return reqs
+}
+
+// formatScreeningQuestion converts a screening question into its API representation.
+// Knockout answers are only shown to the task's employer.
+func formatScreeningQuestion(q *models.TaskScreeningQuestion, withKnockouts bool) gin.H {
+	item := gin.H{
+		"uuid":     q.UUID,
+		"question": q.Question,
+		"type":     q.Type,
+		"options":  q.OptionList(),
+		"required": q.Required,
+	}
+	if withKnockouts {
+		item["knockout_answers"] = q.KnockoutList()
+	}
+	return item
+}
+
+// buildScreeningAnswers checks an applicant's answers against the task's questions and
+// turns them into unsaved answers. It reports whether any answer is a knockout answer.
+func buildScreeningAnswers(questions []models.TaskScreeningQuestion, reqs []ScreeningAnswerRequest) ([]models.ScreeningAnswer, bool, error) {
+	byUUID := make(map[string]ScreeningAnswerRequest, len(reqs))
+	for _, r := range reqs {
+		if _, ok := byUUID[r.QuestionUUID]; ok {
+			return nil, false, fmt.Errorf("同一问题只能回答一次")
+		}
+		byUUID[r.QuestionUUID] = r
+	}
+	known := make(map[string]bool, len(questions))
+	for _, q := range questions {
+		known[q.UUID] = true
+	}
+	for questionUUID := range byUUID {
+		if !known[questionUUID] {
+			return nil, false, fmt.Errorf("筛选问题不存在: %s", questionUUID)
+		}
+	}
+
+	answers := make([]models.ScreeningAnswer, 0, len(reqs))
+	knockedOut := false
+	for i := range questions {
+		q := &questions[i]
+		r, answered := byUUID[q.UUID]
+		text := strings.TrimSpace(r.Text)
+		if !answered || (text == "" && len(r.Choices) == 0) {
+			if q.Required {
+				return nil, false, fmt.Errorf("请回答必答问题「%s」", q.Question)
+			}
+			continue
+		}
+
+		answer := models.ScreeningAnswer{QuestionID: q.ID}
+		if q.Type == models.ScreeningQuestionText {
+			if len(r.Choices) > 0 {
+				return nil, false, fmt.Errorf("问题「%s」需要文字回答", q.Question)
+			}
+			if utf8.RuneCountInString(text) > maxScreeningAnswerLength {
+				return nil, false, fmt.Errorf("问题「%s」的回答不能超过%d个字符", q.Question, maxScreeningAnswerLength)
+			}
+			answer.Text = &text
+			answers = append(answers, answer)
+			continue
+		}
+
+		options := make(map[string]bool)
+		for _, o := range q.OptionList() {
+			options[o] = true
+		}
+		choices := make([]string, 0, len(r.Choices))
+		chosen := make(map[string]bool, len(r.Choices))
+		for _, choice := range r.Choices {
+			if !options[choice] {
+				return nil, false, fmt.Errorf("问题「%s」没有选项「%s」", q.Question, choice)
+			}
+			if !chosen[choice] {
+				chosen[choice] = true
+				choices = append(choices, choice)
+			}
+		}
+		if len(choices) == 0 {
+			return nil, false, fmt.Errorf("请选择问题「%s」的答案", q.Question)
+		}
+		if q.Type == models.ScreeningQuestionSingleChoice && len(choices) != 1 {
+			return nil, false, fmt.Errorf("问题「%s」只能选择一个答案", q.Question)
+		}
+		choicesJSON, err := json.Marshal(choices)
+		if err != nil {
+			return nil, false, err
+		}
+		answer.Choices = datatypes.JSON(choicesJSON)
+		answer.Knockout = q.IsKnockout(choices)
+		knockedOut = knockedOut || answer.Knockout
+		answers = append(answers, answer)
+	}
+	return answers, knockedOut, nil
+}
+
+// formatScreeningAnswers lists an application's answers in question order for the employer
+func formatScreeningAnswers(questions []models.TaskScreeningQuestion, answers []models.ScreeningAnswer) []gin.H {
+	byQuestion := make(map[uint]*models.ScreeningAnswer, len(answers))
+	for i := range answers {
+		byQuestion[answers[i].QuestionID] = &answers[i]
+	}
+
+	items := make([]gin.H, 0, len(answers))
+	for i := range questions {
+		q := &questions[i]
+		a, ok := byQuestion[q.ID]
+		if !ok {
+			continue
+		}
+		items = append(items, gin.H{
+			"question_uuid": q.UUID,
+			"question":      q.Question,
+			"type":          q.Type,
+			"text":          a.Text,
+			"choices":       a.ChoiceList(),
+			"knockout":      a.Knockout,
+		})
+	}
+	return items
+}
@@ -0,0 +1,500 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+	"zhlg/backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits for skill autocomplete
+const (
+	defaultAutocompleteLimit = 10
+	maxAutocompleteLimit     = 30
+	autocompleteCandidates   = 100
+)
+
+// SkillCategoryRequest represents the request body for creating a skill category
+type SkillCategoryRequest struct {
+	Name      string `json:"name" binding:"required,max=100"`
+	ParentID  *uint  `json:"parent_id"`
+	SortOrder int    `json:"sort_order"`
+}
+
+// UpdateSkillRequest represents the request body for editing a skill. A category_id or
+// parent_id of 0 detaches the skill.
+type UpdateSkillRequest struct {
+	Name       *string `json:"name" binding:"omitempty,max=100"`
+	CategoryID *uint   `json:"category_id"`
+	ParentID   *uint   `json:"parent_id"`
+}
+
+// SkillSynonymRequest represents the request body for adding a synonym
+type SkillSynonymRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// MergeSkillsRequest represents the request body for merging duplicate skills
+type MergeSkillsRequest struct {
+	TargetID  uint   `json:"target_id" binding:"required"`
+	SourceIDs []uint `json:"source_ids" binding:"required,min=1"`
+}
+
+// skillUsageCounts returns how many tasks and users are tagged with each skill
+func skillUsageCounts(skillIDs []uint) map[uint]int64 {
+	counts := make(map[uint]int64)
+	if len(skillIDs) == 0 {
+		return counts
+	}
+	for _, pivot := range []string{"task_skills", "user_skills"} {
+		var rows []struct {
+			SkillID uint
+			Count   int64
+		}
+		if err := db.DB.Table(pivot).Select("skill_id, COUNT(*) AS count").
+			Where("skill_id IN ?", skillIDs).Group("skill_id").Scan(&rows).Error; err != nil {
+			log.Printf("[skillUsageCounts] 统计技能使用次数失败: %v", err)
+			continue
+		}
+		for _, r := range rows {
+			counts[r.SkillID] += r.Count
+		}
+	}
+	return counts
+}
+
+// AutocompleteSkills handles suggesting skills for a partial name. Skills are matched by
+// name and synonyms, ranked exact match first, then prefix, then substring, and the most
+// used skills first within each rank.
+// @Summary Autocomplete skills
+// @Tags skills
+// @Produce json
+// @Param q query string true "Partial skill name"
+// @Param limit query int false "Maximum suggestions"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/skills/autocomplete [get]
+func AutocompleteSkills(c *gin.Context) {
+	key := services.NormalizeSkillName(c.Query("q"))
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultAutocompleteLimit)))
+	if err != nil || limit < 1 || limit > maxAutocompleteLimit {
+		limit = defaultAutocompleteLimit
+	}
+	if key == "" {
+		c.JSON(http.StatusOK, gin.H{"skills": []gin.H{}})
+		return
+	}
+	like := "%" + key + "%"
+
+	var skills []models.Skill
+	if err := db.DB.Preload("Category").Where("normalized_name LIKE ?", like).
+		Limit(autocompleteCandidates).Find(&skills).Error; err != nil {
+		log.Printf("[AutocompleteSkills] 查询技能失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询技能失败"})
+		return
+	}
+
+	var synonyms []models.SkillSynonym
+	if err := db.DB.Where("normalized_name LIKE ?", like).Limit(autocompleteCandidates).Find(&synonyms).Error; err != nil {
+		log.Printf("[AutocompleteSkills] 查询同义词失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询技能失败"})
+		return
+	}
+
+	rank := func(normalized string) int {
+		switch {
+		case normalized == key:
+			return 0
+		case strings.HasPrefix(normalized, key):
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	type suggestion struct {
+		skill   models.Skill
+		rank    int
+		matched string
+	}
+	byID := make(map[uint]*suggestion)
+	for _, s := range skills {
+		byID[s.ID] = &suggestion{skill: s, rank: rank(s.NormalizedName)}
+	}
+
+	missing := make([]uint, 0)
+	for _, syn := range synonyms {
+		if _, ok := byID[syn.SkillID]; !ok {
+			missing = append(missing, syn.SkillID)
+		}
+	}
+	if len(missing) > 0 {
+		var extra []models.Skill
+		if err := db.DB.Preload("Category").Where("id IN ?", missing).Find(&extra).Error; err != nil {
+			log.Printf("[AutocompleteSkills] 查询技能失败: %v", err)
+		}
+		for _, s := range extra {
+			byID[s.ID] = &suggestion{skill: s, rank: 3}
+		}
+	}
+	for _, syn := range synonyms {
+		if s, ok := byID[syn.SkillID]; ok && rank(syn.NormalizedName) < s.rank {
+			s.rank = rank(syn.NormalizedName)
+			s.matched = syn.Name
+		}
+	}
+
+	suggestions := make([]*suggestion, 0, len(byID))
+	ids := make([]uint, 0, len(byID))
+	for id, s := range byID {
+		suggestions = append(suggestions, s)
+		ids = append(ids, id)
+	}
+	usage := skillUsageCounts(ids)
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].rank != suggestions[j].rank {
+			return suggestions[i].rank < suggestions[j].rank
+		}
+		ui, uj := usage[suggestions[i].skill.ID], usage[suggestions[j].skill.ID]
+		if ui != uj {
+			return ui > uj
+		}
+		return suggestions[i].skill.Name < suggestions[j].skill.Name
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	items := make([]gin.H, 0, len(suggestions))
+	for _, s := range suggestions {
+		item := gin.H{
+			"id":          s.skill.ID,
+			"name":        s.skill.Name,
+			"category":    nil,
+			"matched":     nil,
+			"usage_count": usage[s.skill.ID],
+		}
+		if s.skill.Category != nil {
+			item["category"] = s.skill.Category.Name
+		}
+		if s.matched != "" {
+			item["matched"] = s.matched
+		}
+		items = append(items, item)
+	}
+	c.JSON(http.StatusOK, gin.H{"skills": items})
+}
+
+// GetSkillCategories handles returning the skill taxonomy as a tree of categories, each
+// with its sub-categories and skills. Skills without a category are listed separately.
+func GetSkillCategories(c *gin.Context) {
+	var categories []models.SkillCategory
+	if err := db.DB.Order("sort_order ASC, name ASC").Find(&categories).Error; err != nil {
+		log.Printf("[GetSkillCategories] 查询技能分类失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询技能分类失败"})
+		return
+	}
+	var skills []models.Skill
+	if err := db.DB.Order("name ASC").Find(&skills).Error; err != nil {
+		log.Printf("[GetSkillCategories] 查询技能失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询技能失败"})
+		return
+	}
+
+	skillsByCategory := make(map[uint][]gin.H)
+	uncategorized := make([]gin.H, 0)
+	for _, s := range skills {
+		item := gin.H{"id": s.ID, "name": s.Name, "parent_id": s.ParentID}
+		if s.CategoryID == nil {
+			uncategorized = append(uncategorized, item)
+			continue
+		}
+		skillsByCategory[*s.CategoryID] = append(skillsByCategory[*s.CategoryID], item)
+	}
+
+	children := make(map[uint][]models.SkillCategory)
+	roots := make([]models.SkillCategory, 0)
+	for _, cat := range categories {
+		if cat.ParentID == nil {
+			roots = append(roots, cat)
+			continue
+		}
+		children[*cat.ParentID] = append(children[*cat.ParentID], cat)
+	}
+
+	var build func(cat models.SkillCategory, depth int) gin.H
+	build = func(cat models.SkillCategory, depth int) gin.H {
+		subs := make([]gin.H, 0)
+		// 防止错误数据形成环
+		if depth < len(categories) {
+			for _, child := range children[cat.ID] {
+				subs = append(subs, build(child, depth+1))
+			}
+		}
+		catSkills := skillsByCategory[cat.ID]
+		if catSkills == nil {
+			catSkills = []gin.H{}
+		}
+		return gin.H{
+			"id":         cat.ID,
+			"name":       cat.Name,
+			"categories": subs,
+			"skills":     catSkills,
+		}
+	}
+
+	tree := make([]gin.H, 0, len(roots))
+	for _, root := range roots {
+		tree = append(tree, build(root, 0))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"categories":    tree,
+		"uncategorized": uncategorized,
+	})
+}
+
+// AdminCreateSkillCategory handles creating a skill category
+func AdminCreateSkillCategory(c *gin.Context) {
+	var req SkillCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	category := models.SkillCategory{Name: strings.TrimSpace(req.Name), SortOrder: req.SortOrder}
+	if req.ParentID != nil && *req.ParentID != 0 {
+		var parent models.SkillCategory
+		if err := db.DB.First(&parent, *req.ParentID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "上级分类不存在"})
+			return
+		}
+		category.ParentID = &parent.ID
+	}
+
+	var count int64
+	db.DB.Model(&models.SkillCategory{}).Where("name = ?", category.Name).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "分类名称已存在"})
+		return
+	}
+
+	if err := db.DB.Create(&category).Error; err != nil {
+		log.Printf("[AdminCreateSkillCategory] 创建技能分类失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建技能分类失败"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "技能分类已创建",
+		"category": category,
+	})
+}
+
+// AdminUpdateSkill handles renaming a skill and placing it in the taxonomy
+func AdminUpdateSkill(c *gin.Context) {
+	var skill models.Skill
+	if err := db.DB.First(&skill, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "技能不存在"})
+		return
+	}
+
+	var req UpdateSkillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		key := services.NormalizeSkillName(name)
+		if key == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "技能名称不能为空"})
+			return
+		}
+		var count int64
+		db.DB.Model(&models.Skill{}).Where("normalized_name = ? AND id <> ?", key, skill.ID).Count(&count)
+		var synonyms int64
+		db.DB.Model(&models.SkillSynonym{}).Where("normalized_name = ? AND skill_id <> ?", key, skill.ID).Count(&synonyms)
+		if count+synonyms > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "已存在同名技能，请使用合并"})
+			return
+		}
+		updates["name"] = name
+		updates["normalized_name"] = key
+	}
+
+	if req.CategoryID != nil {
+		if *req.CategoryID == 0 {
+			updates["category_id"] = nil
+		} else {
+			var category models.SkillCategory
+			if err := db.DB.First(&category, *req.CategoryID).Error; err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "技能分类不存在"})
+				return
+			}
+			updates["category_id"] = category.ID
+		}
+	}
+
+	if req.ParentID != nil {
+		if *req.ParentID == 0 {
+			updates["parent_id"] = nil
+		} else {
+			// 上级技能不能是自身或自身的下级
+			descendants, err := services.SkillsWithDescendants(db.DB, []uint{skill.ID})
+			if err != nil {
+				log.Printf("[AdminUpdateSkill] 查询下级技能失败: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "更新技能失败"})
+				return
+			}
+			for _, id := range descendants {
+				if id == *req.ParentID {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "上级技能不能是自身或其下级技能"})
+					return
+				}
+			}
+			var parent models.Skill
+			if err := db.DB.First(&parent, *req.ParentID).Error; err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "上级技能不存在"})
+				return
+			}
+			updates["parent_id"] = parent.ID
+		}
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要更新的字段"})
+		return
+	}
+	if err := db.DB.Model(&skill).Updates(updates).Error; err != nil {
+		log.Printf("[AdminUpdateSkill] 更新技能失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新技能失败"})
+		return
+	}
+
+	db.DB.Preload("Category").Preload("Synonyms").First(&skill, skill.ID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "技能已更新",
+		"skill":   skill,
+	})
+}
+
+// AdminAddSkillSynonym handles mapping an alternative spelling to a skill
+func AdminAddSkillSynonym(c *gin.Context) {
+	var skill models.Skill
+	if err := db.DB.First(&skill, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "技能不存在"})
+		return
+	}
+
+	var req SkillSynonymRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	synonym, err := services.AddSkillSynonym(db.DB, skill.ID, req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "添加同义词失败", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "同义词已添加",
+		"synonym": synonym,
+	})
+}
+
+// AdminDeleteSkillSynonym handles removing a synonym
+func AdminDeleteSkillSynonym(c *gin.Context) {
+	result := db.DB.Delete(&models.SkillSynonym{}, c.Param("id"))
+	if result.Error != nil {
+		log.Printf("[AdminDeleteSkillSynonym] 删除同义词失败: %v", result.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除同义词失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "同义词不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "同义词已删除"})
+}
+
+// AdminGetDuplicateSkills handles listing groups of skills whose names normalize to the
+// same key, the usual candidates for merging
+func AdminGetDuplicateSkills(c *gin.Context) {
+	var keys []string
+	if err := db.DB.Model(&models.Skill{}).Group("normalized_name").Having("COUNT(*) > 1").
+		Pluck("normalized_name", &keys).Error; err != nil {
+		log.Printf("[AdminGetDuplicateSkills] 查询重复技能失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询重复技能失败"})
+		return
+	}
+
+	groups := make([]gin.H, 0, len(keys))
+	if len(keys) > 0 {
+		var skills []models.Skill
+		if err := db.DB.Where("normalized_name IN ?", keys).Order("id ASC").Find(&skills).Error; err != nil {
+			log.Printf("[AdminGetDuplicateSkills] 查询重复技能失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询重复技能失败"})
+			return
+		}
+		ids := make([]uint, 0, len(skills))
+		for _, s := range skills {
+			ids = append(ids, s.ID)
+		}
+		usage := skillUsageCounts(ids)
+
+		byKey := make(map[string][]gin.H)
+		for _, s := range skills {
+			byKey[s.NormalizedName] = append(byKey[s.NormalizedName], gin.H{
+				"id":          s.ID,
+				"name":        s.Name,
+				"usage_count": usage[s.ID],
+			})
+		}
+		for _, key := range keys {
+			groups = append(groups, gin.H{"key": key, "skills": byKey[key]})
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"duplicates": groups})
+}
+
+// AdminMergeSkills handles merging duplicate skills into a canonical one. Tasks and users
+// tagged with a source skill are retagged with the target and the source names keep
+// resolving to the target as synonyms.
+func AdminMergeSkills(c *gin.Context) {
+	var req MergeSkillsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	tx := db.DB.Begin()
+
+	if err := services.MergeSkills(tx, req.TargetID, req.SourceIDs); err != nil {
+		tx.Rollback()
+		log.Printf("[AdminMergeSkills] 合并技能失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "合并技能失败", "details": err.Error()})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("[AdminMergeSkills] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "合并技能失败"})
+		return
+	}
+
+	var target models.Skill
+	db.DB.Preload("Category").Preload("Synonyms").First(&target, req.TargetID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("已合并%d个技能", len(req.SourceIDs)),
+		"skill":   target,
+	})
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+	"zhlg/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Limits for deliverable uploads
+const (
+	maxSubmissionFiles    = 10
+	maxSubmissionFileSize = 20 * 1024 * 1024
+	submissionUploadDir   = "uploads/submissions"
+)
+
+// CompleteTaskRequest represents the optional deliverable payload sent with CompleteTask.
+// It can be sent as JSON or as multipart form data with files under the "files" field.
+type CompleteTaskRequest struct {
+	Notes string   `json:"notes" form:"notes"`
+	Links []string `json:"links" form:"links"`
+}
+
+// RequestChangesRequest represents the request body for asking a worker to rework a submission
+type RequestChangesRequest struct {
+	Feedback string `json:"feedback" binding:"required"`
+}
+
+// parseSubmissionPayload reads notes, links and uploaded files from the request. Files are
+// saved under uploads/submissions/<task uuid>/ like avatars are under uploads/avatars; when
+// the request turns out to be invalid the files saved so far are removed again.
+func parseSubmissionPayload(c *gin.Context, taskUUID string) (string, []models.SubmissionAttachment, error) {
+	var req CompleteTaskRequest
+	attachments := make([]models.SubmissionAttachment, 0)
+
+	if c.Request.ContentLength == 0 {
+		return "", attachments, nil
+	}
+
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		if err := c.ShouldBind(&req); err != nil {
+			return "", nil, err
+		}
+		form, err := c.MultipartForm()
+		if err != nil {
+			return "", nil, err
+		}
+		files := form.File["files"]
+		if len(files) > maxSubmissionFiles {
+			return "", nil, fmt.Errorf("最多上传%d个文件", maxSubmissionFiles)
+		}
+		uploadDir := filepath.Join(submissionUploadDir, taskUUID)
+		if len(files) > 0 {
+			if err := os.MkdirAll(uploadDir, 0755); err != nil {
+				return "", nil, fmt.Errorf("上传失败")
+			}
+		}
+		for _, file := range files {
+			if file.Size > maxSubmissionFileSize {
+				return "", nil, fmt.Errorf("文件%s过大，最大支持20MB", file.Filename)
+			}
+		}
+		for _, file := range files {
+			filename := uuid.New().String() + filepath.Ext(file.Filename)
+			if err := c.SaveUploadedFile(file, filepath.Join(uploadDir, filename)); err != nil {
+				removeSubmissionFiles(attachments)
+				return "", nil, fmt.Errorf("上传失败")
+			}
+			attachments = append(attachments, models.SubmissionAttachment{
+				Kind: "file",
+				Name: file.Filename,
+				URL:  "/" + submissionUploadDir + "/" + taskUUID + "/" + filename,
+				Size: file.Size,
+			})
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		return "", nil, err
+	}
+
+	for _, link := range req.Links {
+		link = strings.TrimSpace(link)
+		if link == "" {
+			continue
+		}
+		parsed, err := url.ParseRequestURI(link)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			removeSubmissionFiles(attachments)
+			return "", nil, fmt.Errorf("链接格式不正确: %s", link)
+		}
+		attachments = append(attachments, models.SubmissionAttachment{
+			Kind: "link",
+			Name: link,
+			URL:  link,
+		})
+	}
+
+	return strings.TrimSpace(req.Notes), attachments, nil
+}
+
+// removeSubmissionFiles deletes the uploaded files of attachments that were never stored
+func removeSubmissionFiles(attachments []models.SubmissionAttachment) {
+	for _, a := range attachments {
+		if a.Kind == "file" {
+			removeUpload(a.URL)
+		}
+	}
+}
+
+// removeUpload deletes a file saved under uploads/ given its URL
+func removeUpload(fileURL string) {
+	if err := os.Remove(strings.TrimPrefix(fileURL, "/")); err != nil && !os.IsNotExist(err) {
+		log.Printf("[removeUpload] 删除上传文件失败: %s, err=%v", fileURL, err)
+	}
+}
+
+// createSubmission stores a new submission version for the assignment
+func createSubmission(tx *gorm.DB, assignment *models.TaskAssignment, notes string, attachments []models.SubmissionAttachment) (*models.TaskSubmission, error) {
+	var previous int64
+	if err := tx.Model(&models.TaskSubmission{}).Where("task_assignment_id = ?", assignment.ID).Count(&previous).Error; err != nil {
+		return nil, err
+	}
+
+	attachmentsJSON, err := json.Marshal(attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	submission := models.TaskSubmission{
+		UUID:             uuid.New().String(),
+		TaskID:           assignment.TaskID,
+		TaskAssignmentID: assignment.ID,
+		Version:          uint(previous) + 1,
+		Attachments:      datatypes.JSON(attachmentsJSON),
+		Status:           models.SubmissionStatusSubmitted,
+		SubmittedAt:      time.Now(),
+	}
+	if notes != "" {
+		submission.Notes = &notes
+	}
+	if err := tx.Create(&submission).Error; err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// formatSubmission converts a submission into its API representation
+func formatSubmission(s models.TaskSubmission) gin.H {
+	attachments := make([]models.SubmissionAttachment, 0)
+	if len(s.Attachments) > 0 {
+		if err := json.Unmarshal(s.Attachments, &attachments); err != nil {
+			log.Printf("[formatSubmission] 解析附件失败: submission=%s, err=%v", s.UUID, err)
+		}
+	}
+	item := gin.H{
+		"uuid":         s.UUID,
+		"version":      s.Version,
+		"notes":        s.Notes,
+		"attachments":  attachments,
+		"status":       s.Status,
+		"feedback":     s.Feedback,
+		"submitted_at": s.SubmittedAt.Format(time.RFC3339),
+		"reviewed_at":  nil,
+	}
+	if s.ReviewedAt != nil {
+		item["reviewed_at"] = s.ReviewedAt.Format(time.RFC3339)
+	}
+	return item
+}
+
+// taskSubmissionsFor returns the submissions on a task visible to the user. The employer sees
+// every worker's submissions, an assigned worker only sees their own.
+func taskSubmissionsFor(task *models.Task, userID uint, isEmployer bool) []gin.H {
+	var submissions []models.TaskSubmission
+	query := db.DB.Preload("TaskAssignment.Worker").Where("task_submissions.task_id = ?", task.ID)
+	if !isEmployer {
+		query = query.Joins("JOIN task_assignments ON task_assignments.id = task_submissions.task_assignment_id").
+			Where("task_assignments.worker_id = ?", userID)
+	}
+	if err := query.Order("task_submissions.submitted_at DESC").Find(&submissions).Error; err != nil {
+		log.Printf("[taskSubmissionsFor] 查询提交记录失败: %v", err)
+		return []gin.H{}
+	}
+
+	items := make([]gin.H, 0, len(submissions))
+	for _, s := range submissions {
+		item := formatSubmission(s)
+		item["assignment_uuid"] = s.TaskAssignment.UUID
+		item["worker"] = gin.H{
+			"uuid":       s.TaskAssignment.Worker.UUID,
+			"name":       s.TaskAssignment.Worker.Name,
+			"avatar_url": s.TaskAssignment.Worker.AvatarURL,
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// RequestSubmissionChanges handles an employer sending submitted work back to the worker
+// @Summary Request changes on a submission
+// @Description Employer rejects the latest submission with feedback and puts the worker back to work
+// @Tags assignments
+// @Accept json
+// @Produce json
+// @Param uuid path string true "Assignment UUID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/assignments/{uuid}/request-changes [put]
+func RequestSubmissionChanges(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req RequestChangesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	var assignment models.TaskAssignment
+	if err := db.DB.Preload("Task").Where("uuid = ?", c.Param("uuid")).First(&assignment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务分配不存在"})
+		return
+	}
+
+	task := assignment.Task
+	if task.EmployerID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的雇主"})
+		return
+	}
+
+	if assignment.WorkerStatus != models.WorkerStatusSubmitted || assignment.EmployerStatus != models.EmployerStatusReviewPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该任务分配没有待审核的提交"})
+		return
+	}
+
+	var submission models.TaskSubmission
+	if err := db.DB.Where("task_assignment_id = ?", assignment.ID).Order("version DESC").First(&submission).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未找到提交记录"})
+		return
+	}
+
+	tx := services.BeginTx(db.DB)
+
+	submission.RequestChanges(req.Feedback)
+	if err := tx.Model(&submission).Updates(map[string]interface{}{
+		"status":      submission.Status,
+		"feedback":    submission.Feedback,
+		"reviewed_at": submission.ReviewedAt,
+	}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[RequestSubmissionChanges] 更新提交记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新提交记录失败"})
+		return
+	}
+
+	assignment.ResumeWork()
+	if err := tx.Model(&assignment).Updates(map[string]interface{}{
+		"worker_status":   assignment.WorkerStatus,
+		"employer_status": assignment.EmployerStatus,
+	}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[RequestSubmissionChanges] 更新任务分配失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务分配状态失败"})
+		return
+	}
+
+	// 任务回到进行中，零工才能重新提交
+	if task.Status == models.TaskStatusPaymentPending {
+		task.Status = models.TaskStatusInProgress
+		if err := tx.Model(&task).Update("status", task.Status).Error; err != nil {
+			tx.Rollback()
+			log.Printf("[RequestSubmissionChanges] 更新任务状态失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新任务状态失败"})
+			return
+		}
+	}
+
+	services.RecordActivity(tx, task.EmployerID, assignment.WorkerID, "task_submission", submission.UUID, "submission_changes_requested",
+		fmt.Sprintf("雇主要求修改您提交的工作：%s", task.Title), map[string]interface{}{
+			"task_uuid": task.UUID,
+			"version":   submission.Version,
+			"feedback":  req.Feedback,
+		})
+
+	if err := services.CommitTx(tx); err != nil {
+		log.Printf("[RequestSubmissionChanges] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "已要求零工修改",
+		"submission": formatSubmission(submission),
+		"task": gin.H{
+			"uuid":   task.UUID,
+			"status": task.Status,
+		},
+	})
+}
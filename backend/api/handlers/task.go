@@ -2,52 +2,143 @@ package handlers
 
 import (
 	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"zhlg/backend/db"
 	"zhlg/backend/models"
+	"zhlg/backend/services"
+	"zhlg/backend/utils"
 
 	"log"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// searchSnippetLength is the length in characters of highlighted description snippets
+const searchSnippetLength = 120
+
 // TaskQueryParams represents the query parameters for fetching tasks
 type TaskQueryParams struct {
-	Page         int    `form:"page,default=1"`
-	Limit        int    `form:"limit,default=10"`
-	SearchQuery  string `form:"search_query"`
-	StatusFilter string `form:"status,default=all"`
-	UserScope    string `form:"user_scope,default=all"`
-	Skills       string `form:"skills"`
-	LocationType string `form:"location_type"`
-	SortBy       string `form:"sort_by,default=created_at_desc"`
+	Page         int     `form:"page,default=1"`
+	Limit        int     `form:"limit,default=10"`
+	SearchQuery  string  `form:"search_query"`
+	StatusFilter string  `form:"status,default=all"`
+	UserScope    string  `form:"user_scope,default=all"`
+	Skills       string  `form:"skills"`
+	LocationType string  `form:"location_type"`
+	SortBy       string  `form:"sort_by,default=created_at_desc"`
+	Near         string  `form:"near"`
+	Radius       float64 `form:"radius"`
+}
+
+// Limits for geo search radius in kilometers
+const (
+	defaultSearchRadiusKm = 5
+	maxSearchRadiusKm     = 100
+)
+
+// resolveNear turns the near parameter into coordinates. It accepts "lat,lng", "me" for the
+// current user's saved location, or a city/district name resolved by the offline geocoder.
+func resolveNear(c *gin.Context, near string) (float64, float64, string, error) {
+	near = strings.TrimSpace(near)
+	if near == "me" {
+		userID, exists := c.Get("userID")
+		if !exists {
+			return 0, 0, "", fmt.Errorf("未登录")
+		}
+		var user models.User
+		if err := db.DB.Select("id", "location", "latitude", "longitude").First(&user, userID).Error; err != nil || user.Latitude == nil || user.Longitude == nil {
+			return 0, 0, "", fmt.Errorf("请先在个人资料中设置所在地")
+		}
+		name := ""
+		if user.Location != nil {
+			name = *user.Location
+		}
+		return *user.Latitude, *user.Longitude, name, nil
+	}
+
+	if parts := strings.Split(near, ","); len(parts) == 2 {
+		lat, errLat := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		lng, errLng := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if errLat == nil && errLng == nil {
+			if !utils.ValidCoordinates(lat, lng) {
+				return 0, 0, "", fmt.Errorf("坐标超出范围")
+			}
+			return lat, lng, "", nil
+		}
+	}
+
+	place, ok := utils.Geocode(near)
+	if !ok {
+		return 0, 0, "", fmt.Errorf("无法识别的位置: %s", near)
+	}
+	return place.Latitude, place.Longitude, place.Name(), nil
+}
+
+// taskSortOrders whitelists the sort_by values accepted by GetTasks. "relevance" is handled
+// separately because it depends on the search query.
+var taskSortOrders = map[string]string{
+	"created_at_desc": "tasks.created_at DESC",
+	"created_at_asc":  "tasks.created_at ASC",
+	"budget_desc":     "tasks.budget_amount DESC, tasks.created_at DESC",
+	"budget_asc":      "tasks.budget_amount ASC, tasks.created_at DESC",
+	"start_date_asc":  "tasks.start_date ASC, tasks.created_at DESC",
+	"start_date_desc": "tasks.start_date DESC, tasks.created_at DESC",
+	"urgent":          "tasks.is_urgent DESC, tasks.created_at DESC",
+	"applicants_desc": "(SELECT COUNT(*) FROM task_applications WHERE task_applications.task_id = tasks.id) DESC, tasks.created_at DESC",
+	"applicants_asc":  "(SELECT COUNT(*) FROM task_applications WHERE task_applications.task_id = tasks.id) ASC, tasks.created_at DESC",
+}
+
+// taskUserScopes whitelists the user_scope values accepted by GetTasks, mapping each to the
+// condition restricting tasks to those related to the current user
+var taskUserScopes = map[string]string{
+	"mine":      "tasks.employer_id = ?",
+	"applied":   "tasks.id IN (SELECT task_id FROM task_applications WHERE worker_id = ?)",
+	"assigned":  "tasks.id IN (SELECT task_id FROM task_assignments WHERE worker_id = ?)",
+	"favorites": "tasks.id IN (SELECT task_id FROM user_favorites WHERE user_id = ?)",
 }
 
 // CreateTaskRequest represents the request body for creating a new task
 type CreateTaskRequest struct {
-	Title           string   `json:"title" binding:"required"`
-	Description     string   `json:"description" binding:"required"`
-	LocationType    string   `json:"location_type" binding:"required,oneof=online offline"`
-	LocationDetails string   `json:"location_details"`
-	StartDate       string   `json:"start_date" binding:"required"`
-	EndDate         string   `json:"end_date" binding:"required"`
-	PaymentType     string   `json:"payment_type" binding:"required,oneof=hourly daily fixed"`
-	BudgetAmount    float64  `json:"budget_amount" binding:"required,gt=0"`
-	EstimatedHours  float64  `json:"estimated_hours" binding:"min=0"`
-	Headcount       int      `json:"headcount" binding:"required,gt=0"`
-	Skills          []string `json:"skills" binding:"required"`
-	IsPublic        bool     `json:"is_public"`
-	IsUrgent        bool     `json:"is_urgent"`
+	Title           string             `json:"title" binding:"required"`
+	Description     string             `json:"description" binding:"required"`
+	LocationType    string             `json:"location_type" binding:"required,oneof=online offline"`
+	LocationDetails string             `json:"location_details"`
+	Latitude        *float64           `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude       *float64           `json:"longitude" binding:"omitempty,min=-180,max=180"`
+	CheckInRadius   uint               `json:"check_in_radius"`
+	StartDate       string             `json:"start_date" binding:"required"`
+	EndDate         string             `json:"end_date" binding:"required"`
+	PaymentType     string             `json:"payment_type" binding:"required,oneof=hourly daily fixed"`
+	BudgetAmount    float64            `json:"budget_amount" binding:"required,gt=0"`
+	EstimatedHours  float64            `json:"estimated_hours" binding:"min=0"`
+	Headcount       int                `json:"headcount" binding:"required,gt=0"`
+	BudgetCeiling   *float64           `json:"budget_ceiling" binding:"omitempty,gt=0"`
+	Skills          []string           `json:"skills" binding:"required"`
+	IsPublic        bool               `json:"is_public"`
+	IsUrgent        bool               `json:"is_urgent"`
+	BiddingEnabled  bool               `json:"bidding_enabled"`
+	Milestones      []MilestoneRequest `json:"milestones" binding:"omitempty,dive"`
+	// ScreeningQuestions are asked of every applicant, at most 10
+	ScreeningQuestions []ScreeningQuestionRequest `json:"screening_questions" binding:"omitempty,max=10,dive"`
+	// RecruitingDeadline is an RFC3339 time after which applications are no longer taken
+	RecruitingDeadline string `json:"recruiting_deadline"`
 }
 
 // TaskApplicationRequest represents the request body for applying to a task
 type TaskApplicationRequest struct {
-	CoverLetter string `json:"cover_letter"`
+	CoverLetter string                   `json:"cover_letter"`
+	Answers     []ScreeningAnswerRequest `json:"answers" binding:"omitempty,dive"`
+	BidAmount   *float64                 `json:"bid_amount" binding:"omitempty,gt=0"`
+	BidDays     *uint                    `json:"bid_days" binding:"omitempty,min=1,max=365"`
 }
 
 // GetTasks handles fetching a list of tasks with filtering, search, and pagination
@@ -75,6 +166,50 @@ func GetTasks(c *gin.Context) {
 		params.Limit = 10
 	}
 
+	if params.SortBy == "" {
+		params.SortBy = "created_at_desc"
+	}
+	if params.UserScope == "" {
+		params.UserScope = "all"
+	}
+	if _, ok := taskSortOrders[params.SortBy]; !ok && params.SortBy != "relevance" && params.SortBy != "distance" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的排序方式", "details": params.SortBy})
+		return
+	}
+	scopeCondition, ok := taskUserScopes[params.UserScope]
+	if !ok && params.UserScope != "all" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的筛选参数", "details": params.UserScope})
+		return
+	}
+	userID, loggedIn := c.Get("userID")
+	if ok && !loggedIn {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	var viewerID uint
+	if loggedIn {
+		viewerID = userID.(uint)
+	}
+
+	var nearLat, nearLng float64
+	var nearName string
+	nearby := params.Near != ""
+	if nearby {
+		var err error
+		nearLat, nearLng, nearName, err = resolveNear(c, params.Near)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的位置参数", "details": err.Error()})
+			return
+		}
+		if params.Radius == 0 {
+			params.Radius = defaultSearchRadiusKm
+		}
+		if params.Radius < 0 || params.Radius > maxSearchRadiusKm {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的位置参数", "details": fmt.Sprintf("搜索半径需在0到%d公里之间", maxSearchRadiusKm)})
+			return
+		}
+	}
+
 	var tasks []models.Task
 	query := db.DB.Debug().Model(&models.Task{}).Preload("Employer").Preload("Skills")
 
@@ -97,27 +232,119 @@ func GetTasks(c *gin.Context) {
 	} else {
 		log.Printf("[GetTasks] 不添加状态筛选")
 	}
-	if params.SearchQuery != "" {
-		like := "%" + params.SearchQuery + "%"
-		query = query.Where("title LIKE ? OR description LIKE ?", like, like)
+	searchQuery := strings.TrimSpace(params.SearchQuery)
+	rankable := false
+	if searchQuery != "" {
+		query, rankable = services.SearchTasks(query, searchQuery)
 	}
 	if params.Skills != "" {
-		skillNames := strings.Split(params.Skills, ",")
-		query = query.Joins("JOIN task_skills ON tasks.id = task_skills.task_id").Joins("JOIN skills ON task_skills.skill_id = skills.id").Where("skills.name IN ?", skillNames)
+		skillIDs, err := services.SkillFilterIDs(db.DB, params.Skills)
+		if err != nil {
+			log.Printf("[GetTasks] 解析技能筛选失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务失败"})
+			return
+		}
+		query = query.Where("tasks.id IN (SELECT task_id FROM task_skills WHERE skill_id IN ?)", skillIDs)
 	}
 	if params.LocationType != "" {
 		query = query.Where("location_type = ?", params.LocationType)
 	}
-	// 用户过滤（如我的任务、收藏等）可根据业务需求补充
+	if nearby {
+		query = services.NearTasks(query, nearLat, nearLng, params.Radius)
+	}
+	// 用户过滤：我发布的、我申请的、我执行的、我收藏的
+	if scopeCondition != "" {
+		query = query.Where(scopeCondition, userID)
+	}
+	// 草稿只对发布者可见
+	query = query.Where("(tasks.status <> ? OR tasks.employer_id = ?)", models.TaskStatusDraft, viewerID)
 
 	totalCount := int64(0)
 	query.Count(&totalCount)
 	query = query.Offset((params.Page - 1) * params.Limit).Limit(params.Limit)
-	query.Order("created_at DESC").Find(&tasks)
+	switch {
+	case params.SortBy == "relevance" && rankable:
+		query = services.OrderTasksByRelevance(query, searchQuery)
+	case params.SortBy == "distance" && nearby:
+		query = services.OrderTasksByDistance(query, nearLat, nearLng)
+	case params.SortBy == "relevance" || params.SortBy == "distance":
+		query = query.Order(taskSortOrders["created_at_desc"])
+	default:
+		query = query.Order(taskSortOrders[params.SortBy])
+	}
+	query.Find(&tasks)
+
+	tasksResp := formatTaskList(tasks, viewerID)
+	for i, t := range tasks {
+		if nearby && t.Latitude != nil && t.Longitude != nil {
+			distance := utils.DistanceMeters(nearLat, nearLng, *t.Latitude, *t.Longitude)
+			tasksResp[i]["distance_km"] = math.Round(distance/10) / 100
+		}
+		if searchQuery != "" {
+			tasksResp[i]["highlight"] = gin.H{
+				"title":       utils.Highlight(t.Title, searchQuery, 0),
+				"description": utils.Highlight(t.Description, searchQuery, searchSnippetLength),
+			}
+		}
+	}
 
-	// 格式化返回
+	response := gin.H{
+		"tasks": tasksResp,
+		"pagination": gin.H{
+			"current_page":   params.Page,
+			"total_pages":    (totalCount + int64(params.Limit) - 1) / int64(params.Limit),
+			"total_items":    totalCount,
+			"items_per_page": params.Limit,
+		},
+	}
+	if nearby {
+		response["near"] = gin.H{
+			"latitude":  nearLat,
+			"longitude": nearLng,
+			"name":      nearName,
+			"radius_km": params.Radius,
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// formatTaskList converts tasks into list items with applicant counts. For a logged-in
+// viewer each item also says whether they favorited it, and employers see how many users
+// favorited their own tasks.
+func formatTaskList(tasks []models.Task, viewerID uint) []gin.H {
+	items := make([]gin.H, 0, len(tasks))
+	if len(tasks) == 0 {
+		return items
+	}
+
+	taskIDs := make([]uint, 0, len(tasks))
+	for _, t := range tasks {
+		taskIDs = append(taskIDs, t.ID)
+	}
+	var rows []struct {
+		TaskID uint
+		Count  int64
+	}
+	db.DB.Model(&models.TaskApplication{}).Select("task_id, COUNT(*) AS count").
+		Where("task_id IN ?", taskIDs).Group("task_id").Scan(&rows)
+	applicantCounts := make(map[uint]int64, len(rows))
+	for _, r := range rows {
+		applicantCounts[r.TaskID] = r.Count
+	}
+
+	var favorited map[uint]bool
+	var favoriteCounts map[uint]int64
+	if viewerID != 0 {
+		favorited = favoritedTaskIDs(viewerID, taskIDs)
+		ownTaskIDs := make([]uint, 0)
+		for _, t := range tasks {
+			if t.EmployerID == viewerID {
+				ownTaskIDs = append(ownTaskIDs, t.ID)
+			}
+		}
+		favoriteCounts = taskFavoriteCounts(ownTaskIDs)
+	}
 
-	tasksResp := make([]gin.H, 0, len(tasks))
 	for _, t := range tasks {
 		skills := make([]string, 0)
 		for _, s := range t.Skills {
@@ -127,7 +354,7 @@ func GetTasks(c *gin.Context) {
 		if t.LocationType == models.LocationTypeOffline && t.LocationDetails != nil {
 			locationDisplay = *t.LocationDetails
 		}
-		tasksResp = append(tasksResp, gin.H{
+		item := gin.H{
 			"uuid":        t.UUID,
 			"title":       t.Title,
 			"description": t.Description,
@@ -142,20 +369,18 @@ func GetTasks(c *gin.Context) {
 			"start_date":       t.StartDate.Format("2006-01-02"),
 			"end_date":         t.EndDate.Format("2006-01-02"),
 			"budget_display":   t.BudgetDisplay(),
-			"applicants_count": len(t.Applications),
+			"applicants_count": applicantCounts[t.ID],
 			"created_at":       t.CreatedAt.Format(time.RFC3339),
-		})
+		}
+		if viewerID != 0 {
+			item["is_favorited"] = favorited[t.ID]
+			if t.EmployerID == viewerID {
+				item["favorites_count"] = favoriteCounts[t.ID]
+			}
+		}
+		items = append(items, item)
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"tasks": tasksResp,
-		"pagination": gin.H{
-			"current_page":   params.Page,
-			"total_pages":    (totalCount + int64(params.Limit) - 1) / int64(params.Limit),
-			"total_items":    totalCount,
-			"items_per_page": params.Limit,
-		},
-	})
+	return items
 }
 
 // CreateTask handles creating a new task by an employer
@@ -179,23 +404,38 @@ func CreateTask(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
 		return
 	}
-	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	task, milestones, err := buildTask(user.ID, &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": "开始日期格式不正确"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+	if err := saveNewTask(&task, milestones, req.Skills); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "任务创建失败", "details": err.Error()})
 		return
 	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "任务发布成功",
+		"task":    formatCreatedTask(&task, &user),
+	})
+}
+
+// buildTask validates a create request and turns it into an unsaved, published task with
+// its milestones. Screening questions are set on the task and saved along with it. The
+// returned error describes the invalid field.
+func buildTask(employerID uint, req *CreateTaskRequest) (models.Task, []models.TaskMilestone, error) {
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		return models.Task{}, nil, fmt.Errorf("开始日期格式不正确")
+	}
 	endDate, err := time.Parse("2006-01-02", req.EndDate)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": "结束日期格式不正确"})
-		return
+		return models.Task{}, nil, fmt.Errorf("结束日期格式不正确")
 	}
 	if startDate.Before(time.Now().Truncate(24 * time.Hour)) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": "开始日期不能早于今天"})
-		return
+		return models.Task{}, nil, fmt.Errorf("开始日期不能早于今天")
 	}
 	if endDate.Before(startDate) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": "结束日期不能早于开始日期"})
-		return
+		return models.Task{}, nil, fmt.Errorf("结束日期不能早于开始日期")
 	}
 	var locationType models.LocationType
 	switch req.LocationType {
@@ -213,9 +453,10 @@ func CreateTask(c *gin.Context) {
 	case "fixed":
 		paymentType = models.PaymentTypeFixed
 	}
+	now := time.Now()
 	task := models.Task{
 		UUID:           uuid.New().String(),
-		EmployerID:     user.ID,
+		EmployerID:     employerID,
 		Title:          req.Title,
 		Description:    req.Description,
 		LocationType:   locationType,
@@ -226,53 +467,172 @@ func CreateTask(c *gin.Context) {
 		EstimatedHours: req.EstimatedHours,
 		Headcount:      uint(req.Headcount),
 		Status:         models.TaskStatusRecruiting,
+		BudgetCeiling:  req.BudgetCeiling,
 		IsPublic:       req.IsPublic,
 		IsUrgent:       req.IsUrgent,
+		BiddingEnabled: req.BiddingEnabled,
+		PublishedAt:    &now,
+	}
+	if req.RecruitingDeadline != "" {
+		deadline, err := time.Parse(time.RFC3339, req.RecruitingDeadline)
+		if err != nil {
+			return models.Task{}, nil, fmt.Errorf("招募截止时间格式不正确")
+		}
+		if !deadline.After(now) {
+			return models.Task{}, nil, fmt.Errorf("招募截止时间必须晚于当前时间")
+		}
+		if !deadline.Before(endDate.AddDate(0, 0, 1)) {
+			return models.Task{}, nil, fmt.Errorf("招募截止时间不能晚于结束日期")
+		}
+		task.RecruitingDeadline = &deadline
 	}
 	if locationType == models.LocationTypeOffline && req.LocationDetails != "" {
-		task.LocationDetails = &req.LocationDetails
+		locationDetails := req.LocationDetails
+		task.LocationDetails = &locationDetails
+	}
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		return models.Task{}, nil, fmt.Errorf("经纬度需要同时提供")
+	}
+	if locationType == models.LocationTypeOffline && req.Latitude != nil {
+		task.Latitude = req.Latitude
+		task.Longitude = req.Longitude
+		task.CheckInRadius = req.CheckInRadius
+	} else if task.LocationDetails != nil {
+		// 未提供坐标时按地址解析到区县中心，仅用于附近搜索
+		if place, ok := utils.Geocode(*task.LocationDetails); ok {
+			task.Latitude = &place.Latitude
+			task.Longitude = &place.Longitude
+			task.GeoApproximate = true
+		}
 	}
+	var milestones []models.TaskMilestone
+	if len(req.Milestones) > 0 {
+		if req.BiddingEnabled {
+			return models.Task{}, nil, fmt.Errorf("报价任务不能预设里程碑")
+		}
+		milestones, err = buildMilestones(&task, req.Milestones, 0, 0)
+		if err != nil {
+			return models.Task{}, nil, err
+		}
+	}
+	if len(req.ScreeningQuestions) > 0 {
+		if task.ScreeningQuestions, err = buildScreeningQuestions(req.ScreeningQuestions); err != nil {
+			return models.Task{}, nil, err
+		}
+	}
+	return task, milestones, nil
+}
+
+// saveNewTask stores a built task with its milestones and skills. Published tasks are
+// matched against saved searches in the background.
+func saveNewTask(task *models.Task, milestones []models.TaskMilestone, skillNames []string) error {
+	skills, err := services.ResolveSkills(db.DB, skillNames)
+	if err != nil {
+		return err
+	}
+	if err := services.CreateTaskWithParts(db.DB, task, milestones, skills); err != nil {
+		return err
+	}
+	if task.Status == models.TaskStatusRecruiting {
+		announceTask(task.ID)
+	}
+	return nil
+}
+
+// PublishTask handles publishing a draft task so workers can find and apply to it. Drafts
+// saved incomplete are validated like a new task first.
+func PublishTask(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var task models.Task
+	if err := db.DB.Where("uuid = ?", c.Param("uuid")).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	if task.EmployerID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的发布者"})
+		return
+	}
+	if task.Status != models.TaskStatusDraft {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "只有草稿可以发布"})
+		return
+	}
+	// 草稿在发布时才校验必填项
+	if !finalizeDraft(c, &task) {
+		return
+	}
+	if task.StartDate.Before(time.Now().Truncate(24 * time.Hour)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": "开始日期不能早于今天"})
+		return
+	}
+
 	now := time.Now()
+	task.Status = models.TaskStatusRecruiting
 	task.PublishedAt = &now
-	if err := db.DB.Create(&task).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "任务创建失败", "details": err.Error()})
+	task.ScheduledPublishAt = nil
+	if err := db.DB.Model(&task).Updates(map[string]interface{}{
+		"status":               task.Status,
+		"published_at":         task.PublishedAt,
+		"scheduled_publish_at": nil,
+	}).Error; err != nil {
+		log.Printf("[PublishTask] 发布任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "发布任务失败"})
 		return
 	}
-	// 处理技能
-	for _, skillName := range req.Skills {
-		var skill models.Skill
-		db.DB.Where("name = ?", skillName).FirstOrCreate(&skill, models.Skill{Name: skillName})
-		db.DB.Model(&task).Association("Skills").Append(&skill)
-	}
+	announceTask(task.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "任务发布成功",
+		"task": gin.H{
+			"uuid":         task.UUID,
+			"status":       task.Status,
+			"published_at": task.PublishedAt.Format(time.RFC3339),
+		},
+	})
+}
+
+// announceTask matches a newly published task against saved searches in the background
+func announceTask(taskID uint) {
+	go func() {
+		if err := services.MatchSavedSearches(db.DB, taskID); err != nil {
+			log.Printf("[announceTask] 匹配保存的搜索失败: %v", err)
+		}
+	}()
+}
+
+// formatCreatedTask converts a newly created task into the response returned by the
+// endpoints that create tasks
+func formatCreatedTask(task *models.Task, employer *models.User) gin.H {
 	locationDisplay := "线上远程"
-	if locationType == models.LocationTypeOffline && task.LocationDetails != nil {
+	if task.LocationType == models.LocationTypeOffline && task.LocationDetails != nil {
 		locationDisplay = *task.LocationDetails
 	}
 	skills := make([]string, 0)
 	for _, s := range task.Skills {
 		skills = append(skills, s.Name)
 	}
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "任务发布成功",
-		"task": gin.H{
-			"uuid":        task.UUID,
-			"title":       task.Title,
-			"description": task.Description,
-			"employer": gin.H{
-				"uuid":       user.UUID,
-				"name":       user.Name,
-				"avatar_url": user.AvatarURL,
-			},
-			"status":           task.Status,
-			"skills":           skills,
-			"location":         locationDisplay,
-			"start_date":       task.StartDate.Format("2006-01-02"),
-			"end_date":         task.EndDate.Format("2006-01-02"),
-			"budget_display":   task.BudgetDisplay(),
-			"applicants_count": 0,
-			"created_at":       task.CreatedAt.Format(time.RFC3339),
+	return gin.H{
+		"uuid":        task.UUID,
+		"title":       task.Title,
+		"description": task.Description,
+		"employer": gin.H{
+			"uuid":       employer.UUID,
+			"name":       employer.Name,
+			"avatar_url": employer.AvatarURL,
 		},
-	})
+		"status":           task.Status,
+		"skills":           skills,
+		"location":         locationDisplay,
+		"start_date":       task.StartDate.Format("2006-01-02"),
+		"end_date":         task.EndDate.Format("2006-01-02"),
+		"budget_display":   task.BudgetDisplay(),
+		"applicants_count": 0,
+		"created_at":       task.CreatedAt.Format(time.RFC3339),
+	}
 }
 
 // GetTaskByUUID handles retrieving task details by UUID
@@ -313,11 +673,18 @@ func GetTaskByUUID(c *gin.Context) {
 	}
 
 	var task models.Task
-	err := db.DB.Preload("Employer").Preload("Skills").Preload("Applications.Worker").Where("uuid = ?", taskUUID).First(&task).Error
+	err := db.DB.Preload("Employer").Preload("Skills").Preload("Applications.Worker").Preload("Applications.ScreeningAnswers").
+		Preload("ScreeningQuestions", func(tx *gorm.DB) *gorm.DB {
+			return tx.Order("sort_order ASC")
+		}).Where("uuid = ?", taskUUID).First(&task).Error
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "任务未找到"})
 		return
 	}
+	if task.Status == models.TaskStatusDraft && task.EmployerID != currentUserID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务未找到"})
+		return
+	}
 
 	log.Printf("[GetTaskByUUID] 任务ID: %d, UUID: %s, 标题: %s, 雇主ID: %d, 申请数量: %d",
 		task.ID, task.UUID, task.Title, task.EmployerID, len(task.Applications))
@@ -398,6 +765,12 @@ func GetTaskByUUID(c *gin.Context) {
 			if app.CoverLetter != nil {
 				applicantInfo["cover_letter"] = *app.CoverLetter
 			}
+			applicantInfo["knocked_out"] = app.KnockedOut
+			if task.BiddingEnabled {
+				applicantInfo["bid_amount"] = app.BidAmount
+				applicantInfo["bid_days"] = app.BidDays
+			}
+			applicantInfo["screening_answers"] = formatScreeningAnswers(task.ScreeningQuestions, app.ScreeningAnswers)
 		}
 
 		applicants = append(applicants, applicantInfo)
@@ -428,17 +801,64 @@ func GetTaskByUUID(c *gin.Context) {
 		"start_date":       task.StartDate.Format("2006-01-02"),
 		"end_date":         task.EndDate.Format("2006-01-02"),
 		"budget_display":   task.BudgetDisplay(),
+		"latitude":         task.Latitude,
+		"longitude":        task.Longitude,
 		"payment_type":     task.PaymentType,
 		"budget_amount":    task.BudgetAmount,
 		"headcount":        task.Headcount,
 		"is_public":        task.IsPublic,
 		"is_urgent":        task.IsUrgent,
+		"bidding_enabled":  task.BiddingEnabled,
 		"applicants_count": len(task.Applications),
 		"created_at":       task.CreatedAt.Format(time.RFC3339),
 	}
 
+	// 申请前需要回答的筛选问题，淘汰答案只对雇主可见
+	screeningQuestions := make([]gin.H, 0, len(task.ScreeningQuestions))
+	for i := range task.ScreeningQuestions {
+		screeningQuestions = append(screeningQuestions, formatScreeningQuestion(&task.ScreeningQuestions[i], isEmployer))
+	}
+	response["screening_questions"] = screeningQuestions
+
+	// 公开问答，雇主可以看到全部问题及审核状态
+	questions, err := taskQuestionsFor(&task, currentUserID, isEmployer)
+	if err != nil {
+		log.Printf("[GetTaskByUUID] 查询问答失败: %v", err)
+		questions = []gin.H{}
+	}
+	response["questions"] = questions
+
 	log.Printf("[GetTaskByUUID] currentUserID=%v, task.EmployerID=%v, applicants=%d", currentUserID, task.EmployerID, len(applicants))
 
+	// 雇主和执行者可以看到里程碑
+	if isEmployer || isWorker {
+		var milestones []models.TaskMilestone
+		db.DB.Where("task_id = ?", task.ID).Order("sort_order ASC").Find(&milestones)
+		milestoneItems := make([]gin.H, 0, len(milestones))
+		for _, m := range milestones {
+			milestoneItems = append(milestoneItems, formatMilestone(m))
+		}
+		response["milestones"] = milestoneItems
+		response["submissions"] = taskSubmissionsFor(&task, currentUserID, isEmployer)
+		if task.IsGeofenced() {
+			response["check_in_radius"] = checkInRadius(&task)
+		}
+	}
+
+	// 登录用户可以看到是否已收藏，雇主可以看到收藏人数
+	if exists {
+		response["is_favorited"] = favoritedTaskIDs(currentUserID, []uint{task.ID})[task.ID]
+	}
+	if isEmployer {
+		response["favorites_count"] = taskFavoriteCounts([]uint{task.ID})[task.ID]
+	}
+
+	if isEmployer && task.Status == models.TaskStatusDraft {
+		req := draftRequest(&task)
+		response["missing_fields"] = draftMissingFields(&req)
+		response["scheduled_publish_at"] = task.ScheduledPublishAt
+	}
+
 	// 只对特定用户添加额外字段
 	response["is_applicant"] = isApplicant
 	response["is_worker"] = isWorker
@@ -482,14 +902,29 @@ func ApplyToTask(c *gin.Context) {
 		return
 	}
 
+	// 请求体可选
 	var req TaskApplicationRequest
-	_ = c.ShouldBindJSON(&req) // cover letter 可选
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
 
 	var task models.Task
-	if err := db.DB.Where("uuid = ? AND status = ?", taskUUID, models.TaskStatusRecruiting).First(&task).Error; err != nil {
+	if err := db.DB.Preload("ScreeningQuestions", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("sort_order ASC")
+	}).Where("uuid = ? AND status = ?", taskUUID, models.TaskStatusRecruiting).First(&task).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "任务未找到或已关闭"})
 		return
 	}
+	if task.RecruitingDeadline != nil && !time.Now().Before(*task.RecruitingDeadline) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该任务已截止招募"})
+		return
+	}
+	// 结束日期当天过后不再接受申请，不依赖定时任务是否已关闭该任务
+	if !time.Now().Before(workDate(task.EndDate).AddDate(0, 0, 1)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "任务结束日期已过"})
+		return
+	}
 
 	var existingApplication models.TaskApplication
 	if err := db.DB.Where("task_id = ? AND worker_id = ?", task.ID, user.ID).First(&existingApplication).Error; err == nil {
@@ -497,24 +932,70 @@ func ApplyToTask(c *gin.Context) {
 		return
 	}
 
+	if err := validateApplicationBid(&task, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	answers, knockedOut, err := buildScreeningAnswers(task.ScreeningQuestions, req.Answers)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
 	application := models.TaskApplication{
-		UUID:      uuid.New().String(),
-		TaskID:    task.ID,
-		WorkerID:  user.ID,
-		Status:    models.ApplicationStatusPending,
-		AppliedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		UUID:             uuid.New().String(),
+		TaskID:           task.ID,
+		WorkerID:         user.ID,
+		Status:           models.ApplicationStatusPending,
+		AppliedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+		ScreeningAnswers: answers,
+		BidAmount:        req.BidAmount,
+		BidDays:          req.BidDays,
 	}
 	if req.CoverLetter != "" {
 		coverLetter := req.CoverLetter
 		application.CoverLetter = &coverLetter
 	}
+	// 命中淘汰答案的申请直接拒绝
+	if knockedOut {
+		application.Reject()
+		application.KnockedOut = true
+	}
 	if err := db.DB.Create(&application).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "任务申请失败", "details": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "任务申请成功"})
+	if !knockedOut {
+		services.RecordActivity(db.DB, user.ID, task.EmployerID, "task_application", application.UUID, "application_received",
+			fmt.Sprintf("%s申请了您的任务：%s", user.DisplayName(), task.Title), map[string]interface{}{
+				"task_uuid":  task.UUID,
+				"bid_amount": application.BidAmount,
+			})
+	}
+
+	if knockedOut {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "很抱歉，您的回答不符合该任务的要求，申请未通过",
+			"status":  application.Status,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "任务申请成功", "status": application.Status})
+}
+
+// validateApplicationBid checks that an application quotes a bid exactly when the task
+// takes bids
+func validateApplicationBid(task *models.Task, req *TaskApplicationRequest) error {
+	if task.BiddingEnabled && req.BidAmount == nil {
+		return fmt.Errorf("该任务需要填写报价")
+	}
+	if !task.BiddingEnabled && (req.BidAmount != nil || req.BidDays != nil) {
+		return fmt.Errorf("该任务不接受报价")
+	}
+	return nil
 }
 
 // CompleteTask handles task completion by the worker
@@ -567,14 +1048,45 @@ func CompleteTask(c *gin.Context) {
 		return
 	}
 
+	if assignment.WorkerStatus == models.WorkerStatusQuit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "您已退出该任务"})
+		return
+	}
+
+	if assignment.WorkerStatus != models.WorkerStatusWorking {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "您已提交工作，等待雇主审核"})
+		return
+	}
+
 	// Check if task status allows completion
 	if task.Status != models.TaskStatusInProgress {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "任务不在进行中状态，无法完成"})
 		return
 	}
 
+	// Milestone tasks complete once the employer has approved every milestone
+	if taskHasMilestones(task.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该任务按里程碑付款，请逐个提交里程碑"})
+		return
+	}
+
+	// Read deliverables (notes, links, files)
+	notes, attachments, err := parseSubmissionPayload(c, task.UUID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	// Remove the uploaded files again unless the submission is stored
+	stored := false
+	defer func() {
+		if !stored {
+			removeSubmissionFiles(attachments)
+		}
+	}()
+
 	// Start transaction
-	tx := db.DB.Begin()
+	tx := services.BeginTx(db.DB)
 
 	// Submit work
 	assignment.SubmitWork()
@@ -585,6 +1097,29 @@ func CompleteTask(c *gin.Context) {
 		return
 	}
 
+	// Close a work session left open when the worker submits
+	if isTimeTracked(&task) {
+		var open models.TimesheetEntry
+		if err := tx.Where("task_assignment_id = ? AND status = ?", assignment.ID, models.TimesheetStatusOpen).First(&open).Error; err == nil {
+			open.ClockOut(time.Now())
+			if err := tx.Save(&open).Error; err != nil {
+				tx.Rollback()
+				log.Printf("Error closing timesheet entry: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "更新工时记录失败"})
+				return
+			}
+		}
+	}
+
+	// Store the deliverables as a new submission version
+	submission, err := createSubmission(tx, &assignment, notes, attachments)
+	if err != nil {
+		tx.Rollback()
+		log.Printf("Error creating submission: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存提交内容失败"})
+		return
+	}
+
 	// Update task status to payment_pending
 	task.Status = models.TaskStatusPaymentPending
 	if err := tx.Save(&task).Error; err != nil {
@@ -594,12 +1129,20 @@ func CompleteTask(c *gin.Context) {
 		return
 	}
 
+	services.RecordActivity(tx, assignment.WorkerID, task.EmployerID, "task_submission", submission.UUID, "submission_received",
+		fmt.Sprintf("零工提交了任务成果：%s", task.Title), map[string]interface{}{
+			"task_uuid":       task.UUID,
+			"assignment_uuid": assignment.UUID,
+			"version":         submission.Version,
+		})
+
 	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
+	if err := services.CommitTx(tx); err != nil {
 		log.Printf("Error committing transaction: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "完成任务失败"})
 		return
 	}
+	stored = true
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "任务已完成，等待雇主确认",
@@ -607,6 +1150,7 @@ func CompleteTask(c *gin.Context) {
 			"uuid":   task.UUID,
 			"status": task.Status,
 		},
+		"submission": formatSubmission(*submission),
 	})
 }
 
@@ -660,6 +1204,12 @@ func ConfirmTaskCompletion(c *gin.Context) {
 		return
 	}
 
+	// 按里程碑付款的任务在最后一个里程碑付款时自动完成
+	if taskHasMilestones(task.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该任务按里程碑付款，请逐个确认里程碑"})
+		return
+	}
+
 	// Find the task assignment
 	var assignments []models.TaskAssignment
 	if err := db.DB.Where("task_id = ? AND employer_status = ?", task.ID, models.EmployerStatusReviewPending).Find(&assignments).Error; err != nil {
@@ -673,8 +1223,14 @@ func ConfirmTaskCompletion(c *gin.Context) {
 		return
 	}
 
+	// 按小时/按天计费的任务根据已批准的工时付款，需先审批全部工时
+	if isTimeTracked(&task) && countUnreviewedTimesheets(assignments) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "还有待审批的工时记录，请先审批工时"})
+		return
+	}
+
 	// Start transaction
-	tx := db.DB.Begin()
+	tx := services.BeginTx(db.DB)
 
 	// Update task status to completed
 	task.Status = models.TaskStatusCompleted
@@ -694,13 +1250,35 @@ func ConfirmTaskCompletion(c *gin.Context) {
 			continue
 		}
 
+		// Accept the submission under review
+		if err := tx.Model(&models.TaskSubmission{}).
+			Where("task_assignment_id = ? AND status = ?", assignment.ID, models.SubmissionStatusSubmitted).
+			Updates(map[string]interface{}{"status": models.SubmissionStatusAccepted, "reviewed_at": assignment.CompletedAt}).Error; err != nil {
+			log.Printf("Error accepting submission for assignment %d: %v", assignment.ID, err)
+		}
+
+		// Fixed tasks pay each worker their contracted amount, hourly and daily tasks pay
+		// approved time times the contracted rate
+		amount := fixedPayout(&task, &assignment)
+		if isTimeTracked(&task) {
+			var err error
+			if _, amount, err = timesheetPayout(tx, &task, &assignment); err != nil {
+				log.Printf("Error calculating timesheet payout for assignment %d: %v", assignment.ID, err)
+				continue
+			}
+			if amount <= 0 {
+				successCount++
+				continue
+			}
+		}
+
 		// Create transaction for worker payment
 		paymentTransaction := models.Transaction{
 			UUID:             uuid.New().String(),
 			UserID:           uint(assignment.WorkerID),
 			TaskAssignmentID: &assignment.ID,
 			Type:             models.TransactionTypeEarning,
-			Amount:           task.BudgetAmount / float64(len(assignments)), // Split payment if multiple workers
+			Amount:           amount,
 			Currency:         task.Currency,
 			Status:           models.TransactionStatusCompleted,
 			Title:            "任务完成报酬",
@@ -731,11 +1309,18 @@ func ConfirmTaskCompletion(c *gin.Context) {
 			continue
 		}
 
+		services.RecordActivity(tx, task.EmployerID, assignment.WorkerID, "transaction", paymentTransaction.UUID, "payment_received",
+			fmt.Sprintf("您已收到任务报酬：%s", task.Title), map[string]interface{}{
+				"task_uuid": task.UUID,
+				"amount":    paymentTransaction.Amount,
+				"currency":  paymentTransaction.Currency,
+			})
+
 		successCount++
 	}
 
 	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
+	if err := services.CommitTx(tx); err != nil {
 		log.Printf("Error committing transaction: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "确认任务完成失败"})
 		return
@@ -755,7 +1340,18 @@ func ConfirmTaskCompletion(c *gin.Context) {
 	})
 }
 
-// AcceptTaskApplication handles accepting a worker's application by the task owner
+// AcceptApplicationRequest represents the optional terms an employer hires a worker on,
+// overriding the bid and the task budget. On fixed-price tasks ContractedAmount is the
+// worker's total pay; on hourly and daily tasks ContractedRate is their rate and
+// ContractedAmount an optional payout cap.
+type AcceptApplicationRequest struct {
+	ContractedAmount *float64 `json:"contracted_amount" binding:"omitempty,gt=0"`
+	ContractedRate   *float64 `json:"contracted_rate" binding:"omitempty,gt=0"`
+}
+
+// AcceptTaskApplication handles accepting a worker's application by the task owner. Each
+// worker is hired on their own contract, so workers on the same task can be paid
+// different amounts or rates.
 func AcceptTaskApplication(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -769,6 +1365,13 @@ func AcceptTaskApplication(c *gin.Context) {
 		return
 	}
 
+	// 请求体可选
+	var req AcceptApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
 	var application models.TaskApplication
 	if err := db.DB.Preload("Task").Where("uuid = ?", applicationUUID).First(&application).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "申请不存在"})
@@ -787,8 +1390,8 @@ func AcceptTaskApplication(c *gin.Context) {
 		return
 	}
 
-	// 验证任务状态是否为招募中
-	if task.Status != models.TaskStatusRecruiting {
+	// 验证任务状态是否为招募中，进行中的任务在有空缺名额时（如零工退出）也可以接受申请
+	if task.Status != models.TaskStatusRecruiting && task.Status != models.TaskStatusInProgress {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "任务不在招募阶段"})
 		return
 	}
@@ -799,34 +1402,50 @@ func AcceptTaskApplication(c *gin.Context) {
 		return
 	}
 
+	// 约定报酬：默认按任务预算或采纳的报价，雇主可为每位零工单独指定
+	amount, rate := applicationContract(&task, &application)
+	if req.ContractedRate != nil {
+		if !isTimeTracked(&task) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "固定价格任务不能设置约定费率，请设置约定金额"})
+			return
+		}
+		rate = req.ContractedRate
+	}
+	if req.ContractedAmount != nil {
+		amount = req.ContractedAmount
+	}
+
 	// 开始事务
-	tx := db.DB.Begin()
+	tx := services.BeginTx(db.DB)
 
-	// 1. 更新申请状态为已接受
-	application.Accept()
-	if err := tx.Save(&application).Error; err != nil {
+	// 锁定任务行，避免并发接受申请超出招募人数
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&task, application.TaskID).Error; err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新申请状态失败"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "任务信息获取失败"})
 		return
 	}
-
-	// 2. 创建任务分配记录
-	assignment := models.TaskAssignment{
-		UUID:              uuid.New().String(),
-		TaskApplicationID: &application.ID,
-		TaskID:            application.TaskID,
-		WorkerID:          application.WorkerID,
-		AssignedAt:        time.Now(),
-		WorkerStatus:      models.WorkerStatusWorking,
-		EmployerStatus:    models.EmployerStatusInProgress,
+	open, err := taskHasOpenSlot(tx, &task)
+	if err != nil {
+		tx.Rollback()
+		log.Printf("[AcceptTaskApplication] 查询任务分配失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务分配失败"})
+		return
+	}
+	if !open {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "任务已关闭或名额已满"})
+		return
 	}
-	if err := tx.Create(&assignment).Error; err != nil {
+
+	// 1. 更新申请状态为已接受并按约定报酬创建任务分配记录
+	assignment, err := assignApplication(tx, &application, amount, rate)
+	if err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建任务分配失败"})
 		return
 	}
 
-	// 3. 更新任务状态为进行中
+	// 2. 更新任务状态为进行中
 	task.Status = models.TaskStatusInProgress
 	if err := tx.Save(&task).Error; err != nil {
 		tx.Rollback()
@@ -834,16 +1453,28 @@ func AcceptTaskApplication(c *gin.Context) {
 		return
 	}
 
+	// 3. 通知零工申请已通过
+	services.RecordActivity(tx, task.EmployerID, application.WorkerID, "task_assignment", assignment.UUID, "application_accepted",
+		fmt.Sprintf("您的申请已通过：%s", task.Title), map[string]interface{}{
+			"task_uuid":         task.UUID,
+			"application_uuid":  application.UUID,
+			"contracted_amount": assignment.ContractedAmount,
+			"contracted_rate":   assignment.ContractedRate,
+		})
+
 	// 提交事务
-	if err := tx.Commit().Error; err != nil {
+	if err := services.CommitTx(tx); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "接受申请失败"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "已成功接受申请，任务已进入进行中状态",
-		"task_id": task.UUID,
-		"status":  task.Status,
+		"message":           "已成功接受申请，任务已进入进行中状态",
+		"task_id":           task.UUID,
+		"status":            task.Status,
+		"assignment_uuid":   assignment.UUID,
+		"contracted_amount": assignment.ContractedAmount,
+		"contracted_rate":   assignment.ContractedRate,
 	})
 }
 
@@ -0,0 +1,558 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+	"zhlg/backend/services"
+	"zhlg/backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxTaskQuestionLength caps the length of a question or an answer, in characters
+const maxTaskQuestionLength = 1000
+
+// maxOpenTaskQuestions caps how many unanswered questions a user can have on one task
+const maxOpenTaskQuestions = 3
+
+// TaskQuestionRequest represents a question asked about a task
+type TaskQuestionRequest struct {
+	Question string `json:"question" binding:"required"`
+}
+
+// TaskAnswerRequest represents the employer's answer to a task question
+type TaskAnswerRequest struct {
+	Answer string `json:"answer" binding:"required"`
+}
+
+// TaskQuestionReportRequest represents a report of an inappropriate task question
+type TaskQuestionReportRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// TaskQuestionModerationRequest represents a moderation decision on a task question
+type TaskQuestionModerationRequest struct {
+	Status string `json:"status" binding:"required,oneof=visible hidden"`
+	Note   string `json:"note" binding:"max=255"`
+}
+
+// taskQuestionReportThreshold is how many reports send a question back to moderation
+func taskQuestionReportThreshold() int64 {
+	return int64(utils.EnvInt("TASK_QUESTION_REPORT_THRESHOLD", 3))
+}
+
+// formatTaskQuestion converts a task question into its API representation. The moderation
+// status is only shown to the employer, admins and the asker.
+func formatTaskQuestion(q *models.TaskQuestion, withModeration bool) gin.H {
+	item := gin.H{
+		"uuid":     q.UUID,
+		"question": q.Question,
+		"asker": gin.H{
+			"uuid":       q.Asker.UUID,
+			"name":       q.Asker.Name,
+			"avatar_url": q.Asker.AvatarURL,
+		},
+		"answer":      q.Answer,
+		"answered_at": nil,
+		"created_at":  q.CreatedAt.Format(time.RFC3339),
+	}
+	if q.AnsweredAt != nil {
+		item["answered_at"] = q.AnsweredAt.Format(time.RFC3339)
+	}
+	if withModeration {
+		item["status"] = q.Status
+		item["moderation_note"] = q.ModerationNote
+		item["report_count"] = q.ReportCount
+	}
+	return item
+}
+
+// taskQuestionsFor lists the questions on a task the user may see: visible questions for
+// everyone, every question for the employer, and the user's own questions in any status
+func taskQuestionsFor(task *models.Task, userID uint, isEmployer bool) ([]gin.H, error) {
+	query := db.DB.Preload("Asker").Where("task_id = ?", task.ID)
+	if !isEmployer {
+		query = query.Where("status = ? OR asker_id = ?", models.TaskQuestionStatusVisible, userID)
+	}
+	var questions []models.TaskQuestion
+	if err := query.Order("created_at ASC").Find(&questions).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]gin.H, 0, len(questions))
+	for i := range questions {
+		q := &questions[i]
+		items = append(items, formatTaskQuestion(q, isEmployer || (userID != 0 && q.AskerID == userID)))
+	}
+	return items, nil
+}
+
+// loadTaskQuestion finds the question in the uuid path parameter together with its task,
+// writing an error response and returning false if it does not exist
+func loadTaskQuestion(c *gin.Context) (*models.TaskQuestion, bool) {
+	var question models.TaskQuestion
+	if err := db.DB.Preload("Task").Preload("Asker").Where("uuid = ?", c.Param("uuid")).First(&question).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "问题不存在"})
+		} else {
+			log.Printf("[loadTaskQuestion] 查询问题失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询问题失败"})
+		}
+		return nil, false
+	}
+	return &question, true
+}
+
+// moderatedText trims user-written text, checks its length and runs it through moderation,
+// writing an error response and returning false if it cannot be published
+func moderatedText(c *gin.Context, kind, text string) (string, services.ModerationVerdict, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "内容不能为空"})
+		return "", services.ModerationAllow, false
+	}
+	if utf8.RuneCountInString(text) > maxTaskQuestionLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("内容不能超过%d个字符", maxTaskQuestionLength)})
+		return "", services.ModerationAllow, false
+	}
+	verdict := services.ModerateText(kind, text)
+	if verdict == services.ModerationReject {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "内容包含不当信息，请修改后重试"})
+		return "", verdict, false
+	}
+	return text, verdict, true
+}
+
+// notifyTaskQuestion tells the employer about a newly published question, or the asker
+// about the answer once the question is visible
+func notifyTaskQuestion(tx *gorm.DB, q *models.TaskQuestion, task *models.Task) {
+	if q.Status != models.TaskQuestionStatusVisible {
+		return
+	}
+	details := map[string]interface{}{"task_uuid": task.UUID}
+	if q.IsAnswered() {
+		services.RecordActivity(tx, task.EmployerID, q.AskerID, "task_question", q.UUID, "task_question_answered",
+			fmt.Sprintf("雇主回答了您关于任务「%s」的提问", task.Title), details)
+		return
+	}
+	services.RecordActivity(tx, q.AskerID, task.EmployerID, "task_question", q.UUID, "task_question_asked",
+		fmt.Sprintf("有人对您的任务「%s」提出了问题", task.Title), details)
+}
+
+// GetTaskQuestions handles listing the questions and answers on a task
+func GetTaskQuestions(c *gin.Context) {
+	var currentUserID uint
+	if userID, exists := c.Get("userID"); exists {
+		currentUserID = userID.(uint)
+	}
+
+	var task models.Task
+	if err := db.DB.Where("uuid = ?", c.Param("uuid")).First(&task).Error; err != nil ||
+		(task.Status == models.TaskStatusDraft && task.EmployerID != currentUserID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务未找到"})
+		return
+	}
+
+	questions, err := taskQuestionsFor(&task, currentUserID, task.EmployerID == currentUserID)
+	if err != nil {
+		log.Printf("[GetTaskQuestions] 查询问题失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询问题失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"questions": questions,
+	})
+}
+
+// AskTaskQuestion handles asking a public question about a recruiting task. Questions that
+// moderation holds back are published once an admin approves them.
+func AskTaskQuestion(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req TaskQuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	var task models.Task
+	if err := db.DB.Where("uuid = ?", c.Param("uuid")).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务未找到"})
+		return
+	}
+	if task.EmployerID == userID.(uint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能对自己发布的任务提问"})
+		return
+	}
+	if task.Status != models.TaskStatusRecruiting {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "任务不在招募阶段，无法提问"})
+		return
+	}
+
+	var open int64
+	if err := db.DB.Model(&models.TaskQuestion{}).
+		Where("task_id = ? AND asker_id = ? AND answer IS NULL AND status <> ?", task.ID, userID, models.TaskQuestionStatusHidden).
+		Count(&open).Error; err != nil {
+		log.Printf("[AskTaskQuestion] 查询问题失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询问题失败"})
+		return
+	}
+	if open >= maxOpenTaskQuestions {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("您在该任务下最多只能有%d个待回答的问题", maxOpenTaskQuestions)})
+		return
+	}
+
+	text, verdict, ok := moderatedText(c, "task_question", req.Question)
+	if !ok {
+		return
+	}
+
+	question := models.TaskQuestion{
+		TaskID:   task.ID,
+		AskerID:  userID.(uint),
+		Question: text,
+		Status:   models.TaskQuestionStatusVisible,
+	}
+	if verdict == services.ModerationReview {
+		question.Status = models.TaskQuestionStatusPending
+	}
+
+	tx := services.BeginTx(db.DB)
+	if err := tx.Create(&question).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[AskTaskQuestion] 创建问题失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提问失败"})
+		return
+	}
+	notifyTaskQuestion(tx, &question, &task)
+	if err := services.CommitTx(tx); err != nil {
+		log.Printf("[AskTaskQuestion] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提问失败"})
+		return
+	}
+
+	db.DB.First(&question.Asker, question.AskerID)
+	message := "提问成功"
+	if question.Status == models.TaskQuestionStatusPending {
+		message = "问题已提交，审核通过后公开显示"
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"success":  true,
+		"message":  message,
+		"question": formatTaskQuestion(&question, true),
+	})
+}
+
+// AnswerTaskQuestion handles the employer answering a question on their task. Answering
+// again replaces the previous answer.
+func AnswerTaskQuestion(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req TaskAnswerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	question, ok := loadTaskQuestion(c)
+	if !ok {
+		return
+	}
+	if question.Task.EmployerID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的发布者"})
+		return
+	}
+	if question.Status == models.TaskQuestionStatusHidden {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该问题已被隐藏"})
+		return
+	}
+
+	text, verdict, ok := moderatedText(c, "task_answer", req.Answer)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	question.Answer = &text
+	question.AnsweredAt = &now
+	if verdict == services.ModerationReview {
+		question.Status = models.TaskQuestionStatusPending
+	}
+
+	tx := services.BeginTx(db.DB)
+	if err := tx.Model(question).Updates(map[string]interface{}{
+		"answer":      question.Answer,
+		"answered_at": question.AnsweredAt,
+		"status":      question.Status,
+	}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[AnswerTaskQuestion] 保存回答失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "回答失败"})
+		return
+	}
+	notifyTaskQuestion(tx, question, &question.Task)
+	if err := services.CommitTx(tx); err != nil {
+		log.Printf("[AnswerTaskQuestion] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "回答失败"})
+		return
+	}
+
+	message := "回答成功"
+	if question.Status == models.TaskQuestionStatusPending {
+		message = "回答已提交，审核通过后公开显示"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  message,
+		"question": formatTaskQuestion(question, true),
+	})
+}
+
+// DeleteTaskQuestion handles the asker withdrawing a question that has not been answered
+func DeleteTaskQuestion(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	question, ok := loadTaskQuestion(c)
+	if !ok {
+		return
+	}
+	if question.AskerID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能删除自己的问题"})
+		return
+	}
+	if question.IsAnswered() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "已回答的问题不能删除"})
+		return
+	}
+
+	tx := services.BeginTx(db.DB)
+	if err := tx.Where("question_id = ?", question.ID).Delete(&models.TaskQuestionReport{}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[DeleteTaskQuestion] 删除举报记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除问题失败"})
+		return
+	}
+	if err := tx.Delete(question).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[DeleteTaskQuestion] 删除问题失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除问题失败"})
+		return
+	}
+	if err := services.CommitTx(tx); err != nil {
+		log.Printf("[DeleteTaskQuestion] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除问题失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "问题已删除",
+	})
+}
+
+// ReportTaskQuestion handles reporting an inappropriate question or answer. Once enough
+// users have reported it, a visible question is held for an admin to review.
+func ReportTaskQuestion(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req TaskQuestionReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	question, ok := loadTaskQuestion(c)
+	if !ok {
+		return
+	}
+	if question.AskerID == userID.(uint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能举报自己的问题"})
+		return
+	}
+
+	var reported int64
+	if err := db.DB.Model(&models.TaskQuestionReport{}).
+		Where("question_id = ? AND reporter_id = ?", question.ID, userID).Count(&reported).Error; err != nil {
+		log.Printf("[ReportTaskQuestion] 查询举报失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "举报失败"})
+		return
+	}
+	if reported > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "您已举报过该问题"})
+		return
+	}
+
+	tx := services.BeginTx(db.DB)
+	report := models.TaskQuestionReport{
+		QuestionID: question.ID,
+		ReporterID: userID.(uint),
+		Reason:     strings.TrimSpace(req.Reason),
+	}
+	if err := tx.Create(&report).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[ReportTaskQuestion] 创建举报失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "举报失败"})
+		return
+	}
+
+	updates := map[string]interface{}{"report_count": gorm.Expr("report_count + 1")}
+	if question.Status == models.TaskQuestionStatusVisible && int64(question.ReportCount)+1 >= taskQuestionReportThreshold() {
+		updates["status"] = models.TaskQuestionStatusPending
+	}
+	if err := tx.Model(question).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[ReportTaskQuestion] 更新问题失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "举报失败"})
+		return
+	}
+	if err := services.CommitTx(tx); err != nil {
+		log.Printf("[ReportTaskQuestion] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "举报失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "举报提交成功，我们将尽快审核",
+	})
+}
+
+// ModerateTaskQuestion handles a moderation decision on a question. The task's employer
+// can hide questions on their own task; only admins can publish questions held for review
+// or restore hidden ones.
+func ModerateTaskQuestion(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	isAdmin := currentUserIsAdmin(c)
+
+	var req TaskQuestionModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	question, ok := loadTaskQuestion(c)
+	if !ok {
+		return
+	}
+	if !isAdmin {
+		if question.Task.EmployerID != userID.(uint) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "您没有权限管理该问题"})
+			return
+		}
+		if req.Status != string(models.TaskQuestionStatusHidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "只有管理员可以公开问题"})
+			return
+		}
+	}
+
+	previous := question.Status
+	question.Status = models.TaskQuestionStatus(req.Status)
+	var note *string
+	if n := strings.TrimSpace(req.Note); n != "" {
+		note = &n
+	}
+	question.ModerationNote = note
+
+	tx := services.BeginTx(db.DB)
+	if err := tx.Model(question).Updates(map[string]interface{}{
+		"status":          question.Status,
+		"moderation_note": question.ModerationNote,
+	}).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[ModerateTaskQuestion] 更新问题失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新问题失败"})
+		return
+	}
+	// 审核通过后补发提问或回答的通知
+	if previous == models.TaskQuestionStatusPending {
+		notifyTaskQuestion(tx, question, &question.Task)
+	}
+	if err := services.CommitTx(tx); err != nil {
+		log.Printf("[ModerateTaskQuestion] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新问题失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"question": formatTaskQuestion(question, true),
+	})
+}
+
+// AdminGetTaskQuestions handles listing task questions for moderation, by default the ones
+// held for review, with the reasons they were reported
+func AdminGetTaskQuestions(c *gin.Context) {
+	status := c.DefaultQuery("status", string(models.TaskQuestionStatusPending))
+
+	var questions []models.TaskQuestion
+	if err := db.DB.Preload("Task").Preload("Asker").
+		Where("status = ?", status).
+		Order("report_count DESC, created_at ASC").
+		Limit(200).Find(&questions).Error; err != nil {
+		log.Printf("[AdminGetTaskQuestions] 查询问题失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询问题失败"})
+		return
+	}
+
+	ids := make([]uint, 0, len(questions))
+	for _, q := range questions {
+		ids = append(ids, q.ID)
+	}
+	var reports []models.TaskQuestionReport
+	if len(ids) > 0 {
+		if err := db.DB.Where("question_id IN ?", ids).Order("created_at ASC").Find(&reports).Error; err != nil {
+			log.Printf("[AdminGetTaskQuestions] 查询举报失败: %v", err)
+		}
+	}
+	reasons := make(map[uint][]string, len(questions))
+	for _, r := range reports {
+		reasons[r.QuestionID] = append(reasons[r.QuestionID], r.Reason)
+	}
+
+	items := make([]gin.H, 0, len(questions))
+	for i := range questions {
+		q := &questions[i]
+		item := formatTaskQuestion(q, true)
+		item["task"] = gin.H{
+			"uuid":  q.Task.UUID,
+			"title": q.Task.Title,
+		}
+		item["report_reasons"] = reasons[q.ID]
+		items = append(items, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"questions": items,
+	})
+}
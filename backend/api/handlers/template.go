@@ -0,0 +1,589 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+	"zhlg/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// maxTemplatesPerEmployer caps how many templates one employer can keep
+const maxTemplatesPerEmployer = 50
+
+func init() {
+	services.RegisterTemplateTaskBuilder(buildTemplateTask)
+}
+
+// TemplateMilestoneRequest represents a milestone in a template request
+type TemplateMilestoneRequest struct {
+	Title         string  `json:"title" binding:"required"`
+	Description   string  `json:"description"`
+	Amount        float64 `json:"amount" binding:"required,gt=0"`
+	DueOffsetDays *int    `json:"due_offset_days" binding:"omitempty,min=0"`
+}
+
+// TaskTemplateRequest represents the request body for creating or updating a task
+// template. It has the fields of CreateTaskRequest with a duration in place of the dates.
+type TaskTemplateRequest struct {
+	Name            string                     `json:"name" binding:"required,max=100"`
+	Title           string                     `json:"title" binding:"required"`
+	Description     string                     `json:"description" binding:"required"`
+	LocationType    string                     `json:"location_type" binding:"required,oneof=online offline"`
+	LocationDetails string                     `json:"location_details"`
+	Latitude        *float64                   `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude       *float64                   `json:"longitude" binding:"omitempty,min=-180,max=180"`
+	CheckInRadius   uint                       `json:"check_in_radius"`
+	PaymentType     string                     `json:"payment_type" binding:"required,oneof=hourly daily fixed"`
+	BudgetAmount    float64                    `json:"budget_amount" binding:"required,gt=0"`
+	EstimatedHours  float64                    `json:"estimated_hours" binding:"min=0"`
+	Headcount       int                        `json:"headcount" binding:"required,gt=0"`
+	BudgetCeiling   *float64                   `json:"budget_ceiling" binding:"omitempty,gt=0"`
+	Skills          []string                   `json:"skills" binding:"required"`
+	IsPublic        bool                       `json:"is_public"`
+	IsUrgent        bool                       `json:"is_urgent"`
+	DurationDays    int                        `json:"duration_days" binding:"min=0,max=365"`
+	Milestones      []TemplateMilestoneRequest `json:"milestones" binding:"omitempty,dive"`
+}
+
+// UseTemplateRequest represents the request body for creating a task from a template.
+// The end date defaults to the start date plus the template's duration.
+type UseTemplateRequest struct {
+	StartDate string `json:"start_date" binding:"required"`
+	EndDate   string `json:"end_date"`
+	Title     string `json:"title"`
+	AsDraft   bool   `json:"as_draft"`
+}
+
+// CloneTaskRequest represents the request body for cloning a task. The end date defaults
+// to keeping the original task's duration.
+type CloneTaskRequest struct {
+	StartDate string `json:"start_date" binding:"required"`
+	EndDate   string `json:"end_date"`
+}
+
+// parseNewDates parses the dates of a task created from a template or clone, deriving the
+// end date from the duration when it is omitted
+func parseNewDates(startDate, endDate string, durationDays int) (time.Time, time.Time, error) {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("开始日期格式不正确")
+	}
+	if start.Before(time.Now().Truncate(24 * time.Hour)) {
+		return time.Time{}, time.Time{}, fmt.Errorf("开始日期不能早于今天")
+	}
+	end := start.AddDate(0, 0, durationDays)
+	if endDate != "" {
+		if end, err = time.Parse("2006-01-02", endDate); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("结束日期格式不正确")
+		}
+		if end.Before(start) {
+			return time.Time{}, time.Time{}, fmt.Errorf("结束日期不能早于开始日期")
+		}
+	}
+	return start, end, nil
+}
+
+// applyTemplateRequest validates the request and copies it onto the template
+func applyTemplateRequest(tmpl *models.TaskTemplate, req *TaskTemplateRequest) error {
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		return fmt.Errorf("经纬度需要同时提供")
+	}
+	if len(req.Milestones) > 0 {
+		if req.PaymentType != string(models.PaymentTypeFixed) {
+			return fmt.Errorf("只有固定价格任务可以设置里程碑")
+		}
+		total := 0.0
+		for _, m := range req.Milestones {
+			if m.DueOffsetDays != nil && *m.DueOffsetDays > req.DurationDays {
+				return fmt.Errorf("里程碑「%s」截止日期必须在任务周期内", m.Title)
+			}
+			total += m.Amount
+		}
+		if total > req.BudgetAmount {
+			return fmt.Errorf("里程碑总金额 %.2f 超出任务预算 %.2f", total, req.BudgetAmount)
+		}
+	}
+
+	milestones := make([]models.TemplateMilestone, 0, len(req.Milestones))
+	for _, m := range req.Milestones {
+		milestones = append(milestones, models.TemplateMilestone{
+			Title:         m.Title,
+			Description:   m.Description,
+			Amount:        m.Amount,
+			DueOffsetDays: m.DueOffsetDays,
+		})
+	}
+	milestonesJSON, err := json.Marshal(milestones)
+	if err != nil {
+		return err
+	}
+
+	tmpl.Name = strings.TrimSpace(req.Name)
+	tmpl.Title = req.Title
+	tmpl.Description = req.Description
+	tmpl.LocationType = models.LocationType(req.LocationType)
+	tmpl.LocationDetails = nil
+	tmpl.Latitude, tmpl.Longitude, tmpl.CheckInRadius = nil, nil, 0
+	if tmpl.LocationType == models.LocationTypeOffline {
+		if req.LocationDetails != "" {
+			locationDetails := req.LocationDetails
+			tmpl.LocationDetails = &locationDetails
+		}
+		tmpl.Latitude, tmpl.Longitude, tmpl.CheckInRadius = req.Latitude, req.Longitude, req.CheckInRadius
+	}
+	tmpl.PaymentType = models.PaymentType(req.PaymentType)
+	tmpl.BudgetAmount = req.BudgetAmount
+	tmpl.EstimatedHours = req.EstimatedHours
+	tmpl.Headcount = uint(req.Headcount)
+	tmpl.BudgetCeiling = req.BudgetCeiling
+	tmpl.IsPublic = req.IsPublic
+	tmpl.IsUrgent = req.IsUrgent
+	tmpl.DurationDays = req.DurationDays
+	tmpl.Milestones = datatypes.JSON(milestonesJSON)
+	return nil
+}
+
+// templateTaskRequest turns a template into a create request for a task on the given dates
+func templateTaskRequest(tmpl *models.TaskTemplate, start, end time.Time) (CreateTaskRequest, error) {
+	req := CreateTaskRequest{
+		Title:          tmpl.Title,
+		Description:    tmpl.Description,
+		LocationType:   string(tmpl.LocationType),
+		Latitude:       tmpl.Latitude,
+		Longitude:      tmpl.Longitude,
+		CheckInRadius:  tmpl.CheckInRadius,
+		StartDate:      start.Format("2006-01-02"),
+		EndDate:        end.Format("2006-01-02"),
+		PaymentType:    string(tmpl.PaymentType),
+		BudgetAmount:   tmpl.BudgetAmount,
+		EstimatedHours: tmpl.EstimatedHours,
+		Headcount:      int(tmpl.Headcount),
+		BudgetCeiling:  tmpl.BudgetCeiling,
+		IsPublic:       tmpl.IsPublic,
+		IsUrgent:       tmpl.IsUrgent,
+	}
+	if tmpl.LocationDetails != nil {
+		req.LocationDetails = *tmpl.LocationDetails
+	}
+	for _, s := range tmpl.Skills {
+		req.Skills = append(req.Skills, s.Name)
+	}
+
+	var milestones []models.TemplateMilestone
+	if len(tmpl.Milestones) > 0 {
+		if err := json.Unmarshal(tmpl.Milestones, &milestones); err != nil {
+			return req, err
+		}
+	}
+	for _, m := range milestones {
+		milestone := MilestoneRequest{Title: m.Title, Description: m.Description, Amount: m.Amount}
+		if m.DueOffsetDays != nil {
+			due := start.AddDate(0, 0, *m.DueOffsetDays)
+			if due.After(end) {
+				due = end
+			}
+			milestone.DueDate = due.Format("2006-01-02")
+		}
+		req.Milestones = append(req.Milestones, milestone)
+	}
+	return req, nil
+}
+
+// buildTemplateTask validates a template on the given dates and builds the task the same way
+// CreateTaskFromTemplate does, for the recurring task scheduler
+func buildTemplateTask(tmpl *models.TaskTemplate, start, end time.Time) (models.Task, []models.TaskMilestone, []string, error) {
+	req, err := templateTaskRequest(tmpl, start, end)
+	if err != nil {
+		return models.Task{}, nil, nil, fmt.Errorf("解析模板失败: %w", err)
+	}
+	task, milestones, err := buildTask(tmpl.EmployerID, &req)
+	if err != nil {
+		return models.Task{}, nil, nil, err
+	}
+	return task, milestones, req.Skills, nil
+}
+
+// formatTemplate converts a task template into its API representation
+func formatTemplate(tmpl models.TaskTemplate) gin.H {
+	skills := make([]string, 0, len(tmpl.Skills))
+	for _, s := range tmpl.Skills {
+		skills = append(skills, s.Name)
+	}
+	var milestones []models.TemplateMilestone
+	if len(tmpl.Milestones) > 0 {
+		if err := json.Unmarshal(tmpl.Milestones, &milestones); err != nil {
+			log.Printf("[formatTemplate] 解析模板里程碑失败: template=%s, err=%v", tmpl.UUID, err)
+		}
+	}
+	if milestones == nil {
+		milestones = []models.TemplateMilestone{}
+	}
+
+	item := gin.H{
+		"uuid":             tmpl.UUID,
+		"name":             tmpl.Name,
+		"title":            tmpl.Title,
+		"description":      tmpl.Description,
+		"location_type":    tmpl.LocationType,
+		"location_details": tmpl.LocationDetails,
+		"latitude":         tmpl.Latitude,
+		"longitude":        tmpl.Longitude,
+		"check_in_radius":  tmpl.CheckInRadius,
+		"payment_type":     tmpl.PaymentType,
+		"budget_amount":    tmpl.BudgetAmount,
+		"estimated_hours":  tmpl.EstimatedHours,
+		"headcount":        tmpl.Headcount,
+		"budget_ceiling":   tmpl.BudgetCeiling,
+		"skills":           skills,
+		"is_public":        tmpl.IsPublic,
+		"is_urgent":        tmpl.IsUrgent,
+		"duration_days":    tmpl.DurationDays,
+		"milestones":       milestones,
+		"usage_count":      tmpl.UsageCount,
+		"last_used_at":     nil,
+		"created_at":       tmpl.CreatedAt.Format(time.RFC3339),
+		"updated_at":       tmpl.UpdatedAt.Format(time.RFC3339),
+	}
+	if tmpl.LastUsedAt != nil {
+		item["last_used_at"] = tmpl.LastUsedAt.Format(time.RFC3339)
+	}
+	return item
+}
+
+// loadOwnTemplate finds one of the current employer's templates, writing a 404 when it
+// does not exist or belongs to someone else
+func loadOwnTemplate(c *gin.Context, employerID uint) (*models.TaskTemplate, bool) {
+	var tmpl models.TaskTemplate
+	if err := db.DB.Preload("Skills").Where("uuid = ? AND employer_id = ?", c.Param("uuid"), employerID).
+		First(&tmpl).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "模板不存在"})
+		return nil, false
+	}
+	return &tmpl, true
+}
+
+// GetTaskTemplates handles listing the current employer's task templates, most used first
+func GetTaskTemplates(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var templates []models.TaskTemplate
+	if err := db.DB.Preload("Skills").Where("employer_id = ?", userID).
+		Order("last_used_at IS NULL, last_used_at DESC, created_at DESC").Find(&templates).Error; err != nil {
+		log.Printf("[GetTaskTemplates] 查询模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询模板失败"})
+		return
+	}
+
+	items := make([]gin.H, 0, len(templates))
+	for _, t := range templates {
+		items = append(items, formatTemplate(t))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"templates": items,
+	})
+}
+
+// GetTaskTemplate handles retrieving one of the current employer's task templates
+func GetTaskTemplate(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	tmpl, ok := loadOwnTemplate(c, userID.(uint))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"template": formatTemplate(*tmpl),
+	})
+}
+
+// CreateTaskTemplate handles saving a reusable task template
+func CreateTaskTemplate(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req TaskTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	var count int64
+	db.DB.Model(&models.TaskTemplate{}).Where("employer_id = ?", userID).Count(&count)
+	if count >= maxTemplatesPerEmployer {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("最多保存%d个模板", maxTemplatesPerEmployer)})
+		return
+	}
+
+	tmpl := models.TaskTemplate{
+		UUID:       uuid.New().String(),
+		EmployerID: userID.(uint),
+	}
+	if err := applyTemplateRequest(&tmpl, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+	skills, err := services.ResolveSkills(db.DB, req.Skills)
+	if err != nil {
+		log.Printf("[CreateTaskTemplate] 处理技能失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存模板失败"})
+		return
+	}
+	tmpl.Skills = skills
+
+	if err := db.DB.Create(&tmpl).Error; err != nil {
+		log.Printf("[CreateTaskTemplate] 保存模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存模板失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "模板已保存",
+		"template": formatTemplate(tmpl),
+	})
+}
+
+// UpdateTaskTemplate handles replacing the contents of a task template
+func UpdateTaskTemplate(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	tmpl, ok := loadOwnTemplate(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	var req TaskTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+	if err := applyTemplateRequest(tmpl, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+	skills, err := services.ResolveSkills(db.DB, req.Skills)
+	if err != nil {
+		log.Printf("[UpdateTaskTemplate] 处理技能失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新模板失败"})
+		return
+	}
+
+	tx := db.DB.Begin()
+
+	if err := tx.Omit("Skills").Save(tmpl).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[UpdateTaskTemplate] 更新模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新模板失败"})
+		return
+	}
+	if err := tx.Model(tmpl).Association("Skills").Replace(&skills); err != nil {
+		tx.Rollback()
+		log.Printf("[UpdateTaskTemplate] 更新模板技能失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新模板失败"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("[UpdateTaskTemplate] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新模板失败"})
+		return
+	}
+
+	tmpl.Skills = skills
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "模板已更新",
+		"template": formatTemplate(*tmpl),
+	})
+}
+
+// DeleteTaskTemplate handles deleting a task template. Tasks created from it are kept.
+func DeleteTaskTemplate(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	tmpl, ok := loadOwnTemplate(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	tx := db.DB.Begin()
+
+	if err := tx.Model(tmpl).Association("Skills").Clear(); err != nil {
+		tx.Rollback()
+		log.Printf("[DeleteTaskTemplate] 清除模板技能失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除模板失败"})
+		return
+	}
+	if err := tx.Delete(tmpl).Error; err != nil {
+		tx.Rollback()
+		log.Printf("[DeleteTaskTemplate] 删除模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除模板失败"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Printf("[DeleteTaskTemplate] 提交事务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除模板失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "模板已删除"})
+}
+
+// CreateTaskFromTemplate handles posting a new task from a template on new dates, either
+// published right away or saved as a draft
+func CreateTaskFromTemplate(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	var user models.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	tmpl, ok := loadOwnTemplate(c, user.ID)
+	if !ok {
+		return
+	}
+
+	var req UseTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+	start, end, err := parseNewDates(req.StartDate, req.EndDate, tmpl.DurationDays)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	taskReq, err := templateTaskRequest(tmpl, start, end)
+	if err != nil {
+		log.Printf("[CreateTaskFromTemplate] 解析模板失败: template=%s, err=%v", tmpl.UUID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "解析模板失败"})
+		return
+	}
+	if title := strings.TrimSpace(req.Title); title != "" {
+		taskReq.Title = title
+	}
+
+	task, milestones, err := buildTask(user.ID, &taskReq)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+	if req.AsDraft {
+		task.Status = models.TaskStatusDraft
+		task.PublishedAt = nil
+	}
+	if err := saveNewTask(&task, milestones, taskReq.Skills); err != nil {
+		log.Printf("[CreateTaskFromTemplate] 创建任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "任务创建失败"})
+		return
+	}
+
+	now := time.Now()
+	if err := db.DB.Model(tmpl).Updates(map[string]interface{}{
+		"usage_count":  tmpl.UsageCount + 1,
+		"last_used_at": now,
+	}).Error; err != nil {
+		log.Printf("[CreateTaskFromTemplate] 更新模板使用次数失败: %v", err)
+	}
+
+	message := "任务发布成功"
+	if req.AsDraft {
+		message = "已根据模板创建草稿"
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": message,
+		"task":    formatCreatedTask(&task, &user),
+	})
+}
+
+// CloneTask handles copying one of the employer's tasks into a new draft on new dates.
+// Skills, screening questions and milestones are copied with milestone due dates shifted
+// along with the task.
+func CloneTask(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+	var user models.User
+	if err := db.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	var source models.Task
+	if err := db.DB.Preload("Skills").Preload("Milestones").Preload("ScreeningQuestions").
+		Where("uuid = ?", c.Param("uuid")).First(&source).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+	if source.EmployerID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的发布者"})
+		return
+	}
+	if len(source.DraftData) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "草稿尚未填写完整，无法复制"})
+		return
+	}
+
+	var req CloneTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+	durationDays := int(source.EndDate.Sub(source.StartDate).Hours() / 24)
+	start, end, err := parseNewDates(req.StartDate, req.EndDate, durationDays)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	task, err := services.CopyTask(db.DB, &source, start, end, models.TaskStatusDraft)
+	if err != nil {
+		log.Printf("[CloneTask] 复制任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "复制任务失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "已复制为草稿",
+		"source_uuid": source.UUID,
+		"task":        formatCreatedTask(task, &user),
+	})
+}
@@ -0,0 +1,486 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+	"zhlg/backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxTimesheetEntryHours caps a single manual entry or clock session
+const maxTimesheetEntryHours = 24
+
+// ManualTimesheetRequest represents the request body for a manual timesheet entry
+type ManualTimesheetRequest struct {
+	StartedAt   string `json:"started_at" binding:"required"`
+	EndedAt     string `json:"ended_at" binding:"required"`
+	Description string `json:"description"`
+}
+
+// TimesheetReviewRequest represents the request body for approving or rejecting an entry
+type TimesheetReviewRequest struct {
+	Note string `json:"note"`
+}
+
+// isTimeTracked reports whether the task is paid by the hour or by the day
+func isTimeTracked(task *models.Task) bool {
+	return task.PaymentType == models.PaymentTypeHourly || task.PaymentType == models.PaymentTypeDaily
+}
+
+// loadWorkingAssignment finds the task and the current user's active assignment on it,
+// writing an error response and returning false if either is missing. The task must be in
+// progress unless other statuses are allowed.
+func loadWorkingAssignment(c *gin.Context, userID interface{}, allowed ...models.TaskStatus) (*models.Task, *models.TaskAssignment, bool) {
+	var task models.Task
+	if err := db.DB.Where("uuid = ?", c.Param("uuid")).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return nil, nil, false
+	}
+
+	var assignment models.TaskAssignment
+	if err := db.DB.Where("task_id = ? AND worker_id = ? AND worker_status = ?", task.ID, userID, models.WorkerStatusWorking).First(&assignment).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的执行者"})
+		} else {
+			log.Printf("[loadWorkingAssignment] 查询任务分配失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务分配失败"})
+		}
+		return nil, nil, false
+	}
+
+	if task.Status != models.TaskStatusInProgress && !taskStatusIn(task.Status, allowed) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "任务不在进行中状态"})
+		return nil, nil, false
+	}
+	return &task, &assignment, true
+}
+
+// taskStatusIn reports whether the status is one of the given statuses
+func taskStatusIn(status models.TaskStatus, statuses []models.TaskStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// timesheetPayout computes what an assignment earns from its approved entries. Hourly tasks
+// pay approved hours times the rate, daily tasks pay each distinct day worked times the rate.
+// The rate is the assignment's contracted rate, or the task budget when none was agreed, and
+// the contracted amount, when set, caps the payout.
+func timesheetPayout(tx *gorm.DB, task *models.Task, assignment *models.TaskAssignment) (float64, float64, error) {
+	var entries []models.TimesheetEntry
+	if err := tx.Where("task_assignment_id = ? AND status = ?", assignment.ID, models.TimesheetStatusApproved).
+		Find(&entries).Error; err != nil {
+		return 0, 0, err
+	}
+
+	var units float64
+	if task.PaymentType == models.PaymentTypeDaily {
+		days := make(map[string]bool)
+		for _, e := range entries {
+			days[e.StartedAt.Format("2006-01-02")] = true
+		}
+		units = float64(len(days))
+	} else {
+		for _, e := range entries {
+			units += e.Hours
+		}
+	}
+
+	ceiling, rate := assignmentContract(task, assignment)
+	if rate == nil {
+		rate = &task.BudgetAmount
+	}
+	amount := math.Round(units**rate*100) / 100
+	if ceiling != nil && amount > *ceiling {
+		amount = *ceiling
+	}
+	return units, amount, nil
+}
+
+// formatTimesheetEntry converts an entry into its API representation
+func formatTimesheetEntry(e models.TimesheetEntry) gin.H {
+	item := gin.H{
+		"uuid":        e.UUID,
+		"source":      e.Source,
+		"started_at":  e.StartedAt.Format(time.RFC3339),
+		"ended_at":    nil,
+		"hours":       e.Hours,
+		"description": e.Description,
+		"status":      e.Status,
+		"review_note": e.ReviewNote,
+		"reviewed_at": nil,
+	}
+	if e.EndedAt != nil {
+		item["ended_at"] = e.EndedAt.Format(time.RFC3339)
+	}
+	if e.ReviewedAt != nil {
+		item["reviewed_at"] = e.ReviewedAt.Format(time.RFC3339)
+	}
+	return item
+}
+
+// ClockIn handles a worker starting a timed work session on an hourly or daily task
+func ClockIn(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	task, assignment, ok := loadWorkingAssignment(c, userID)
+	if !ok {
+		return
+	}
+
+	if !isTimeTracked(task) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "只有按小时或按天计费的任务需要记录工时"})
+		return
+	}
+
+	// 有坐标的线下任务必须现场定位签到
+	if task.IsGeofenced() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该任务需要现场定位签到，请使用定位签到"})
+		return
+	}
+
+	var open models.TimesheetEntry
+	if err := db.DB.Where("task_assignment_id = ? AND status = ?", assignment.ID, models.TimesheetStatusOpen).First(&open).Error; err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "您已在工作中，请先下班打卡"})
+		return
+	}
+
+	entry := models.TimesheetEntry{
+		UUID:             uuid.New().String(),
+		TaskID:           task.ID,
+		TaskAssignmentID: assignment.ID,
+		WorkerID:         assignment.WorkerID,
+		Source:           models.TimesheetSourceClock,
+		StartedAt:        time.Now(),
+		Status:           models.TimesheetStatusOpen,
+	}
+	if err := db.DB.Create(&entry).Error; err != nil {
+		log.Printf("[ClockIn] 创建工时记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "上班打卡失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "上班打卡成功",
+		"entry":   formatTimesheetEntry(entry),
+	})
+}
+
+// ClockOut handles a worker ending their current work session
+func ClockOut(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	// 其他零工已提交时任务进入待付款，仍允许下班打卡
+	_, assignment, ok := loadWorkingAssignment(c, userID, models.TaskStatusPaymentPending)
+	if !ok {
+		return
+	}
+
+	var entry models.TimesheetEntry
+	if err := db.DB.Where("task_assignment_id = ? AND status = ?", assignment.ID, models.TimesheetStatusOpen).First(&entry).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "您尚未上班打卡"})
+		return
+	}
+	if entry.Source == models.TimesheetSourceAttendance {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该工时由定位签到产生，请使用定位签退"})
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(entry.StartedAt).Hours() > maxTimesheetEntryHours {
+		now = entry.StartedAt.Add(maxTimesheetEntryHours * time.Hour)
+	}
+	entry.ClockOut(now)
+	if err := db.DB.Model(&entry).Updates(map[string]interface{}{
+		"ended_at": entry.EndedAt,
+		"hours":    entry.Hours,
+		"status":   entry.Status,
+	}).Error; err != nil {
+		log.Printf("[ClockOut] 更新工时记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "下班打卡失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "下班打卡成功，工时等待雇主审批",
+		"entry":   formatTimesheetEntry(entry),
+	})
+}
+
+// CreateTimesheetEntry handles a worker adding a manual timesheet entry
+func CreateTimesheetEntry(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req ManualTimesheetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	task, assignment, ok := loadWorkingAssignment(c, userID)
+	if !ok {
+		return
+	}
+
+	if !isTimeTracked(task) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "只有按小时或按天计费的任务需要记录工时"})
+		return
+	}
+
+	// 现场任务的工时只能来自定位签到
+	if task.IsGeofenced() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该任务需要现场定位签到，不能手动登记工时"})
+		return
+	}
+
+	startedAt, err := time.Parse(time.RFC3339, req.StartedAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": "开始时间格式不正确"})
+		return
+	}
+	endedAt, err := time.Parse(time.RFC3339, req.EndedAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": "结束时间格式不正确"})
+		return
+	}
+	if !endedAt.After(startedAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": "结束时间必须晚于开始时间"})
+		return
+	}
+	if endedAt.Sub(startedAt).Hours() > maxTimesheetEntryHours {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": "单条工时不能超过24小时"})
+		return
+	}
+	if endedAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": "不能登记未来的工时"})
+		return
+	}
+	if startedAt.Before(task.StartDate) || startedAt.After(task.EndDate.AddDate(0, 0, 1)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": "工时必须在任务周期内"})
+		return
+	}
+
+	// 不允许与已有工时重叠
+	var overlapping int64
+	db.DB.Model(&models.TimesheetEntry{}).
+		Where("task_assignment_id = ? AND status <> ?", assignment.ID, models.TimesheetStatusRejected).
+		Where("started_at < ? AND (ended_at IS NULL OR ended_at > ?)", endedAt, startedAt).
+		Count(&overlapping)
+	if overlapping > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": "该时间段与已有工时重叠"})
+		return
+	}
+
+	entry := models.TimesheetEntry{
+		UUID:             uuid.New().String(),
+		TaskID:           task.ID,
+		TaskAssignmentID: assignment.ID,
+		WorkerID:         assignment.WorkerID,
+		Source:           models.TimesheetSourceManual,
+		StartedAt:        startedAt,
+		EndedAt:          &endedAt,
+		Status:           models.TimesheetStatusPending,
+	}
+	if req.Description != "" {
+		entry.Description = &req.Description
+	}
+	if err := db.DB.Create(&entry).Error; err != nil {
+		log.Printf("[CreateTimesheetEntry] 创建工时记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "登记工时失败"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "工时已登记，等待雇主审批",
+		"entry":   formatTimesheetEntry(entry),
+	})
+}
+
+// GetTaskTimesheets handles listing timesheet entries of a task. The employer sees every
+// worker's timesheet, an assigned worker only sees their own.
+func GetTaskTimesheets(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var task models.Task
+	if err := db.DB.Where("uuid = ?", c.Param("uuid")).First(&task).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return
+	}
+
+	isEmployer := task.EmployerID == userID.(uint)
+	if !canViewTaskWork(&task, userID.(uint)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该任务的工时"})
+		return
+	}
+
+	var assignments []models.TaskAssignment
+	query := db.DB.Preload("Worker").Where("task_id = ?", task.ID)
+	if !isEmployer {
+		query = query.Where("worker_id = ?", userID)
+	}
+	if err := query.Find(&assignments).Error; err != nil {
+		log.Printf("[GetTaskTimesheets] 查询任务分配失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询任务分配失败"})
+		return
+	}
+
+	timesheets := make([]gin.H, 0, len(assignments))
+	for _, assignment := range assignments {
+		var entries []models.TimesheetEntry
+		if err := db.DB.Where("task_assignment_id = ?", assignment.ID).Order("started_at ASC").Find(&entries).Error; err != nil {
+			log.Printf("[GetTaskTimesheets] 查询工时失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询工时失败"})
+			return
+		}
+
+		var pendingHours float64
+		entryItems := make([]gin.H, 0, len(entries))
+		for _, e := range entries {
+			if e.Status == models.TimesheetStatusPending {
+				pendingHours += e.Hours
+			}
+			entryItems = append(entryItems, formatTimesheetEntry(e))
+		}
+
+		units, amount, err := timesheetPayout(db.DB, &task, &assignment)
+		if err != nil {
+			log.Printf("[GetTaskTimesheets] 计算工时报酬失败: %v", err)
+		}
+
+		timesheets = append(timesheets, gin.H{
+			"assignment_uuid": assignment.UUID,
+			"worker": gin.H{
+				"uuid":       assignment.Worker.UUID,
+				"name":       assignment.Worker.Name,
+				"avatar_url": assignment.Worker.AvatarURL,
+			},
+			"contracted_rate":   assignment.ContractedRate,
+			"contracted_amount": assignment.ContractedAmount,
+			"entries":           entryItems,
+			"pending_hours":     pendingHours,
+			"approved_units":    units,
+			"payout":            amount,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"payment_type":   task.PaymentType,
+		"rate":           task.BudgetAmount,
+		"budget_ceiling": task.BudgetCeiling,
+		"timesheets":     timesheets,
+	})
+}
+
+// ApproveTimesheetEntry handles an employer approving a timesheet entry
+func ApproveTimesheetEntry(c *gin.Context) {
+	reviewTimesheetEntry(c, true)
+}
+
+// RejectTimesheetEntry handles an employer rejecting a timesheet entry
+func RejectTimesheetEntry(c *gin.Context) {
+	reviewTimesheetEntry(c, false)
+}
+
+// reviewTimesheetEntry approves or rejects a pending entry on the employer's task
+func reviewTimesheetEntry(c *gin.Context, approve bool) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return
+	}
+
+	var req TimesheetReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": err.Error()})
+		return
+	}
+
+	var entry models.TimesheetEntry
+	if err := db.DB.Preload("Task").Where("uuid = ?", c.Param("uuid")).First(&entry).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "工时记录不存在"})
+		return
+	}
+
+	if entry.Task.EmployerID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您不是该任务的雇主"})
+		return
+	}
+
+	if entry.Status != models.TimesheetStatusPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该工时记录不在待审批状态"})
+		return
+	}
+
+	action := "timesheet_approved"
+	message := "工时已批准"
+	if approve {
+		entry.Approve(req.Note)
+	} else {
+		entry.Reject(req.Note)
+		action = "timesheet_rejected"
+		message = "工时已驳回"
+	}
+	if err := db.DB.Model(&entry).Updates(map[string]interface{}{
+		"status":      entry.Status,
+		"review_note": entry.ReviewNote,
+		"reviewed_at": entry.ReviewedAt,
+	}).Error; err != nil {
+		log.Printf("[reviewTimesheetEntry] 更新工时记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "审批工时失败"})
+		return
+	}
+
+	services.RecordActivity(db.DB, entry.Task.EmployerID, entry.WorkerID, "timesheet_entry", entry.UUID, action,
+		fmt.Sprintf("%s：%s（%.2f小时）", message, entry.Task.Title, entry.Hours), map[string]interface{}{
+			"task_uuid": entry.Task.UUID,
+			"note":      req.Note,
+		})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"entry":   formatTimesheetEntry(entry),
+	})
+}
+
+// countUnreviewedTimesheets counts open or pending entries of the given assignments
+func countUnreviewedTimesheets(assignments []models.TaskAssignment) int64 {
+	ids := make([]uint, 0, len(assignments))
+	for _, a := range assignments {
+		ids = append(ids, a.ID)
+	}
+	var count int64
+	db.DB.Model(&models.TimesheetEntry{}).
+		Where("task_assignment_id IN ? AND status IN ?", ids, []models.TimesheetStatus{models.TimesheetStatusOpen, models.TimesheetStatusPending}).
+		Count(&count)
+	return count
+}
@@ -9,6 +9,8 @@ import (
 
 	"zhlg/backend/db"
 	"zhlg/backend/models"
+	"zhlg/backend/services"
+	"zhlg/backend/utils"
 
 	"log"
 
@@ -23,6 +25,8 @@ type UpdateUserProfileRequest struct {
 	Name       *string   `json:"name"`
 	Bio        *string   `json:"bio"`
 	Location   *string   `json:"location"`
+	Latitude   *float64  `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude  *float64  `json:"longitude" binding:"omitempty,min=-180,max=180"`
 	HourlyRate *float64  `json:"hourly_rate"`
 	Skills     *[]string `json:"skills"`
 }
@@ -65,18 +69,34 @@ func UpdateUserProfile(c *gin.Context) {
 	if req.Bio != nil {
 		user.Bio = req.Bio
 	}
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数错误", "details": "经纬度需要同时提供"})
+		return
+	}
 	if req.Location != nil {
 		user.Location = req.Location
+		// 所在地变化时用离线地名库重新解析坐标
+		user.Latitude, user.Longitude = nil, nil
+		if place, ok := utils.Geocode(*req.Location); ok {
+			user.SetLocation(place.Latitude, place.Longitude)
+		}
+	}
+	if req.Latitude != nil {
+		user.SetLocation(*req.Latitude, *req.Longitude)
 	}
 	if req.HourlyRate != nil && user.UserType == models.UserTypeWorker {
 		user.HourlyRate = req.HourlyRate
 	}
 	if req.Skills != nil && user.UserType == models.UserTypeWorker {
+		skills, err := services.ResolveSkills(db.DB, *req.Skills)
+		if err != nil {
+			log.Printf("[UpdateUserProfile] 处理技能失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新技能失败"})
+			return
+		}
 		db.DB.Model(&user).Association("Skills").Clear()
-		for _, skillName := range *req.Skills {
-			var skill models.Skill
-			db.DB.Where("name = ?", skillName).FirstOrCreate(&skill, models.Skill{Name: skillName})
-			db.DB.Model(&user).Association("Skills").Append(&skill)
+		if len(skills) > 0 {
+			db.DB.Model(&user).Association("Skills").Append(&skills)
 		}
 	}
 	if len(user.IdentityVerificationDocs) == 0 {
@@ -248,6 +268,10 @@ func UpdateUserSettings(c *gin.Context) {
 		authUser.PhoneNumber = req.PhoneNumber
 	}
 
+	if req.PrivacySettings != nil {
+		authUser.PrivacySettings.Apply(req.PrivacySettings)
+	}
+
 	// Update user in database
 	result := db.DB.Save(authUser)
 	if result.Error != nil {
@@ -259,13 +283,14 @@ func UpdateUserSettings(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"message": "用户设置已更新",
 		"user": gin.H{
-			"uuid":       authUser.UUID,
-			"username":   authUser.Username,
-			"email":      authUser.Email,
-			"phone":      authUser.PhoneNumber,
-			"user_type":  authUser.UserType,
-			"name":       authUser.Name,
-			"avatar_url": authUser.AvatarURL,
+			"uuid":             authUser.UUID,
+			"username":         authUser.Username,
+			"email":            authUser.Email,
+			"phone":            authUser.PhoneNumber,
+			"user_type":        authUser.UserType,
+			"name":             authUser.Name,
+			"avatar_url":       authUser.AvatarURL,
+			"privacy_settings": authUser.PrivacySettings,
 		},
 	})
 	log.Printf("[UpdateUserSettings] db.Save userID=%v", authUser.ID)
@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+	"zhlg/backend/services"
+	"zhlg/backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WorkerQueryParams represents the query parameters for searching the worker directory
+type WorkerQueryParams struct {
+	Page      int     `form:"page,default=1"`
+	Limit     int     `form:"limit,default=10"`
+	Query     string  `form:"q"`
+	Skills    string  `form:"skills"`
+	MinRating float64 `form:"min_rating" binding:"min=0,max=5"`
+	MinRate   float64 `form:"min_rate" binding:"min=0"`
+	MaxRate   float64 `form:"max_rate" binding:"min=0"`
+	Verified  bool    `form:"verified"`
+	Near      string  `form:"near"`
+	Radius    float64 `form:"radius"`
+	SortBy    string  `form:"sort_by,default=rating_desc"`
+}
+
+// workerRatingsJoin attaches each worker's average rating and review count from employers
+const workerRatingsJoin = "LEFT JOIN (SELECT reviewee_id, AVG(rating) AS avg_rating, COUNT(*) AS review_count FROM reviews WHERE review_type = ? GROUP BY reviewee_id) AS worker_ratings ON worker_ratings.reviewee_id = users.id"
+
+// visibleHourlyRate is the worker's hourly rate, or NULL when they chose to hide it
+const visibleHourlyRate = "(CASE WHEN users.privacy_show_hourly_rate THEN users.hourly_rate END)"
+
+// workerSortOrders whitelists the sort_by values accepted by GetWorkers. "distance" is
+// handled separately because it depends on the near parameter.
+var workerSortOrders = map[string]string{
+	"rating_desc":      "COALESCE(worker_ratings.avg_rating, 0) DESC, COALESCE(worker_ratings.review_count, 0) DESC, users.created_at DESC",
+	"reviews_desc":     "COALESCE(worker_ratings.review_count, 0) DESC, users.created_at DESC",
+	"rate_asc":         visibleHourlyRate + " IS NULL, " + visibleHourlyRate + " ASC, users.created_at DESC",
+	"rate_desc":        visibleHourlyRate + " IS NULL, " + visibleHourlyRate + " DESC, users.created_at DESC",
+	"reliability_desc": "users.reliability_score DESC, users.created_at DESC",
+	"newest":           "users.created_at DESC",
+}
+
+// workerStats holds review and work history aggregates shown on profile cards
+type workerStats struct {
+	AverageRating  float64
+	ReviewCount    int64
+	CompletedTasks int64
+}
+
+// loadWorkerStats returns rating and completed task aggregates for the given workers
+func loadWorkerStats(workerIDs []uint) map[uint]*workerStats {
+	stats := make(map[uint]*workerStats, len(workerIDs))
+	if len(workerIDs) == 0 {
+		return stats
+	}
+	for _, id := range workerIDs {
+		stats[id] = &workerStats{}
+	}
+
+	var ratings []struct {
+		RevieweeID uint
+		Average    float64
+		Count      int64
+	}
+	if err := db.DB.Model(&models.Review{}).Select("reviewee_id, AVG(rating) AS average, COUNT(*) AS count").
+		Where("review_type = ? AND reviewee_id IN ?", models.ReviewTypeEmployerToWorker, workerIDs).
+		Group("reviewee_id").Scan(&ratings).Error; err != nil {
+		log.Printf("[loadWorkerStats] 统计评价失败: %v", err)
+	}
+	for _, r := range ratings {
+		stats[r.RevieweeID].AverageRating = math.Round(r.Average*10) / 10
+		stats[r.RevieweeID].ReviewCount = r.Count
+	}
+
+	var completed []struct {
+		WorkerID uint
+		Count    int64
+	}
+	if err := db.DB.Model(&models.TaskAssignment{}).Select("worker_id, COUNT(*) AS count").
+		Where("worker_status = ? AND worker_id IN ?", models.WorkerStatusCompleted, workerIDs).
+		Group("worker_id").Scan(&completed).Error; err != nil {
+		log.Printf("[loadWorkerStats] 统计完成任务失败: %v", err)
+	}
+	for _, r := range completed {
+		stats[r.WorkerID].CompletedTasks = r.Count
+	}
+	return stats
+}
+
+// formatWorkerCard builds the public profile card of a worker, leaving out whatever their
+// privacy settings hide
+func formatWorkerCard(u models.User, stats *workerStats) gin.H {
+	skills := make([]string, 0, len(u.Skills))
+	for _, s := range u.Skills {
+		skills = append(skills, s.Name)
+	}
+	if stats == nil {
+		stats = &workerStats{}
+	}
+
+	card := gin.H{
+		"uuid":              u.UUID,
+		"name":              u.Name,
+		"avatar_url":        u.AvatarURL,
+		"bio":               u.Bio,
+		"skills":            skills,
+		"identity_verified": u.IdentityVerifiedStatus == models.IdentityStatusVerified,
+		"reliability_score": u.ReliabilityScore,
+		"rating": gin.H{
+			"average": stats.AverageRating,
+			"count":   stats.ReviewCount,
+		},
+		"completed_tasks": stats.CompletedTasks,
+		"member_since":    u.CreatedAt.Format("2006-01-02"),
+	}
+	if u.PrivacySettings.ShowLocation {
+		card["location"] = u.Location
+	}
+	if u.PrivacySettings.ShowHourlyRate {
+		card["hourly_rate"] = u.HourlyRate
+	}
+	if u.PrivacySettings.ShowContactInfo {
+		card["contact"] = gin.H{
+			"email":        u.Email,
+			"phone_number": u.PhoneNumber,
+		}
+	}
+	return card
+}
+
+// GetWorkers handles searching the worker directory. Workers who turned off profile
+// visibility never appear, and hidden hourly rates are not used for filtering or sorting.
+// @Summary Search workers
+// @Description Employers browse workers by skill, rating, hourly rate, location and verification status
+// @Tags workers
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/workers [get]
+func GetWorkers(c *gin.Context) {
+	var params WorkerQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的筛选参数", "details": err.Error()})
+		return
+	}
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.Limit < 1 || params.Limit > 50 {
+		params.Limit = 10
+	}
+	if params.SortBy == "" {
+		params.SortBy = "rating_desc"
+	}
+	if _, ok := workerSortOrders[params.SortBy]; !ok && params.SortBy != "distance" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的排序方式", "details": params.SortBy})
+		return
+	}
+	if params.MaxRate > 0 && params.MaxRate < params.MinRate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的筛选参数", "details": "最高时薪不能低于最低时薪"})
+		return
+	}
+
+	query := db.DB.Model(&models.User{}).
+		Joins(workerRatingsJoin, models.ReviewTypeEmployerToWorker).
+		Where("users.user_type = ? AND users.privacy_profile_visibility = ?", models.UserTypeWorker, true)
+
+	if q := strings.TrimSpace(params.Query); q != "" {
+		like := "%" + q + "%"
+		query = query.Where("users.name LIKE ? OR users.bio LIKE ?", like, like)
+	}
+	if params.Skills != "" {
+		skillIDs, err := services.SkillFilterIDs(db.DB, params.Skills)
+		if err != nil {
+			log.Printf("[GetWorkers] 解析技能筛选失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询零工失败"})
+			return
+		}
+		query = query.Where("users.id IN (SELECT user_id FROM user_skills WHERE skill_id IN ?)", skillIDs)
+	}
+	if params.MinRating > 0 {
+		query = query.Where("worker_ratings.avg_rating >= ?", params.MinRating)
+	}
+	if params.MinRate > 0 {
+		query = query.Where(visibleHourlyRate+" >= ?", params.MinRate)
+	}
+	if params.MaxRate > 0 {
+		query = query.Where(visibleHourlyRate+" <= ?", params.MaxRate)
+	}
+	if params.Verified {
+		query = query.Where("users.identity_verified_status = ?", models.IdentityStatusVerified)
+	}
+
+	var nearLat, nearLng float64
+	nearby := params.Near != ""
+	if nearby {
+		var err error
+		nearLat, nearLng, _, err = resolveNear(c, params.Near)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的位置参数", "details": err.Error()})
+			return
+		}
+		if params.Radius == 0 {
+			params.Radius = defaultSearchRadiusKm
+		}
+		if params.Radius < 0 || params.Radius > maxSearchRadiusKm {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的位置参数", "details": fmt.Sprintf("搜索半径需在0到%d公里之间", maxSearchRadiusKm)})
+			return
+		}
+		// 隐藏所在地的零工不参与附近搜索
+		query = services.NearUsers(query.Where("users.privacy_show_location = ?", true), nearLat, nearLng, params.Radius)
+	}
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		log.Printf("[GetWorkers] 统计零工失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询零工失败"})
+		return
+	}
+
+	switch {
+	case params.SortBy == "distance" && nearby:
+		query = services.OrderUsersByDistance(query, nearLat, nearLng)
+	case params.SortBy == "distance":
+		query = query.Order(workerSortOrders["rating_desc"])
+	default:
+		query = query.Order(workerSortOrders[params.SortBy])
+	}
+
+	var workers []models.User
+	if err := query.Preload("Skills").Offset((params.Page - 1) * params.Limit).Limit(params.Limit).
+		Find(&workers).Error; err != nil {
+		log.Printf("[GetWorkers] 查询零工失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询零工失败"})
+		return
+	}
+
+	workerIDs := make([]uint, 0, len(workers))
+	for _, w := range workers {
+		workerIDs = append(workerIDs, w.ID)
+	}
+	stats := loadWorkerStats(workerIDs)
+
+	cards := make([]gin.H, 0, len(workers))
+	for _, w := range workers {
+		card := formatWorkerCard(w, stats[w.ID])
+		if nearby && w.Latitude != nil && w.Longitude != nil {
+			// 只返回整公里数，避免从不同位置搜索推算出零工住址
+			distance := utils.DistanceMeters(nearLat, nearLng, *w.Latitude, *w.Longitude)
+			card["distance_km"] = math.Max(math.Ceil(distance/1000), 1)
+		}
+		cards = append(cards, card)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"workers": cards,
+		"pagination": gin.H{
+			"current_page":   params.Page,
+			"total_pages":    (totalCount + int64(params.Limit) - 1) / int64(params.Limit),
+			"total_items":    totalCount,
+			"items_per_page": params.Limit,
+		},
+	})
+}
+
+// GetWorkerProfile handles fetching a worker's public profile with portfolio and recent reviews
+func GetWorkerProfile(c *gin.Context) {
+	var worker models.User
+	if err := db.DB.Preload("Skills").
+		Where("uuid = ? AND user_type = ?", c.Param("uuid"), models.UserTypeWorker).
+		First(&worker).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "零工不存在"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if !worker.PrivacySettings.ProfileVisibility && userID != worker.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "零工不存在"})
+		return
+	}
+
+	card := formatWorkerCard(worker, loadWorkerStats([]uint{worker.ID})[worker.ID])
+
+	var portfolios []models.UserPortfolio
+	if err := db.DB.Where("user_id = ? AND deleted_at IS NULL", worker.ID).Order("created_at DESC").Find(&portfolios).Error; err != nil {
+		log.Printf("[GetWorkerProfile] 查询作品集失败: %v", err)
+	}
+	portfolioItems := make([]gin.H, 0, len(portfolios))
+	for _, p := range portfolios {
+		portfolioItems = append(portfolioItems, gin.H{
+			"uuid":          p.UUID,
+			"title":         p.Title,
+			"description":   p.Description,
+			"file_url":      p.FileURL,
+			"thumbnail_url": p.ThumbnailURL,
+			"file_type":     p.FileType,
+		})
+	}
+	card["portfolios"] = portfolioItems
+
+	var reviews []models.Review
+	if err := db.DB.Preload("Reviewer").
+		Where("reviewee_id = ? AND review_type = ?", worker.ID, models.ReviewTypeEmployerToWorker).
+		Order("created_at DESC").Limit(5).Find(&reviews).Error; err != nil {
+		log.Printf("[GetWorkerProfile] 查询评价失败: %v", err)
+	}
+	reviewItems := make([]gin.H, 0, len(reviews))
+	for _, r := range reviews {
+		reviewItems = append(reviewItems, gin.H{
+			"rating":  r.Rating,
+			"comment": r.Comment,
+			"reviewer": gin.H{
+				"name":       r.Reviewer.Name,
+				"avatar_url": r.Reviewer.AvatarURL,
+			},
+			"created_at": r.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	card["recent_reviews"] = reviewItems
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"worker":  card,
+	})
+}
@@ -46,17 +46,114 @@ func SetupRoutes(r *gin.Engine) {
 		users.POST("/realname-auth", middlewares.AuthRequired(), handlers.RealNameAuth)
 		users.GET("/realname-auth", middlewares.AuthRequired(), handlers.GetRealNameAuth)
 		users.GET("/my-tasks", middlewares.AuthRequired(), handlers.GetMyTasks)
+		users.GET("/favorites", middlewares.AuthRequired(), handlers.GetFavoriteTasks)
+		users.GET("/recommendations", middlewares.AuthRequired(), middlewares.WorkerRequired(), handlers.GetRecommendedTasks)
+		users.GET("/invitations", middlewares.AuthRequired(), middlewares.WorkerRequired(), handlers.GetMyInvitations)
+		users.GET("/saved-searches", middlewares.AuthRequired(), handlers.GetSavedSearches)
+		users.POST("/saved-searches", middlewares.AuthRequired(), handlers.CreateSavedSearch)
+		users.PUT("/saved-searches/:uuid", middlewares.AuthRequired(), handlers.UpdateSavedSearch)
+		users.DELETE("/saved-searches/:uuid", middlewares.AuthRequired(), handlers.DeleteSavedSearch)
+	}
+
+	// Worker directory routes
+	workers := api.Group("/workers")
+	workers.Use(middlewares.AuthRequired())
+	{
+		workers.GET("", middlewares.EmployerRequired(), handlers.GetWorkers)
+		workers.GET("/:uuid", handlers.GetWorkerProfile)
+	}
+
+	// Skill routes
+	skills := api.Group("/skills")
+	{
+		skills.GET("/autocomplete", handlers.AutocompleteSkills)
+		skills.GET("/categories", handlers.GetSkillCategories)
 	}
 
 	// Task routes
 	tasks := api.Group("/tasks")
 	{
-		tasks.GET("", handlers.GetTasks)
+		tasks.GET("", middlewares.OptionalAuth(), handlers.GetTasks)
 		tasks.POST("", middlewares.AuthRequired(), middlewares.EmployerRequired(), handlers.CreateTask)
+		tasks.GET("/drafts", middlewares.AuthRequired(), middlewares.EmployerRequired(), handlers.GetTaskDrafts)
+		tasks.POST("/drafts", middlewares.AuthRequired(), middlewares.EmployerRequired(), handlers.CreateTaskDraft)
 		tasks.GET("/:uuid", middlewares.OptionalAuth(), handlers.GetTaskByUUID)
+		tasks.PUT("/:uuid/draft", middlewares.AuthRequired(), middlewares.EmployerRequired(), handlers.UpdateTaskDraft)
+		tasks.PUT("/:uuid/publish", middlewares.AuthRequired(), middlewares.EmployerRequired(), handlers.PublishTask)
+		tasks.PUT("/:uuid/schedule", middlewares.AuthRequired(), middlewares.EmployerRequired(), handlers.ScheduleTaskPublish)
+		tasks.DELETE("/:uuid/schedule", middlewares.AuthRequired(), middlewares.EmployerRequired(), handlers.CancelScheduledPublish)
+		tasks.POST("/:uuid/clone", middlewares.AuthRequired(), middlewares.EmployerRequired(), handlers.CloneTask)
 		tasks.POST("/:uuid/apply", middlewares.AuthRequired(), middlewares.WorkerRequired(), handlers.ApplyToTask)
+		tasks.GET("/:uuid/bids", middlewares.AuthRequired(), middlewares.EmployerRequired(), handlers.GetTaskBids)
 		tasks.PUT("/:uuid/complete", middlewares.AuthRequired(), middlewares.WorkerRequired(), handlers.CompleteTask)
 		tasks.PUT("/:uuid/confirm", middlewares.AuthRequired(), middlewares.EmployerRequired(), handlers.ConfirmTaskCompletion)
+		tasks.POST("/:uuid/favorite", middlewares.AuthRequired(), handlers.FavoriteTask)
+		tasks.DELETE("/:uuid/favorite", middlewares.AuthRequired(), handlers.UnfavoriteTask)
+		tasks.PUT("/:uuid/quit", middlewares.AuthRequired(), middlewares.WorkerRequired(), handlers.QuitTask)
+		tasks.GET("/:uuid/milestones", middlewares.AuthRequired(), handlers.GetTaskMilestones)
+		tasks.POST("/:uuid/milestones", middlewares.AuthRequired(), middlewares.EmployerRequired(), handlers.CreateTaskMilestones)
+		tasks.GET("/:uuid/timesheets", middlewares.AuthRequired(), handlers.GetTaskTimesheets)
+		tasks.POST("/:uuid/timesheets", middlewares.AuthRequired(), middlewares.WorkerRequired(), handlers.CreateTimesheetEntry)
+		tasks.POST("/:uuid/clock-in", middlewares.AuthRequired(), middlewares.WorkerRequired(), handlers.ClockIn)
+		tasks.POST("/:uuid/clock-out", middlewares.AuthRequired(), middlewares.WorkerRequired(), handlers.ClockOut)
+		tasks.GET("/:uuid/attendance", middlewares.AuthRequired(), handlers.GetTaskAttendance)
+		tasks.POST("/:uuid/check-in", middlewares.AuthRequired(), middlewares.WorkerRequired(), handlers.CheckIn)
+		tasks.POST("/:uuid/check-out", middlewares.AuthRequired(), middlewares.WorkerRequired(), handlers.CheckOut)
+		tasks.GET("/:uuid/invitations", middlewares.AuthRequired(), middlewares.EmployerRequired(), handlers.GetTaskInvitations)
+		tasks.POST("/:uuid/invitations", middlewares.AuthRequired(), middlewares.EmployerRequired(), handlers.CreateTaskInvitation)
+		tasks.GET("/:uuid/questions", middlewares.OptionalAuth(), handlers.GetTaskQuestions)
+		tasks.POST("/:uuid/questions", middlewares.AuthRequired(), handlers.AskTaskQuestion)
+	}
+
+	// Task template routes
+	templates := api.Group("/task-templates")
+	templates.Use(middlewares.AuthRequired(), middlewares.EmployerRequired())
+	{
+		templates.GET("", handlers.GetTaskTemplates)
+		templates.POST("", handlers.CreateTaskTemplate)
+		templates.GET("/:uuid", handlers.GetTaskTemplate)
+		templates.PUT("/:uuid", handlers.UpdateTaskTemplate)
+		templates.DELETE("/:uuid", handlers.DeleteTaskTemplate)
+		templates.POST("/:uuid/tasks", handlers.CreateTaskFromTemplate)
+	}
+
+	// Task Q&A routes
+	taskQuestions := api.Group("/task-questions")
+	taskQuestions.Use(middlewares.AuthRequired())
+	{
+		taskQuestions.PUT("/:uuid/answer", middlewares.EmployerRequired(), handlers.AnswerTaskQuestion)
+		taskQuestions.DELETE("/:uuid", handlers.DeleteTaskQuestion)
+		taskQuestions.POST("/:uuid/report", handlers.ReportTaskQuestion)
+		taskQuestions.PUT("/:uuid/moderation", handlers.ModerateTaskQuestion)
+	}
+
+	// Recurring task routes
+	recurrences := api.Group("/task-recurrences")
+	recurrences.Use(middlewares.AuthRequired(), middlewares.EmployerRequired())
+	{
+		recurrences.GET("", handlers.GetTaskRecurrences)
+		recurrences.POST("", handlers.CreateTaskRecurrence)
+		recurrences.GET("/:uuid", handlers.GetTaskRecurrence)
+		recurrences.PUT("/:uuid/pause", handlers.PauseTaskRecurrence)
+		recurrences.PUT("/:uuid/resume", handlers.ResumeTaskRecurrence)
+		recurrences.DELETE("/:uuid", handlers.EndTaskRecurrence)
+	}
+
+	// Timesheet routes
+	timesheets := api.Group("/timesheets")
+	timesheets.Use(middlewares.AuthRequired(), middlewares.EmployerRequired())
+	{
+		timesheets.PUT("/:uuid/approve", handlers.ApproveTimesheetEntry)
+		timesheets.PUT("/:uuid/reject", handlers.RejectTimesheetEntry)
+	}
+
+	// Milestone routes
+	milestones := api.Group("/milestones")
+	milestones.Use(middlewares.AuthRequired())
+	{
+		milestones.PUT("/:uuid/submit", middlewares.WorkerRequired(), handlers.SubmitMilestone)
+		milestones.PUT("/:uuid/approve", middlewares.EmployerRequired(), handlers.ApproveMilestone)
+		milestones.PUT("/:uuid/reject", middlewares.EmployerRequired(), handlers.RejectMilestone)
 	}
 
 	// Application routes
@@ -66,6 +163,49 @@ func SetupRoutes(r *gin.Engine) {
 		applications.PUT("/:uuid/accept", middlewares.EmployerRequired(), handlers.AcceptTaskApplication)
 	}
 
+	// Invitation routes
+	invitations := api.Group("/invitations")
+	invitations.Use(middlewares.AuthRequired(), middlewares.WorkerRequired())
+	{
+		invitations.PUT("/:uuid/accept", handlers.AcceptInvitation)
+		invitations.PUT("/:uuid/decline", handlers.DeclineInvitation)
+	}
+
+	// Assignment routes
+	assignments := api.Group("/assignments")
+	assignments.Use(middlewares.AuthRequired())
+	{
+		assignments.PUT("/:uuid/settle", middlewares.EmployerRequired(), handlers.SettleQuitAssignment)
+		assignments.PUT("/:uuid/request-changes", middlewares.EmployerRequired(), handlers.RequestSubmissionChanges)
+	}
+
+	// Messaging routes
+	conversations := api.Group("/conversations")
+	conversations.Use(middlewares.AuthRequired())
+	{
+		conversations.GET("", handlers.GetConversations)
+		conversations.POST("", handlers.StartConversation)
+		conversations.GET("/unread-count", handlers.GetUnreadMessageCount)
+		conversations.GET("/:uuid/messages", handlers.GetConversationMessages)
+		conversations.POST("/:uuid/messages", handlers.SendMessage)
+		conversations.PUT("/:uuid/read", handlers.MarkConversationRead)
+	}
+
+	// Notification center routes
+	notifications := api.Group("/notifications")
+	notifications.Use(middlewares.AuthRequired())
+	{
+		notifications.GET("", handlers.GetNotifications)
+		notifications.GET("/unread-count", handlers.GetUnreadNotificationCount)
+		notifications.PUT("/read-all", handlers.MarkAllNotificationsRead)
+		notifications.GET("/preferences", handlers.GetNotificationPreferences)
+		notifications.PUT("/preferences", handlers.UpdateNotificationPreferences)
+		notifications.PUT("/:uuid/read", handlers.MarkNotificationRead)
+	}
+
+	// Real-time event stream
+	api.GET("/events/stream", middlewares.AuthRequired(), handlers.StreamEvents)
+
 	// Dashboard routes
 	dashboard := api.Group("/dashboard")
 	{
@@ -96,5 +236,14 @@ func SetupRoutes(r *gin.Engine) {
 	admin.Use(middlewares.AuthRequired(), middlewares.AdminRequired())
 	{
 		admin.GET("/dashboard", handlers.GetAdminDashboard)
+		admin.POST("/skill-categories", handlers.AdminCreateSkillCategory)
+		admin.GET("/skills/duplicates", handlers.AdminGetDuplicateSkills)
+		admin.POST("/skills/merge", handlers.AdminMergeSkills)
+		admin.PUT("/skills/:id", handlers.AdminUpdateSkill)
+		admin.POST("/skills/:id/synonyms", handlers.AdminAddSkillSynonym)
+		admin.DELETE("/skill-synonyms/:id", handlers.AdminDeleteSkillSynonym)
+		admin.GET("/task-questions", handlers.AdminGetTaskQuestions)
+		admin.GET("/conversations", handlers.AdminGetConversations)
+		admin.GET("/conversations/:uuid/messages", handlers.GetConversationMessages)
 	}
 }
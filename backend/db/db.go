@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"zhlg/backend/models"
+	"zhlg/backend/services"
 
 	"gopkg.in/yaml.v2"
 	"gorm.io/driver/mysql"
@@ -135,10 +136,30 @@ func migrateDB() {
 		&models.Transaction{},
 		&models.WithdrawalAccount{},
 		&models.Skill{},
+		&models.SkillCategory{},
+		&models.SkillSynonym{},
 		&models.Review{},
 		&models.UserPortfolio{},
 		&models.ActivityLog{},
 		&models.UserFavorite{},
+		&models.TaskMilestone{},
+		&models.TaskSubmission{},
+		&models.TimesheetEntry{},
+		&models.AttendanceRecord{},
+		&models.TaskRecommendation{},
+		&models.TaskInvitation{},
+		&models.SavedSearch{},
+		&models.SavedSearchMatch{},
+		&models.TaskTemplate{},
+		&models.TaskRecurrence{},
+		&models.TaskScreeningQuestion{},
+		&models.ScreeningAnswer{},
+		&models.TaskQuestion{},
+		&models.TaskQuestionReport{},
+		&models.Conversation{},
+		&models.Message{},
+		&models.Notification{},
+		&models.NotificationPreference{},
 	)
 
 	// 执行自定义迁移
@@ -146,8 +167,27 @@ func migrateDB() {
 		log.Printf("警告: Reviews表迁移失败: %v", err)
 	}
 
+	if err := MigrateAttendanceIndex(); err != nil {
+		log.Printf("警告: 考勤记录索引迁移失败: %v", err)
+	}
+
 	// 初始化模型间的关系
 	models.InitModels(DB)
+
+	// 任务全文搜索索引
+	services.EnsureTaskSearchIndex(DB)
+
+	// 附近搜索的空间索引
+	services.EnsureGeoIndex(DB)
+
+	// 用户资料坐标只保留约1公里精度
+	services.CoarsenUserLocations(DB)
+
+	// 回填技能的规范化名称
+	services.EnsureSkillNormalization(DB)
+
+	// 回填任务分配的约定报酬
+	services.EnsureAssignmentContracts(DB)
 }
 
 // getEnv 从环境变量读取配置，如果不存在则使用默认值
@@ -159,6 +199,23 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+// MigrateAttendanceIndex 将考勤记录的 (任务分配, 日期) 索引改为唯一索引。AutoMigrate 不会修改
+// 已存在的同名索引，因此旧库需要单独重建
+func MigrateAttendanceIndex() error {
+	var nonUnique []int
+	if err := DB.Raw("SELECT non_unique FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'attendance_records' AND index_name = 'idx_attendance_assignment_date'").
+		Pluck("non_unique", &nonUnique).Error; err != nil {
+		return err
+	}
+	if len(nonUnique) == 0 || nonUnique[0] == 0 {
+		return nil
+	}
+
+	log.Println("将考勤记录索引改为唯一索引")
+	return DB.Exec("ALTER TABLE attendance_records DROP INDEX idx_attendance_assignment_date, " +
+		"ADD UNIQUE INDEX idx_attendance_assignment_date (task_assignment_id, work_date)").Error
+}
+
 // MigrateReviews 执行reviews表的迁移
 func MigrateReviews() error {
 	log.Println("开始执行reviews表结构更新")
@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+	"zhlg/backend/services"
+	"zhlg/backend/utils"
+
+	"github.com/google/uuid"
+)
+
+// noShowCheckInterval is how often workers who have not checked in are looked for
+const noShowCheckInterval = 15 * time.Minute
+
+// defaultNoShowGraceHours is how long into a work day a worker may still check in before
+// being marked as a no-show, unless NO_SHOW_GRACE_HOURS overrides it
+const defaultNoShowGraceHours = 10
+
+// DetectNoShows marks working assignments on geofenced tasks that have no attendance record
+// for today once the grace period has passed, and notifies the employer.
+func DetectNoShows() error {
+	now := time.Now()
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	grace := time.Duration(utils.EnvInt("NO_SHOW_GRACE_HOURS", defaultNoShowGraceHours)) * time.Hour
+	if now.Before(today.Add(grace)) {
+		return nil
+	}
+
+	var tasks []models.Task
+	if err := db.DB.Where("status = ? AND location_type = ? AND latitude IS NOT NULL AND longitude IS NOT NULL AND geo_approximate = ? AND start_date <= ? AND end_date >= ?",
+		models.TaskStatusInProgress, models.LocationTypeOffline, false, today, today).Find(&tasks).Error; err != nil {
+		return err
+	}
+
+	for _, task := range tasks {
+		var assignments []models.TaskAssignment
+		if err := db.DB.Where("task_id = ? AND worker_status = ?", task.ID, models.WorkerStatusWorking).
+			Where("NOT EXISTS (SELECT 1 FROM attendance_records ar WHERE ar.task_assignment_id = task_assignments.id AND ar.work_date = ?)", today).
+			Find(&assignments).Error; err != nil {
+			return err
+		}
+
+		for _, assignment := range assignments {
+			// 今天才接到的任务不算缺勤
+			if assignment.AssignedAt.After(today) {
+				continue
+			}
+
+			tx := services.BeginTx(db.DB)
+			record := models.AttendanceRecord{
+				UUID:             uuid.New().String(),
+				TaskID:           task.ID,
+				TaskAssignmentID: assignment.ID,
+				WorkerID:         assignment.WorkerID,
+				WorkDate:         today,
+				Status:           models.AttendanceStatusNoShow,
+			}
+			if err := tx.Create(&record).Error; err != nil {
+				tx.Rollback()
+				log.Printf("[DetectNoShows] 创建缺勤记录失败: assignment=%d, err=%v", assignment.ID, err)
+				continue
+			}
+
+			services.RecordActivity(tx, assignment.WorkerID, task.EmployerID, "attendance_record", record.UUID, "no_show",
+				fmt.Sprintf("零工今日未到岗签到：%s", task.Title), map[string]interface{}{
+					"task_uuid":       task.UUID,
+					"assignment_uuid": assignment.UUID,
+					"work_date":       today.Format("2006-01-02"),
+				})
+
+			if err := services.CommitTx(tx); err != nil {
+				log.Printf("[DetectNoShows] 提交事务失败: %v", err)
+				continue
+			}
+			log.Printf("[DetectNoShows] 标记缺勤: task=%s, assignment=%s", task.UUID, assignment.UUID)
+		}
+	}
+	return nil
+}
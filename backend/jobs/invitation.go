@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+)
+
+// invitationExpiryInterval is how often lapsed invitations are closed
+const invitationExpiryInterval = 30 * time.Minute
+
+// ExpireInvitations marks pending invitations past their expiry time as expired so they
+// drop out of the worker's inbox and stop counting as outstanding.
+func ExpireInvitations() error {
+	result := db.DB.Model(&models.TaskInvitation{}).
+		Where("status = ? AND expires_at <= ?", models.InvitationStatusPending, time.Now()).
+		Update("status", models.InvitationStatusExpired)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("[ExpireInvitations] 已过期邀请: %d", result.RowsAffected)
+	}
+	return nil
+}
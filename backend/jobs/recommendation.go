@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/models"
+	"zhlg/backend/services"
+
+	"gorm.io/gorm"
+)
+
+// recommendationRefreshInterval is how often every worker's recommendation feed is rebuilt
+const recommendationRefreshInterval = time.Hour
+
+// recommendationBatchSize is how many workers are loaded at a time while refreshing feeds
+const recommendationBatchSize = 100
+
+// RefreshRecommendationFeeds precomputes the recommendation feed of every worker so the
+// recommendations endpoint can serve them without scoring on each request
+func RefreshRecommendationFeeds() error {
+	var workers []models.User
+	refreshed, failed := 0, 0
+	result := db.DB.Select("id").Where("user_type = ?", models.UserTypeWorker).
+		FindInBatches(&workers, recommendationBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, w := range workers {
+				if err := services.RefreshRecommendations(db.DB, w.ID); err != nil {
+					log.Printf("[RefreshRecommendationFeeds] 计算推荐失败: worker=%d, err=%v", w.ID, err)
+					failed++
+					continue
+				}
+				refreshed++
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	log.Printf("[RefreshRecommendationFeeds] 已更新 %d 个零工的推荐，失败 %d 个", refreshed, failed)
+	return nil
+}
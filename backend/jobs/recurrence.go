@@ -0,0 +1,17 @@
+package jobs
+
+import (
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/services"
+)
+
+// recurrenceCheckInterval is how often recurring series are checked for occurrences
+// entering their lead window
+const recurrenceCheckInterval = time.Hour
+
+// MaterializeRecurringTasks posts the upcoming tasks of active recurring series
+func MaterializeRecurringTasks() error {
+	return services.MaterializeRecurrences(db.DB, time.Now())
+}
@@ -0,0 +1,17 @@
+package jobs
+
+import (
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/services"
+)
+
+// savedSearchDigestCheckInterval is how often daily saved searches are checked for a
+// digest that is due
+const savedSearchDigestCheckInterval = time.Hour
+
+// SendSavedSearchDigests sends the daily new-task digest of saved searches
+func SendSavedSearchDigests() error {
+	return services.SendSavedSearchDigests(db.DB)
+}
@@ -0,0 +1,17 @@
+package jobs
+
+import (
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/services"
+)
+
+// scheduledPublishInterval is how often drafts are checked for a scheduled publish time
+// that has come
+const scheduledPublishInterval = time.Minute
+
+// PublishScheduledTasks publishes drafts whose scheduled publish time has come
+func PublishScheduledTasks() error {
+	return services.PublishScheduledTasks(db.DB, time.Now())
+}
@@ -0,0 +1,31 @@
+// Package jobs runs periodic background work such as attendance checks and feed rebuilds
+package jobs
+
+import (
+	"log"
+	"time"
+)
+
+// Start launches every background job in its own goroutine. It must be called after the
+// database connection has been initialized.
+func Start() {
+	go runEvery("DetectNoShows", noShowCheckInterval, DetectNoShows)
+	go runEvery("RefreshRecommendationFeeds", recommendationRefreshInterval, RefreshRecommendationFeeds)
+	go runEvery("ExpireInvitations", invitationExpiryInterval, ExpireInvitations)
+	go runEvery("SendSavedSearchDigests", savedSearchDigestCheckInterval, SendSavedSearchDigests)
+	go runEvery("MaterializeRecurringTasks", recurrenceCheckInterval, MaterializeRecurringTasks)
+	go runEvery("PublishScheduledTasks", scheduledPublishInterval, PublishScheduledTasks)
+	go runEvery("ExpireStaleTasks", taskExpiryInterval, ExpireStaleTasks)
+}
+
+// runEvery runs job once per interval until the process exits, logging failures
+func runEvery(name string, interval time.Duration, job func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := job(); err != nil {
+			log.Printf("[%s] 定时任务执行失败: %v", name, err)
+		}
+	}
+}
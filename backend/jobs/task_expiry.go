@@ -0,0 +1,17 @@
+package jobs
+
+import (
+	"time"
+
+	"zhlg/backend/db"
+	"zhlg/backend/services"
+)
+
+// taskExpiryInterval is how often recruiting tasks are checked for a passed recruiting
+// deadline or end date
+const taskExpiryInterval = 15 * time.Minute
+
+// ExpireStaleTasks stops recruiting on tasks past their recruiting deadline or end date
+func ExpireStaleTasks() error {
+	return services.ExpireStaleTasks(db.DB, time.Now())
+}
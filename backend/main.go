@@ -8,6 +8,7 @@ import (
 
 	"zhlg/backend/api/routes"
 	"zhlg/backend/db"
+	"zhlg/backend/jobs"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -17,6 +18,9 @@ func main() {
 	// 初始化数据库连接
 	db.Init()
 
+	// 启动后台定时任务
+	jobs.Start()
+
 	// Set Gin mode based on environment
 	mode := os.Getenv("GIN_MODE")
 	if mode == "" {
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AttendanceStatus represents the status of an on-site attendance record
+type AttendanceStatus string
+
+// Enum values for AttendanceStatus
+const (
+	AttendanceStatusCheckedIn  AttendanceStatus = "checked_in"
+	AttendanceStatusCheckedOut AttendanceStatus = "checked_out"
+	AttendanceStatusNoShow     AttendanceStatus = "no_show"
+)
+
+// AttendanceRecord represents the attendance_records table. Workers on offline tasks check
+// in and out with their device location; a missing check-in on a work day is a no-show.
+type AttendanceRecord struct {
+	ID                  uint             `gorm:"primary_key" json:"id"`
+	UUID                string           `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
+	TaskID              uint             `gorm:"index;not null" json:"task_id"`
+	TaskAssignmentID    uint             `gorm:"uniqueIndex:idx_attendance_assignment_date;not null" json:"task_assignment_id"`
+	WorkerID            uint             `gorm:"index;not null" json:"worker_id"`
+	WorkDate            time.Time        `gorm:"type:date;uniqueIndex:idx_attendance_assignment_date;not null" json:"work_date"`
+	Status              AttendanceStatus `gorm:"type:enum('checked_in','checked_out','no_show');not null;index" json:"status"`
+	CheckInAt           *time.Time       `json:"check_in_at"`
+	CheckInLatitude     *float64         `gorm:"type:decimal(10,7)" json:"check_in_latitude"`
+	CheckInLongitude    *float64         `gorm:"type:decimal(10,7)" json:"check_in_longitude"`
+	CheckInDistance     *float64         `gorm:"type:decimal(10,2)" json:"check_in_distance"`
+	CheckOutAt          *time.Time       `json:"check_out_at"`
+	CheckOutLatitude    *float64         `gorm:"type:decimal(10,7)" json:"check_out_latitude"`
+	CheckOutLongitude   *float64         `gorm:"type:decimal(10,7)" json:"check_out_longitude"`
+	CheckOutDistance    *float64         `gorm:"type:decimal(10,2)" json:"check_out_distance"`
+	CheckOutWithinFence bool             `gorm:"not null;default:false" json:"check_out_within_fence"`
+	TimesheetEntryID    *uint            `gorm:"index" json:"timesheet_entry_id"`
+	CreatedAt           time.Time        `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt           time.Time        `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Relations
+	Task           Task            `gorm:"foreignkey:TaskID" json:"task,omitempty"`
+	TaskAssignment TaskAssignment  `gorm:"foreignkey:TaskAssignmentID" json:"task_assignment,omitempty"`
+	Worker         User            `gorm:"foreignkey:WorkerID" json:"worker,omitempty"`
+	TimesheetEntry *TimesheetEntry `gorm:"foreignkey:TimesheetEntryID" json:"timesheet_entry,omitempty"`
+}
+
+// CheckOut records the worker leaving the site
+func (a *AttendanceRecord) CheckOut(lat, lng, distance float64, withinFence bool) {
+	now := time.Now()
+	a.Status = AttendanceStatusCheckedOut
+	a.CheckOutAt = &now
+	a.CheckOutLatitude = &lat
+	a.CheckOutLongitude = &lng
+	a.CheckOutDistance = &distance
+	a.CheckOutWithinFence = withinFence
+}
+
+// BeforeCreate is a GORM hook that runs before creating an attendance record
+func (a *AttendanceRecord) BeforeCreate(tx *gorm.DB) (err error) {
+	// Generate UUID if not set
+	if a.UUID == "" {
+		a.UUID = uuid.New().String()
+	}
+	return nil
+}
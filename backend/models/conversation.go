@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Conversation represents the conversations table, the message thread between a task's
+// employer and one worker. There is at most one conversation per task and worker; it is
+// linked to the worker's application when there is one.
+type Conversation struct {
+	ID                uint       `gorm:"primary_key" json:"id"`
+	UUID              string     `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
+	TaskID            uint       `gorm:"uniqueIndex:idx_conversation_task_worker;not null" json:"task_id"`
+	TaskApplicationID *uint      `gorm:"index" json:"task_application_id"`
+	EmployerID        uint       `gorm:"index;not null" json:"employer_id"`
+	WorkerID          uint       `gorm:"uniqueIndex:idx_conversation_task_worker;index;not null" json:"worker_id"`
+	LastMessageAt     *time.Time `gorm:"index" json:"last_message_at"`
+	CreatedAt         time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt         time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Relations
+	Task            Task            `gorm:"foreignkey:TaskID" json:"task,omitempty"`
+	TaskApplication TaskApplication `gorm:"foreignkey:TaskApplicationID" json:"task_application,omitempty"`
+	Employer        User            `gorm:"foreignkey:EmployerID" json:"employer,omitempty"`
+	Worker          User            `gorm:"foreignkey:WorkerID" json:"worker,omitempty"`
+	Messages        []Message       `gorm:"foreignkey:ConversationID" json:"messages,omitempty"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a conversation record
+func (c *Conversation) BeforeCreate(tx *gorm.DB) (err error) {
+	// Generate UUID if not set
+	if c.UUID == "" {
+		c.UUID = uuid.New().String()
+	}
+	return nil
+}
+
+// HasParticipant reports whether the user is the employer or the worker of the conversation
+func (c *Conversation) HasParticipant(userID uint) bool {
+	return c.EmployerID == userID || c.WorkerID == userID
+}
+
+// OtherParticipant returns the user on the other side of the conversation
+func (c *Conversation) OtherParticipant(userID uint) uint {
+	if c.EmployerID == userID {
+		return c.WorkerID
+	}
+	return c.EmployerID
+}
+
+// MessageAttachment is a single file uploaded with a message
+type MessageAttachment struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Size int64  `json:"size"`
+}
+
+// Message represents the messages table. ReadAt is the read receipt, set when the recipient
+// opens the conversation.
+type Message struct {
+	ID             uint           `gorm:"primary_key" json:"id"`
+	UUID           string         `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
+	ConversationID uint           `gorm:"index:idx_messages_conversation_created;not null" json:"conversation_id"`
+	SenderID       uint           `gorm:"index;not null" json:"sender_id"`
+	Body           string         `gorm:"type:text;not null" json:"body"`
+	Attachments    datatypes.JSON `gorm:"type:json" json:"attachments"`
+	ReadAt         *time.Time     `gorm:"index" json:"read_at"`
+	CreatedAt      time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP;index:idx_messages_conversation_created" json:"created_at"`
+
+	// Relations
+	Conversation Conversation `gorm:"foreignkey:ConversationID" json:"conversation,omitempty"`
+	Sender       User         `gorm:"foreignkey:SenderID" json:"sender,omitempty"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a message record
+func (m *Message) BeforeCreate(tx *gorm.DB) (err error) {
+	// Generate UUID if not set
+	if m.UUID == "" {
+		m.UUID = uuid.New().String()
+	}
+	if len(m.Attachments) == 0 {
+		m.Attachments = datatypes.JSON([]byte("[]"))
+	}
+	return nil
+}
@@ -12,6 +12,8 @@ func InitModels(db *gorm.DB) {
 		&User{},
 		&VerificationCode{},
 		&Skill{},
+		&SkillCategory{},
+		&SkillSynonym{},
 		&Task{},
 		&TaskApplication{},
 		&TaskAssignment{},
@@ -20,6 +22,24 @@ func InitModels(db *gorm.DB) {
 		&Review{},
 		&UserPortfolio{},
 		&ActivityLog{},
+		&TaskMilestone{},
+		&TaskSubmission{},
+		&TimesheetEntry{},
+		&AttendanceRecord{},
+		&TaskRecommendation{},
+		&TaskInvitation{},
+		&SavedSearch{},
+		&SavedSearchMatch{},
+		&TaskTemplate{},
+		&TaskRecurrence{},
+		&TaskScreeningQuestion{},
+		&ScreeningAnswer{},
+		&TaskQuestion{},
+		&TaskQuestionReport{},
+		&Conversation{},
+		&Message{},
+		&Notification{},
+		&NotificationPreference{},
 	)
 
 	// Define foreign key relationships using the new GORM API
@@ -31,13 +51,48 @@ func InitModels(db *gorm.DB) {
 	db.Migrator().CreateConstraint(&TaskSkill{}, "fk_task_skills_task")
 	db.Migrator().CreateConstraint(&TaskSkill{}, "fk_task_skills_skill")
 
+	db.Migrator().CreateConstraint(&SkillSynonym{}, "fk_skill_synonyms_skill")
+
 	db.Migrator().CreateConstraint(&TaskApplication{}, "fk_task_applications_task")
 	db.Migrator().CreateConstraint(&TaskApplication{}, "fk_task_applications_worker")
 
+	db.Migrator().CreateConstraint(&TaskScreeningQuestion{}, "fk_task_screening_questions_task")
+	db.Migrator().CreateConstraint(&ScreeningAnswer{}, "fk_screening_answers_application")
+	db.Migrator().CreateConstraint(&ScreeningAnswer{}, "fk_screening_answers_question")
+	db.Migrator().CreateConstraint(&TaskQuestion{}, "fk_task_questions_task")
+	db.Migrator().CreateConstraint(&TaskQuestionReport{}, "fk_task_question_reports_question")
+	db.Migrator().CreateConstraint(&Conversation{}, "fk_conversations_task")
+	db.Migrator().CreateConstraint(&Message{}, "fk_messages_conversation")
+	db.Migrator().CreateConstraint(&Notification{}, "fk_notifications_user")
+
 	db.Migrator().CreateConstraint(&TaskAssignment{}, "fk_task_assignments_application")
 	db.Migrator().CreateConstraint(&TaskAssignment{}, "fk_task_assignments_task")
 	db.Migrator().CreateConstraint(&TaskAssignment{}, "fk_task_assignments_worker")
 
+	db.Migrator().CreateConstraint(&TaskMilestone{}, "fk_task_milestones_task")
+
+	db.Migrator().CreateConstraint(&TaskSubmission{}, "fk_task_submissions_task")
+	db.Migrator().CreateConstraint(&TaskSubmission{}, "fk_task_submissions_assignment")
+
+	db.Migrator().CreateConstraint(&TimesheetEntry{}, "fk_timesheet_entries_task")
+	db.Migrator().CreateConstraint(&TimesheetEntry{}, "fk_timesheet_entries_assignment")
+
+	db.Migrator().CreateConstraint(&AttendanceRecord{}, "fk_attendance_records_task")
+	db.Migrator().CreateConstraint(&AttendanceRecord{}, "fk_attendance_records_assignment")
+
+	db.Migrator().CreateConstraint(&TaskRecommendation{}, "fk_task_recommendations_worker")
+	db.Migrator().CreateConstraint(&TaskRecommendation{}, "fk_task_recommendations_task")
+
+	db.Migrator().CreateConstraint(&TaskInvitation{}, "fk_task_invitations_task")
+	db.Migrator().CreateConstraint(&TaskInvitation{}, "fk_task_invitations_worker")
+
+	db.Migrator().CreateConstraint(&SavedSearch{}, "fk_saved_searches_user")
+	db.Migrator().CreateConstraint(&SavedSearchMatch{}, "fk_saved_search_matches_saved_search")
+	db.Migrator().CreateConstraint(&SavedSearchMatch{}, "fk_saved_search_matches_task")
+
+	db.Migrator().CreateConstraint(&TaskTemplate{}, "fk_task_templates_employer")
+	db.Migrator().CreateConstraint(&TaskRecurrence{}, "fk_task_recurrences_employer")
+
 	db.Migrator().CreateConstraint(&Transaction{}, "fk_transactions_user")
 	db.Migrator().CreateConstraint(&Transaction{}, "fk_transactions_task_assignment")
 
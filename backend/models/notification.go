@@ -0,0 +1,112 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// NotificationCategory groups notification types in the notification center
+type NotificationCategory string
+
+// Enum values for NotificationCategory
+const (
+	NotificationCategoryApplication NotificationCategory = "application"
+	NotificationCategoryTask        NotificationCategory = "task"
+	NotificationCategoryPayment     NotificationCategory = "payment"
+	NotificationCategoryReview      NotificationCategory = "review"
+	NotificationCategoryMessage     NotificationCategory = "message"
+)
+
+// NotificationType describes a kind of notification that users can turn off. Type matches
+// the action of the activity that creates it.
+type NotificationType struct {
+	Type     string               `json:"type"`
+	Category NotificationCategory `json:"category"`
+	Label    string               `json:"label"`
+}
+
+// NotificationTypes lists every notification type in display order
+var NotificationTypes = []NotificationType{
+	{"application_received", NotificationCategoryApplication, "收到新的任务申请"},
+	{"application_accepted", NotificationCategoryApplication, "申请已通过"},
+	{"application_rejected", NotificationCategoryApplication, "申请未通过"},
+	{"invitation_received", NotificationCategoryApplication, "收到任务邀请"},
+	{"invitation_accepted", NotificationCategoryApplication, "邀请已被接受"},
+	{"invitation_declined", NotificationCategoryApplication, "邀请被拒绝"},
+	{"submission_received", NotificationCategoryTask, "零工提交了任务成果"},
+	{"submission_changes_requested", NotificationCategoryTask, "任务成果需要修改"},
+	{"milestone_submitted", NotificationCategoryTask, "里程碑已提交"},
+	{"milestone_rejected", NotificationCategoryTask, "里程碑被驳回"},
+	{"assignment_quit", NotificationCategoryTask, "零工退出任务"},
+	{"no_show", NotificationCategoryTask, "零工缺勤"},
+	{"check_out_outside_fence", NotificationCategoryTask, "零工在签到范围外签退"},
+	{"timesheet_approved", NotificationCategoryTask, "工时已批准"},
+	{"timesheet_rejected", NotificationCategoryTask, "工时已驳回"},
+	{"task_published", NotificationCategoryTask, "定时发布成功"},
+	{"scheduled_publish_failed", NotificationCategoryTask, "定时发布失败"},
+	{"task_expired", NotificationCategoryTask, "任务到期关闭"},
+	{"task_question_asked", NotificationCategoryTask, "任务收到新的提问"},
+	{"task_question_answered", NotificationCategoryTask, "提问已被回答"},
+	{"saved_search_match", NotificationCategoryTask, "保存的搜索有新任务"},
+	{"payment_received", NotificationCategoryPayment, "任务报酬到账"},
+	{"milestone_paid", NotificationCategoryPayment, "里程碑已付款"},
+	{"assignment_settled", NotificationCategoryPayment, "退出结算已付款"},
+	{"withdrawal_requested", NotificationCategoryPayment, "提现申请已提交"},
+	{"review_received", NotificationCategoryReview, "收到新的评价"},
+	{"message_received", NotificationCategoryMessage, "收到新消息"},
+}
+
+// LookupNotificationType returns the description of a notification type
+func LookupNotificationType(notificationType string) (NotificationType, bool) {
+	for _, t := range NotificationTypes {
+		if t.Type == notificationType {
+			return t, true
+		}
+	}
+	return NotificationType{}, false
+}
+
+// Notification represents the notifications table, an entry in a user's notification
+// center. Payload holds the details of the activity that created it, such as the task UUID.
+type Notification struct {
+	ID         uint           `gorm:"primary_key" json:"id"`
+	UUID       string         `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
+	UserID     uint           `gorm:"index:idx_notifications_user_read;not null" json:"user_id"`
+	ActorID    *uint          `json:"actor_id"`
+	Type       string         `gorm:"type:varchar(50);not null;index" json:"type"`
+	Message    string         `gorm:"type:varchar(500);not null" json:"message"`
+	EntityType string         `gorm:"type:varchar(50)" json:"entity_type"`
+	EntityUUID string         `gorm:"type:varchar(36)" json:"entity_uuid"`
+	Payload    datatypes.JSON `gorm:"type:json" json:"payload"`
+	ReadAt     *time.Time     `gorm:"index:idx_notifications_user_read" json:"read_at"`
+	CreatedAt  time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"created_at"`
+
+	// Relations
+	User  User `gorm:"foreignkey:UserID" json:"user,omitempty"`
+	Actor User `gorm:"foreignkey:ActorID" json:"actor,omitempty"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a notification record
+func (n *Notification) BeforeCreate(tx *gorm.DB) (err error) {
+	// Generate UUID if not set
+	if n.UUID == "" {
+		n.UUID = uuid.New().String()
+	}
+	if len(n.Payload) == 0 {
+		n.Payload = datatypes.JSON([]byte("{}"))
+	}
+	return nil
+}
+
+// NotificationPreference represents the notification_preferences table. A user receives
+// every notification type unless a preference turns it off.
+type NotificationPreference struct {
+	ID        uint      `gorm:"primary_key" json:"id"`
+	UserID    uint      `gorm:"uniqueIndex:idx_notification_preference;not null" json:"user_id"`
+	Type      string    `gorm:"type:varchar(50);uniqueIndex:idx_notification_preference;not null" json:"type"`
+	Enabled   bool      `gorm:"not null;default:true" json:"enabled"`
+	UpdatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
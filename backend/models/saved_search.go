@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AlertFrequency controls how often a saved search notifies its owner about new tasks
+type AlertFrequency string
+
+// Enum values for AlertFrequency
+const (
+	AlertFrequencyInstant AlertFrequency = "instant"
+	AlertFrequencyDaily   AlertFrequency = "daily"
+	AlertFrequencyNever   AlertFrequency = "never"
+)
+
+// SavedSearch represents the saved_searches table. It stores the task list filters a user
+// runs repeatedly so newly published tasks matching them can be pushed to the user. A
+// "near" filter is resolved to coordinates when the search is saved.
+type SavedSearch struct {
+	ID             uint           `gorm:"primary_key" json:"id"`
+	UUID           string         `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
+	UserID         uint           `gorm:"index;not null" json:"user_id"`
+	Name           string         `gorm:"type:varchar(100);not null" json:"name"`
+	SearchQuery    string         `gorm:"type:varchar(255)" json:"search_query"`
+	Skills         string         `gorm:"type:varchar(500)" json:"skills"`
+	LocationType   string         `gorm:"type:varchar(20)" json:"location_type"`
+	Near           string         `gorm:"type:varchar(100)" json:"near"`
+	NearLatitude   *float64       `gorm:"type:decimal(10,7)" json:"near_latitude"`
+	NearLongitude  *float64       `gorm:"type:decimal(10,7)" json:"near_longitude"`
+	Radius         float64        `gorm:"type:decimal(6,2);not null;default:0" json:"radius"`
+	Frequency      AlertFrequency `gorm:"type:enum('instant','daily','never');not null;default:'instant';index" json:"frequency"`
+	NotifyEmail    bool           `gorm:"not null;default:false" json:"notify_email"`
+	LastNotifiedAt *time.Time     `json:"last_notified_at"`
+	CreatedAt      time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt      time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Relations
+	User User `gorm:"foreignkey:UserID" json:"user,omitempty"`
+}
+
+// HasNear reports whether the search is restricted to tasks around a point
+func (s *SavedSearch) HasNear() bool {
+	return s.NearLatitude != nil && s.NearLongitude != nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating a saved search record
+func (s *SavedSearch) BeforeCreate(tx *gorm.DB) (err error) {
+	// Generate UUID if not set
+	if s.UUID == "" {
+		s.UUID = uuid.New().String()
+	}
+	return nil
+}
+
+// SavedSearchMatch represents the saved_search_matches table, a newly published task that
+// matched a saved search. Matches for daily searches wait here until the digest is sent.
+type SavedSearchMatch struct {
+	ID            uint       `gorm:"primary_key" json:"id"`
+	SavedSearchID uint       `gorm:"uniqueIndex:idx_saved_search_match;not null" json:"saved_search_id"`
+	TaskID        uint       `gorm:"uniqueIndex:idx_saved_search_match;not null" json:"task_id"`
+	MatchedAt     time.Time  `gorm:"not null" json:"matched_at"`
+	NotifiedAt    *time.Time `gorm:"index" json:"notified_at"`
+
+	// Relations
+	SavedSearch SavedSearch `gorm:"foreignkey:SavedSearchID" json:"saved_search,omitempty"`
+	Task        Task        `gorm:"foreignkey:TaskID" json:"task,omitempty"`
+}
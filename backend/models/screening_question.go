@@ -0,0 +1,101 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ScreeningQuestionType represents how a screening question is answered
+type ScreeningQuestionType string
+
+// Enum values for ScreeningQuestionType
+const (
+	ScreeningQuestionText           ScreeningQuestionType = "text"
+	ScreeningQuestionSingleChoice   ScreeningQuestionType = "single_choice"
+	ScreeningQuestionMultipleChoice ScreeningQuestionType = "multiple_choice"
+)
+
+// TaskScreeningQuestion represents the task_screening_questions table, a question
+// applicants answer when applying to a task. Options and KnockoutAnswers are JSON lists
+// of strings; choosing any knockout answer rejects the application automatically.
+type TaskScreeningQuestion struct {
+	ID              uint                  `gorm:"primary_key" json:"id"`
+	UUID            string                `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
+	TaskID          uint                  `gorm:"index;not null" json:"task_id"`
+	Question        string                `gorm:"type:varchar(500);not null" json:"question"`
+	Type            ScreeningQuestionType `gorm:"type:enum('text','single_choice','multiple_choice');not null" json:"type"`
+	Options         datatypes.JSON        `gorm:"type:json" json:"options"`
+	Required        bool                  `gorm:"not null;default:false" json:"required"`
+	KnockoutAnswers datatypes.JSON        `gorm:"type:json" json:"knockout_answers"`
+	SortOrder       uint                  `gorm:"not null;default:0" json:"sort_order"`
+	CreatedAt       time.Time             `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// Relations
+	Task Task `gorm:"foreignkey:TaskID" json:"task,omitempty"`
+}
+
+// OptionList returns the choices of a choice question
+func (q *TaskScreeningQuestion) OptionList() []string {
+	return jsonStrings(q.Options)
+}
+
+// KnockoutList returns the answers that reject an application
+func (q *TaskScreeningQuestion) KnockoutList() []string {
+	return jsonStrings(q.KnockoutAnswers)
+}
+
+// IsKnockout reports whether any of the chosen options is a knockout answer
+func (q *TaskScreeningQuestion) IsKnockout(choices []string) bool {
+	for _, knockout := range q.KnockoutList() {
+		for _, choice := range choices {
+			if choice == knockout {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// BeforeCreate is a GORM hook that runs before creating a screening question record
+func (q *TaskScreeningQuestion) BeforeCreate(tx *gorm.DB) (err error) {
+	// Generate UUID if not set
+	if q.UUID == "" {
+		q.UUID = uuid.New().String()
+	}
+	return nil
+}
+
+// ScreeningAnswer represents the screening_answers table, an applicant's answer to one
+// screening question. Text answers use Text; choice answers list the chosen options in
+// Choices.
+type ScreeningAnswer struct {
+	ID            uint           `gorm:"primary_key" json:"id"`
+	ApplicationID uint           `gorm:"uniqueIndex:idx_screening_answer;not null" json:"application_id"`
+	QuestionID    uint           `gorm:"uniqueIndex:idx_screening_answer;not null" json:"question_id"`
+	Text          *string        `gorm:"type:text" json:"text"`
+	Choices       datatypes.JSON `gorm:"type:json" json:"choices"`
+	Knockout      bool           `gorm:"not null;default:false" json:"knockout"`
+	CreatedAt     time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// Relations
+	Application TaskApplication       `gorm:"foreignkey:ApplicationID" json:"application,omitempty"`
+	Question    TaskScreeningQuestion `gorm:"foreignkey:QuestionID" json:"question,omitempty"`
+}
+
+// ChoiceList returns the options chosen in a choice answer
+func (a *ScreeningAnswer) ChoiceList() []string {
+	return jsonStrings(a.Choices)
+}
+
+// jsonStrings decodes a JSON list of strings, returning an empty list when it is unset
+func jsonStrings(data datatypes.JSON) []string {
+	values := make([]string, 0)
+	if len(data) > 0 {
+		_ = json.Unmarshal(data, &values)
+	}
+	return values
+}
@@ -4,13 +4,40 @@ import (
 	"time"
 )
 
-// Skill represents the skills table
+// Skill represents the skills table. Skills hang off a category and may have a broader
+// parent skill, e.g. "Gin" under "Go". NormalizedName is the case- and width-folded name
+// used to tell whether two spellings are the same skill.
 type Skill struct {
+	ID             uint      `gorm:"primary_key" json:"id"`
+	Name           string    `gorm:"type:varchar(100);unique_index;not null" json:"name"`
+	NormalizedName string    `gorm:"type:varchar(100);index;not null;default:''" json:"-"`
+	CategoryID     *uint     `gorm:"index" json:"category_id"`
+	ParentID       *uint     `gorm:"index" json:"parent_id"`
+	CreatedAt      time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// Relations
+	Category *SkillCategory `gorm:"foreignkey:CategoryID" json:"category,omitempty"`
+	Parent   *Skill         `gorm:"foreignkey:ParentID" json:"parent,omitempty"`
+	Synonyms []SkillSynonym `gorm:"foreignkey:SkillID" json:"synonyms,omitempty"`
+	Users    []User         `gorm:"many2many:user_skills;" json:"users,omitempty"`
+	Tasks    []Task         `gorm:"many2many:task_skills;" json:"tasks,omitempty"`
+}
+
+// SkillCategory represents the skill_categories table, a tree grouping related skills
+type SkillCategory struct {
 	ID        uint      `gorm:"primary_key" json:"id"`
-	Name      string    `gorm:"type:varchar(100);unique_index;not null" json:"name"`
+	Name      string    `gorm:"type:varchar(100);uniqueIndex;not null" json:"name"`
+	ParentID  *uint     `gorm:"index" json:"parent_id"`
+	SortOrder int       `gorm:"not null;default:0" json:"sort_order"`
 	CreatedAt time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+}
 
-	// Relations
-	Users []User `gorm:"many2many:user_skills;" json:"users,omitempty"`
-	Tasks []Task `gorm:"many2many:task_skills;" json:"tasks,omitempty"`
+// SkillSynonym represents the skill_synonyms table, mapping an alternative spelling to its
+// canonical skill so "golang" and "GO语言" both resolve to "Go"
+type SkillSynonym struct {
+	ID             uint      `gorm:"primary_key" json:"id"`
+	SkillID        uint      `gorm:"index;not null" json:"skill_id"`
+	Name           string    `gorm:"type:varchar(100);not null" json:"name"`
+	NormalizedName string    `gorm:"type:varchar(100);uniqueIndex;not null" json:"-"`
+	CreatedAt      time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
 }
@@ -2,9 +2,11 @@ package models
 
 import (
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -32,6 +34,7 @@ type TaskStatus string
 
 // Enum values for TaskStatus
 const (
+	TaskStatusDraft           TaskStatus = "draft"
 	TaskStatusPendingApproval TaskStatus = "pending_approval"
 	TaskStatusRecruiting      TaskStatus = "recruiting"
 	TaskStatusInProgress      TaskStatus = "in_progress"
@@ -41,36 +44,52 @@ const (
 	TaskStatusRejected        TaskStatus = "rejected"
 )
 
-// Task represents the tasks table
+// Task represents the tasks table. A draft saved before all required fields were filled
+// in keeps its request in DraftData, which is validated and applied to the task when the
+// draft is published. ScheduledPublishAt is when a draft is published automatically.
+// Recruiting stops at RecruitingDeadline, or at the end date when there is none. On tasks
+// with BiddingEnabled applicants quote their own amount and BudgetAmount is only a guide.
 type Task struct {
-	ID              uint         `gorm:"primary_key" json:"id"`
-	UUID            string       `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
-	EmployerID      uint         `gorm:"index;not null" json:"employer_id"`
-	Title           string       `gorm:"type:varchar(255);not null" json:"title"`
-	Description     string       `gorm:"type:text;not null" json:"description"`
-	LocationType    LocationType `gorm:"type:enum('online','offline');not null" json:"location_type"`
-	LocationDetails *string      `gorm:"type:varchar(255)" json:"location_details"`
-	StartDate       time.Time    `gorm:"type:date;not null;index" json:"start_date"`
-	EndDate         time.Time    `gorm:"type:date;not null;index" json:"end_date"`
-	PaymentType     PaymentType  `gorm:"type:enum('hourly','daily','fixed');not null" json:"payment_type"`
-	BudgetAmount    float64      `gorm:"type:decimal(12,2);not null" json:"budget_amount"`
-	Currency        string       `gorm:"type:varchar(3);not null;default:'CNY'" json:"currency"`
-	Headcount       uint         `gorm:"type:int unsigned;not null;default:1" json:"headcount"`
-	EstimatedHours  float64      `gorm:"type:decimal(8,2);default:0" json:"estimated_hours"`
-	Status          TaskStatus   `gorm:"type:enum('pending_approval','recruiting','in_progress','payment_pending','completed','closed','rejected');not null;default:'pending_approval';index" json:"status"`
-	IsPublic        bool         `gorm:"not null;default:true;index" json:"is_public"`
-	IsUrgent        bool         `gorm:"not null;default:false;index" json:"is_urgent"`
-	PublishedAt     *time.Time   `gorm:"index" json:"published_at"`
-	CreatedAt       time.Time    `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"created_at"`
-	UpdatedAt       time.Time    `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
-	DeletedAt       *time.Time   `gorm:"index" json:"-"`
+	ID                 uint           `gorm:"primary_key" json:"id"`
+	UUID               string         `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
+	EmployerID         uint           `gorm:"index;not null" json:"employer_id"`
+	Title              string         `gorm:"type:varchar(255);not null" json:"title"`
+	Description        string         `gorm:"type:text;not null" json:"description"`
+	LocationType       LocationType   `gorm:"type:enum('online','offline');not null" json:"location_type"`
+	LocationDetails    *string        `gorm:"type:varchar(255)" json:"location_details"`
+	Latitude           *float64       `gorm:"type:decimal(10,7);index:idx_tasks_geo" json:"latitude"`
+	Longitude          *float64       `gorm:"type:decimal(10,7);index:idx_tasks_geo" json:"longitude"`
+	GeoApproximate     bool           `gorm:"not null;default:false" json:"geo_approximate"`
+	CheckInRadius      uint           `gorm:"type:int unsigned;not null;default:0" json:"check_in_radius"`
+	StartDate          time.Time      `gorm:"type:date;not null;index" json:"start_date"`
+	EndDate            time.Time      `gorm:"type:date;not null;index" json:"end_date"`
+	PaymentType        PaymentType    `gorm:"type:enum('hourly','daily','fixed');not null" json:"payment_type"`
+	BudgetAmount       float64        `gorm:"type:decimal(12,2);not null" json:"budget_amount"`
+	Currency           string         `gorm:"type:varchar(3);not null;default:'CNY'" json:"currency"`
+	Headcount          uint           `gorm:"type:int unsigned;not null;default:1" json:"headcount"`
+	EstimatedHours     float64        `gorm:"type:decimal(8,2);default:0" json:"estimated_hours"`
+	BudgetCeiling      *float64       `gorm:"type:decimal(12,2)" json:"budget_ceiling"`
+	Status             TaskStatus     `gorm:"type:enum('draft','pending_approval','recruiting','in_progress','payment_pending','completed','closed','rejected');not null;default:'pending_approval';index" json:"status"`
+	IsPublic           bool           `gorm:"not null;default:true;index" json:"is_public"`
+	IsUrgent           bool           `gorm:"not null;default:false;index" json:"is_urgent"`
+	BiddingEnabled     bool           `gorm:"not null;default:false" json:"bidding_enabled"`
+	RecurrenceID       *uint          `gorm:"index" json:"recurrence_id"`
+	PublishedAt        *time.Time     `gorm:"index" json:"published_at"`
+	ScheduledPublishAt *time.Time     `gorm:"index" json:"scheduled_publish_at"`
+	RecruitingDeadline *time.Time     `gorm:"index" json:"recruiting_deadline"`
+	DraftData          datatypes.JSON `gorm:"type:json" json:"-"`
+	CreatedAt          time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"created_at"`
+	UpdatedAt          time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+	DeletedAt          *time.Time     `gorm:"index" json:"-"`
 
 	// Relations
-	Employer     User              `gorm:"foreignkey:EmployerID" json:"employer,omitempty"`
-	Skills       []Skill           `gorm:"many2many:task_skills;" json:"skills,omitempty"`
-	Applications []TaskApplication `gorm:"foreignkey:TaskID" json:"applications,omitempty"`
-	Assignments  []TaskAssignment  `gorm:"foreignkey:TaskID" json:"assignments,omitempty"`
-	FavoritedBy  []User            `gorm:"many2many:user_favorites;" json:"favorited_by,omitempty"`
+	Employer           User                    `gorm:"foreignkey:EmployerID" json:"employer,omitempty"`
+	Skills             []Skill                 `gorm:"many2many:task_skills;" json:"skills,omitempty"`
+	Applications       []TaskApplication       `gorm:"foreignkey:TaskID" json:"applications,omitempty"`
+	Assignments        []TaskAssignment        `gorm:"foreignkey:TaskID" json:"assignments,omitempty"`
+	FavoritedBy        []User                  `gorm:"many2many:user_favorites;" json:"favorited_by,omitempty"`
+	Milestones         []TaskMilestone         `gorm:"foreignkey:TaskID" json:"milestones,omitempty"`
+	ScreeningQuestions []TaskScreeningQuestion `gorm:"foreignkey:TaskID" json:"screening_questions,omitempty"`
 }
 
 // TaskSkill represents the task_skills pivot table
@@ -114,3 +133,29 @@ func (t *Task) BudgetDisplay() string {
 		return fmt.Sprintf("%.2f%s/项目", t.BudgetAmount, t.Currency)
 	}
 }
+
+// DefaultContract returns what a worker hired on the task is paid unless something else
+// is agreed: an equal share of the budget on fixed-price tasks, or the budget as the rate,
+// capped by the budget ceiling, on hourly and daily tasks
+func (t *Task) DefaultContract() (amount *float64, rate *float64) {
+	if t.PaymentType == PaymentTypeFixed {
+		headcount := t.Headcount
+		if headcount == 0 {
+			headcount = 1
+		}
+		share := math.Round(t.BudgetAmount/float64(headcount)*100) / 100
+		return &share, nil
+	}
+	budget := t.BudgetAmount
+	if t.BudgetCeiling != nil {
+		ceiling := *t.BudgetCeiling
+		amount = &ceiling
+	}
+	return amount, &budget
+}
+
+// IsGeofenced reports whether workers must check in on site with their device location.
+// Coordinates geocoded from the address text are only precise enough for search.
+func (t *Task) IsGeofenced() bool {
+	return t.LocationType == LocationTypeOffline && t.Latitude != nil && t.Longitude != nil && !t.GeoApproximate
+}
@@ -18,7 +18,10 @@ const (
 	ApplicationStatusWithdrawn ApplicationStatus = "withdrawn"
 )
 
-// TaskApplication represents the task_applications table
+// TaskApplication represents the task_applications table. KnockedOut marks an application
+// rejected automatically because of a knockout screening answer. On bidding tasks BidAmount
+// is the applicant's quote, in the task's payment unit, and BidDays the proposed number of
+// days to finish.
 type TaskApplication struct {
 	ID          uint              `gorm:"primary_key" json:"id"`
 	UUID        string            `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
@@ -26,12 +29,16 @@ type TaskApplication struct {
 	WorkerID    uint              `gorm:"index;not null" json:"worker_id"`
 	Status      ApplicationStatus `gorm:"type:enum('pending','accepted','rejected','withdrawn');not null;default:'pending';index" json:"status"`
 	CoverLetter *string           `gorm:"type:text" json:"cover_letter"`
+	KnockedOut  bool              `gorm:"not null;default:false" json:"knocked_out"`
+	BidAmount   *float64          `gorm:"type:decimal(12,2)" json:"bid_amount"`
+	BidDays     *uint             `gorm:"type:int unsigned" json:"bid_days"`
 	AppliedAt   time.Time         `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"applied_at"`
 	UpdatedAt   time.Time         `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
 
 	// Relations
-	Task   Task `gorm:"foreignkey:TaskID" json:"task,omitempty"`
-	Worker User `gorm:"foreignkey:WorkerID" json:"worker,omitempty"`
+	Task             Task              `gorm:"foreignkey:TaskID" json:"task,omitempty"`
+	Worker           User              `gorm:"foreignkey:WorkerID" json:"worker,omitempty"`
+	ScreeningAnswers []ScreeningAnswer `gorm:"foreignkey:ApplicationID" json:"screening_answers,omitempty"`
 }
 
 // TableName specifies the table name and adds a composite index
@@ -30,7 +30,10 @@ const (
 	EmployerStatusDisputed       EmployerStatus = "disputed"
 )
 
-// TaskAssignment represents the task_assignments table
+// TaskAssignment represents the task_assignments table. The contract is fixed when the
+// worker is hired so that the payout does not depend on who else works on the task:
+// ContractedAmount is the total pay on fixed-price tasks and the optional payout cap on
+// hourly and daily tasks, ContractedRate the hourly or daily rate.
 type TaskAssignment struct {
 	ID                uint           `gorm:"primary_key" json:"id"`
 	UUID              string         `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
@@ -41,6 +44,11 @@ type TaskAssignment struct {
 	WorkerStatus      WorkerStatus   `gorm:"type:enum('working','submitted','completed','quit');not null;default:'working';index" json:"worker_status"`
 	EmployerStatus    EmployerStatus `gorm:"type:enum('in_progress','review_pending','payment_pending','completed','disputed');not null;default:'in_progress';index" json:"employer_status"`
 	CompletedAt       *time.Time     `gorm:"index" json:"completed_at"`
+	QuitReason        *string        `gorm:"type:text" json:"quit_reason"`
+	QuitProgress      uint8          `gorm:"type:tinyint unsigned;not null;default:0" json:"quit_progress"`
+	QuitAt            *time.Time     `json:"quit_at"`
+	ContractedAmount  *float64       `gorm:"type:decimal(12,2)" json:"contracted_amount"`
+	ContractedRate    *float64       `gorm:"type:decimal(12,2)" json:"contracted_rate"`
 	UpdatedAt         time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
 
 	// Relations
@@ -57,6 +65,12 @@ func (ta *TaskAssignment) SubmitWork() {
 	ta.EmployerStatus = EmployerStatusReviewPending
 }
 
+// ResumeWork sends a submitted assignment back to the worker after the employer requested changes
+func (ta *TaskAssignment) ResumeWork() {
+	ta.WorkerStatus = WorkerStatusWorking
+	ta.EmployerStatus = EmployerStatusInProgress
+}
+
 // ApproveWork changes the employer status to payment pending
 func (ta *TaskAssignment) ApproveWork() {
 	ta.EmployerStatus = EmployerStatusPaymentPending
@@ -75,9 +89,26 @@ func (ta *TaskAssignment) ReportDispute() {
 	ta.EmployerStatus = EmployerStatusDisputed
 }
 
-// QuitTask changes the worker status to quit
-func (ta *TaskAssignment) QuitTask() {
+// QuitTask changes the worker status to quit and records why and how much work was done.
+// If the worker reports no progress there is nothing left to settle, otherwise the
+// assignment waits for the employer to settle the prorated payment.
+func (ta *TaskAssignment) QuitTask(reason string, progress uint8) {
+	now := time.Now()
 	ta.WorkerStatus = WorkerStatusQuit
+	ta.QuitReason = &reason
+	ta.QuitProgress = progress
+	ta.QuitAt = &now
+	if progress == 0 {
+		ta.EmployerStatus = EmployerStatusCompleted
+		ta.CompletedAt = &now
+	} else {
+		ta.EmployerStatus = EmployerStatusPaymentPending
+	}
+}
+
+// IsActive reports whether the worker still occupies a slot on the task
+func (ta *TaskAssignment) IsActive() bool {
+	return ta.WorkerStatus == WorkerStatusWorking || ta.WorkerStatus == WorkerStatusSubmitted
 }
 
 // BeforeCreate is a GORM hook that runs before creating an assignment record
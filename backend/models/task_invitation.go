@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InvitationStatus represents the status of a task invitation
+type InvitationStatus string
+
+// Enum values for InvitationStatus
+const (
+	InvitationStatusPending  InvitationStatus = "pending"
+	InvitationStatusAccepted InvitationStatus = "accepted"
+	InvitationStatusDeclined InvitationStatus = "declined"
+	InvitationStatusExpired  InvitationStatus = "expired"
+)
+
+// TaskInvitation represents the task_invitations table. An employer invites a worker to a
+// task; accepting either files an application for the employer to review or, for a direct
+// hire, assigns the worker straight away.
+type TaskInvitation struct {
+	ID                uint             `gorm:"primary_key" json:"id"`
+	UUID              string           `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
+	TaskID            uint             `gorm:"index:idx_task_invitations_task_worker;not null" json:"task_id"`
+	EmployerID        uint             `gorm:"index;not null" json:"employer_id"`
+	WorkerID          uint             `gorm:"index:idx_task_invitations_task_worker;index;not null" json:"worker_id"`
+	Message           *string          `gorm:"type:text" json:"message"`
+	DirectHire        bool             `gorm:"not null;default:false" json:"direct_hire"`
+	Status            InvitationStatus `gorm:"type:enum('pending','accepted','declined','expired');not null;default:'pending';index" json:"status"`
+	DeclineReason     *string          `gorm:"type:varchar(255)" json:"decline_reason"`
+	TaskApplicationID *uint            `gorm:"index" json:"task_application_id"`
+	ExpiresAt         time.Time        `gorm:"not null;index" json:"expires_at"`
+	RespondedAt       *time.Time       `json:"responded_at"`
+	CreatedAt         time.Time        `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"created_at"`
+	UpdatedAt         time.Time        `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Relations
+	Task     Task `gorm:"foreignkey:TaskID" json:"task,omitempty"`
+	Employer User `gorm:"foreignkey:EmployerID" json:"employer,omitempty"`
+	Worker   User `gorm:"foreignkey:WorkerID" json:"worker,omitempty"`
+}
+
+// IsExpired reports whether a pending invitation has passed its expiry time
+func (ti *TaskInvitation) IsExpired(now time.Time) bool {
+	return ti.Status == InvitationStatusPending && now.After(ti.ExpiresAt)
+}
+
+// Accept marks the invitation as accepted and links the resulting application
+func (ti *TaskInvitation) Accept(applicationID uint) {
+	now := time.Now()
+	ti.Status = InvitationStatusAccepted
+	ti.TaskApplicationID = &applicationID
+	ti.RespondedAt = &now
+}
+
+// Decline marks the invitation as declined by the worker
+func (ti *TaskInvitation) Decline(reason string) {
+	now := time.Now()
+	ti.Status = InvitationStatusDeclined
+	ti.RespondedAt = &now
+	if reason != "" {
+		ti.DeclineReason = &reason
+	}
+}
+
+// BeforeCreate is a GORM hook that runs before creating an invitation record
+func (ti *TaskInvitation) BeforeCreate(tx *gorm.DB) (err error) {
+	// Generate UUID if not set
+	if ti.UUID == "" {
+		ti.UUID = uuid.New().String()
+	}
+	return nil
+}
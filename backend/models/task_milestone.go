@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MilestoneStatus represents the status of a task milestone
+type MilestoneStatus string
+
+// Enum values for MilestoneStatus
+const (
+	MilestoneStatusPending   MilestoneStatus = "pending"
+	MilestoneStatusSubmitted MilestoneStatus = "submitted"
+	MilestoneStatusPaid      MilestoneStatus = "paid"
+)
+
+// TaskMilestone represents the task_milestones table. Fixed-price tasks can be split into
+// milestones that are submitted, approved and paid one by one.
+type TaskMilestone struct {
+	ID               uint            `gorm:"primary_key" json:"id"`
+	UUID             string          `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
+	TaskID           uint            `gorm:"index;not null" json:"task_id"`
+	TaskAssignmentID *uint           `gorm:"index" json:"task_assignment_id"`
+	Title            string          `gorm:"type:varchar(255);not null" json:"title"`
+	Description      *string         `gorm:"type:text" json:"description"`
+	Amount           float64         `gorm:"type:decimal(12,2);not null" json:"amount"`
+	DueDate          *time.Time      `gorm:"type:date" json:"due_date"`
+	SortOrder        uint            `gorm:"not null;default:0" json:"sort_order"`
+	Status           MilestoneStatus `gorm:"type:enum('pending','submitted','paid');not null;default:'pending';index" json:"status"`
+	SubmissionNote   *string         `gorm:"type:text" json:"submission_note"`
+	FeedbackNote     *string         `gorm:"type:text" json:"feedback_note"`
+	SubmittedAt      *time.Time      `json:"submitted_at"`
+	PaidAt           *time.Time      `json:"paid_at"`
+	CreatedAt        time.Time       `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt        time.Time       `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Relations
+	Task           Task            `gorm:"foreignkey:TaskID" json:"task,omitempty"`
+	TaskAssignment *TaskAssignment `gorm:"foreignkey:TaskAssignmentID" json:"task_assignment,omitempty"`
+}
+
+// Submit marks the milestone as delivered by the given assignment
+func (m *TaskMilestone) Submit(assignmentID uint, note string) {
+	now := time.Now()
+	m.Status = MilestoneStatusSubmitted
+	m.TaskAssignmentID = &assignmentID
+	m.SubmittedAt = &now
+	if note != "" {
+		m.SubmissionNote = &note
+	} else {
+		m.SubmissionNote = nil
+	}
+	m.FeedbackNote = nil
+}
+
+// Reject sends a submitted milestone back to the worker
+func (m *TaskMilestone) Reject(feedback string) {
+	m.Status = MilestoneStatusPending
+	m.SubmittedAt = nil
+	if feedback != "" {
+		m.FeedbackNote = &feedback
+	}
+}
+
+// MarkAsPaid marks the milestone as approved and paid
+func (m *TaskMilestone) MarkAsPaid() {
+	now := time.Now()
+	m.Status = MilestoneStatusPaid
+	m.PaidAt = &now
+}
+
+// BeforeCreate is a GORM hook that runs before creating a milestone record
+func (m *TaskMilestone) BeforeCreate(tx *gorm.DB) (err error) {
+	// Generate UUID if not set
+	if m.UUID == "" {
+		m.UUID = uuid.New().String()
+	}
+	if m.Status == "" {
+		m.Status = MilestoneStatusPending
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TaskQuestionStatus represents the moderation status of a task question
+type TaskQuestionStatus string
+
+// Enum values for TaskQuestionStatus
+const (
+	TaskQuestionStatusVisible TaskQuestionStatus = "visible"
+	TaskQuestionStatusPending TaskQuestionStatus = "pending"
+	TaskQuestionStatusHidden  TaskQuestionStatus = "hidden"
+)
+
+// TaskQuestion represents the task_questions table, a public question about a task and the
+// employer's answer. Questions held back by moderation or reported by enough users are
+// pending until an admin reviews them; hidden questions were taken down.
+type TaskQuestion struct {
+	ID             uint               `gorm:"primary_key" json:"id"`
+	UUID           string             `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
+	TaskID         uint               `gorm:"index;not null" json:"task_id"`
+	AskerID        uint               `gorm:"index;not null" json:"asker_id"`
+	Question       string             `gorm:"type:text;not null" json:"question"`
+	Answer         *string            `gorm:"type:text" json:"answer"`
+	AnsweredAt     *time.Time         `json:"answered_at"`
+	Status         TaskQuestionStatus `gorm:"type:enum('visible','pending','hidden');not null;default:'visible';index" json:"status"`
+	ModerationNote *string            `gorm:"type:varchar(255)" json:"moderation_note"`
+	ReportCount    uint               `gorm:"not null;default:0" json:"report_count"`
+	CreatedAt      time.Time          `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"created_at"`
+	UpdatedAt      time.Time          `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Relations
+	Task  Task `gorm:"foreignkey:TaskID" json:"task,omitempty"`
+	Asker User `gorm:"foreignkey:AskerID" json:"asker,omitempty"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a task question record
+func (q *TaskQuestion) BeforeCreate(tx *gorm.DB) (err error) {
+	// Generate UUID if not set
+	if q.UUID == "" {
+		q.UUID = uuid.New().String()
+	}
+	return nil
+}
+
+// IsAnswered reports whether the employer has answered the question
+func (q *TaskQuestion) IsAnswered() bool {
+	return q.Answer != nil
+}
+
+// TaskQuestionReport represents the task_question_reports table. Each user can report a
+// question once.
+type TaskQuestionReport struct {
+	ID         uint      `gorm:"primary_key" json:"id"`
+	QuestionID uint      `gorm:"uniqueIndex:idx_task_question_report;not null" json:"question_id"`
+	ReporterID uint      `gorm:"uniqueIndex:idx_task_question_report;index;not null" json:"reporter_id"`
+	Reason     string    `gorm:"type:varchar(255);not null" json:"reason"`
+	CreatedAt  time.Time `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// Relations
+	Question TaskQuestion `gorm:"foreignkey:QuestionID" json:"question,omitempty"`
+	Reporter User         `gorm:"foreignkey:ReporterID" json:"reporter,omitempty"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// TaskRecommendation represents the task_recommendations table, a precomputed feed of
+// open tasks ranked for each worker
+type TaskRecommendation struct {
+	ID         uint           `gorm:"primary_key" json:"id"`
+	WorkerID   uint           `gorm:"uniqueIndex:idx_recommendation_worker_task;index:idx_recommendation_worker_score,priority:1;not null" json:"worker_id"`
+	TaskID     uint           `gorm:"uniqueIndex:idx_recommendation_worker_task;not null" json:"task_id"`
+	Score      float64        `gorm:"type:decimal(6,2);index:idx_recommendation_worker_score,priority:2;not null" json:"score"`
+	Reasons    datatypes.JSON `gorm:"type:json" json:"reasons"`
+	ComputedAt time.Time      `gorm:"not null" json:"computed_at"`
+
+	// Relations
+	Worker User `gorm:"foreignkey:WorkerID" json:"worker,omitempty"`
+	Task   Task `gorm:"foreignkey:TaskID" json:"task,omitempty"`
+}
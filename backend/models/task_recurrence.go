@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecurrenceStatus represents the status of a recurring task series
+type RecurrenceStatus string
+
+// Enum values for RecurrenceStatus
+const (
+	RecurrenceStatusActive RecurrenceStatus = "active"
+	RecurrenceStatusPaused RecurrenceStatus = "paused"
+	RecurrenceStatusEnded  RecurrenceStatus = "ended"
+)
+
+// TaskRecurrence represents the task_recurrences table, a series of tasks posted on a
+// schedule. Each occurrence is copied from either a template or a source task and is
+// created LeadDays ahead of its start date. Tasks belonging to the series point back to
+// it through Task.RecurrenceID.
+type TaskRecurrence struct {
+	ID                 uint             `gorm:"primary_key" json:"id"`
+	UUID               string           `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
+	EmployerID         uint             `gorm:"index;not null" json:"employer_id"`
+	TemplateID         *uint            `gorm:"index" json:"template_id"`
+	SourceTaskID       *uint            `gorm:"index" json:"source_task_id"`
+	RRule              string           `gorm:"type:varchar(255);not null" json:"rrule"`
+	StartDate          time.Time        `gorm:"type:date;not null" json:"start_date"`
+	DurationDays       int              `gorm:"not null;default:0" json:"duration_days"`
+	LeadDays           int              `gorm:"not null;default:7" json:"lead_days"`
+	MaxOccurrences     int              `gorm:"not null;default:0" json:"max_occurrences"`
+	OccurrencesCreated int              `gorm:"not null;default:0" json:"occurrences_created"`
+	LastOccurrenceDate *time.Time       `gorm:"type:date" json:"last_occurrence_date"`
+	AutoInvite         bool             `gorm:"not null;default:false" json:"auto_invite"`
+	Status             RecurrenceStatus `gorm:"type:enum('active','paused','ended');not null;default:'active';index" json:"status"`
+	CreatedAt          time.Time        `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt          time.Time        `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Relations
+	Employer   User          `gorm:"foreignkey:EmployerID" json:"employer,omitempty"`
+	Template   *TaskTemplate `gorm:"foreignkey:TemplateID" json:"template,omitempty"`
+	SourceTask *Task         `gorm:"foreignkey:SourceTaskID" json:"source_task,omitempty"`
+}
+
+// ReachedLimit reports whether the series has created as many tasks as it may
+func (r *TaskRecurrence) ReachedLimit() bool {
+	return r.MaxOccurrences > 0 && r.OccurrencesCreated >= r.MaxOccurrences
+}
+
+// BeforeCreate is a GORM hook that runs before creating a recurrence record
+func (r *TaskRecurrence) BeforeCreate(tx *gorm.DB) (err error) {
+	// Generate UUID if not set
+	if r.UUID == "" {
+		r.UUID = uuid.New().String()
+	}
+	return nil
+}
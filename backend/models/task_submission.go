@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// SubmissionStatus represents the review status of a deliverable submission
+type SubmissionStatus string
+
+// Enum values for SubmissionStatus
+const (
+	SubmissionStatusSubmitted        SubmissionStatus = "submitted"
+	SubmissionStatusChangesRequested SubmissionStatus = "changes_requested"
+	SubmissionStatusAccepted         SubmissionStatus = "accepted"
+)
+
+// SubmissionAttachment is a single uploaded file or external link on a submission
+type SubmissionAttachment struct {
+	Kind string `json:"kind"` // "file" or "link"
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// TaskSubmission represents the task_submissions table. Every time a worker completes a
+// task a new version is stored, so re-submissions after requested changes keep history.
+type TaskSubmission struct {
+	ID               uint             `gorm:"primary_key" json:"id"`
+	UUID             string           `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
+	TaskID           uint             `gorm:"index;not null" json:"task_id"`
+	TaskAssignmentID uint             `gorm:"index;not null" json:"task_assignment_id"`
+	Version          uint             `gorm:"not null;default:1" json:"version"`
+	Notes            *string          `gorm:"type:text" json:"notes"`
+	Attachments      datatypes.JSON   `gorm:"type:json" json:"attachments"`
+	Status           SubmissionStatus `gorm:"type:enum('submitted','changes_requested','accepted');not null;default:'submitted';index" json:"status"`
+	Feedback         *string          `gorm:"type:text" json:"feedback"`
+	SubmittedAt      time.Time        `gorm:"not null;default:CURRENT_TIMESTAMP" json:"submitted_at"`
+	ReviewedAt       *time.Time       `json:"reviewed_at"`
+
+	// Relations
+	Task           Task           `gorm:"foreignkey:TaskID" json:"task,omitempty"`
+	TaskAssignment TaskAssignment `gorm:"foreignkey:TaskAssignmentID" json:"task_assignment,omitempty"`
+}
+
+// RequestChanges marks the submission as needing rework
+func (s *TaskSubmission) RequestChanges(feedback string) {
+	now := time.Now()
+	s.Status = SubmissionStatusChangesRequested
+	s.Feedback = &feedback
+	s.ReviewedAt = &now
+}
+
+// Accept marks the submission as accepted by the employer
+func (s *TaskSubmission) Accept() {
+	now := time.Now()
+	s.Status = SubmissionStatusAccepted
+	s.ReviewedAt = &now
+}
+
+// BeforeCreate is a GORM hook that runs before creating a submission record
+func (s *TaskSubmission) BeforeCreate(tx *gorm.DB) (err error) {
+	// Generate UUID if not set
+	if s.UUID == "" {
+		s.UUID = uuid.New().String()
+	}
+	if s.Status == "" {
+		s.Status = SubmissionStatusSubmitted
+	}
+	if len(s.Attachments) == 0 {
+		s.Attachments = datatypes.JSON([]byte("[]"))
+	}
+	return nil
+}
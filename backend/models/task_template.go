@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// TemplateMilestone is a milestone stored on a task template. Its due date is kept as an
+// offset from the task's start date because templates have no dates of their own.
+type TemplateMilestone struct {
+	Title         string  `json:"title"`
+	Description   string  `json:"description"`
+	Amount        float64 `json:"amount"`
+	DueOffsetDays *int    `json:"due_offset_days"`
+}
+
+// TaskTemplate represents the task_templates table, an employer's reusable task with
+// everything needed to post it again except the dates. DurationDays is the number of days
+// between the start and end dates of tasks created from it.
+type TaskTemplate struct {
+	ID              uint           `gorm:"primary_key" json:"id"`
+	UUID            string         `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
+	EmployerID      uint           `gorm:"index;not null" json:"employer_id"`
+	Name            string         `gorm:"type:varchar(100);not null" json:"name"`
+	Title           string         `gorm:"type:varchar(255);not null" json:"title"`
+	Description     string         `gorm:"type:text;not null" json:"description"`
+	LocationType    LocationType   `gorm:"type:enum('online','offline');not null" json:"location_type"`
+	LocationDetails *string        `gorm:"type:varchar(255)" json:"location_details"`
+	Latitude        *float64       `gorm:"type:decimal(10,7)" json:"latitude"`
+	Longitude       *float64       `gorm:"type:decimal(10,7)" json:"longitude"`
+	CheckInRadius   uint           `gorm:"type:int unsigned;not null;default:0" json:"check_in_radius"`
+	PaymentType     PaymentType    `gorm:"type:enum('hourly','daily','fixed');not null" json:"payment_type"`
+	BudgetAmount    float64        `gorm:"type:decimal(12,2);not null" json:"budget_amount"`
+	EstimatedHours  float64        `gorm:"type:decimal(8,2);default:0" json:"estimated_hours"`
+	Headcount       uint           `gorm:"type:int unsigned;not null;default:1" json:"headcount"`
+	BudgetCeiling   *float64       `gorm:"type:decimal(12,2)" json:"budget_ceiling"`
+	IsPublic        bool           `gorm:"not null;default:true" json:"is_public"`
+	IsUrgent        bool           `gorm:"not null;default:false" json:"is_urgent"`
+	DurationDays    int            `gorm:"not null;default:0" json:"duration_days"`
+	Milestones      datatypes.JSON `gorm:"type:json" json:"milestones"`
+	UsageCount      uint           `gorm:"not null;default:0" json:"usage_count"`
+	LastUsedAt      *time.Time     `json:"last_used_at"`
+	CreatedAt       time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt       time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Relations
+	Employer User    `gorm:"foreignkey:EmployerID" json:"employer,omitempty"`
+	Skills   []Skill `gorm:"many2many:task_template_skills;" json:"skills,omitempty"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a task template record
+func (t *TaskTemplate) BeforeCreate(tx *gorm.DB) (err error) {
+	// Generate UUID if not set
+	if t.UUID == "" {
+		t.UUID = uuid.New().String()
+	}
+	return nil
+}
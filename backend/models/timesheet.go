@@ -0,0 +1,100 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TimesheetSource represents how a timesheet entry was recorded
+type TimesheetSource string
+
+// Enum values for TimesheetSource
+const (
+	TimesheetSourceClock  TimesheetSource = "clock"
+	TimesheetSourceManual TimesheetSource = "manual"
+	// TimesheetSourceAttendance entries are opened by a geofenced check-in
+	TimesheetSourceAttendance TimesheetSource = "attendance"
+)
+
+// TimesheetStatus represents the approval status of a timesheet entry
+type TimesheetStatus string
+
+// Enum values for TimesheetStatus
+const (
+	TimesheetStatusOpen     TimesheetStatus = "open"
+	TimesheetStatusPending  TimesheetStatus = "pending"
+	TimesheetStatusApproved TimesheetStatus = "approved"
+	TimesheetStatusRejected TimesheetStatus = "rejected"
+)
+
+// TimesheetEntry represents the timesheet_entries table. Hourly and daily tasks are paid
+// from the approved entries of each assignment.
+type TimesheetEntry struct {
+	ID               uint            `gorm:"primary_key" json:"id"`
+	UUID             string          `gorm:"type:varchar(36);uniqueIndex;not null" json:"uuid"`
+	TaskID           uint            `gorm:"index;not null" json:"task_id"`
+	TaskAssignmentID uint            `gorm:"index;not null" json:"task_assignment_id"`
+	WorkerID         uint            `gorm:"index;not null" json:"worker_id"`
+	Source           TimesheetSource `gorm:"type:enum('clock','manual','attendance');not null;default:'manual'" json:"source"`
+	StartedAt        time.Time       `gorm:"not null;index" json:"started_at"`
+	EndedAt          *time.Time      `json:"ended_at"`
+	Hours            float64         `gorm:"type:decimal(8,2);not null;default:0" json:"hours"`
+	Description      *string         `gorm:"type:text" json:"description"`
+	Status           TimesheetStatus `gorm:"type:enum('open','pending','approved','rejected');not null;default:'pending';index" json:"status"`
+	ReviewNote       *string         `gorm:"type:text" json:"review_note"`
+	ReviewedAt       *time.Time      `json:"reviewed_at"`
+	CreatedAt        time.Time       `gorm:"not null;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt        time.Time       `gorm:"not null;default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Relations
+	Task           Task           `gorm:"foreignkey:TaskID" json:"task,omitempty"`
+	TaskAssignment TaskAssignment `gorm:"foreignkey:TaskAssignmentID" json:"task_assignment,omitempty"`
+	Worker         User           `gorm:"foreignkey:WorkerID" json:"worker,omitempty"`
+}
+
+// ClockOut closes an open entry and moves it to pending approval
+func (te *TimesheetEntry) ClockOut(at time.Time) {
+	te.EndedAt = &at
+	te.Hours = hoursBetween(te.StartedAt, at)
+	te.Status = TimesheetStatusPending
+}
+
+// Approve marks the entry as approved by the employer
+func (te *TimesheetEntry) Approve(note string) {
+	now := time.Now()
+	te.Status = TimesheetStatusApproved
+	te.ReviewedAt = &now
+	if note != "" {
+		te.ReviewNote = &note
+	}
+}
+
+// Reject marks the entry as rejected by the employer
+func (te *TimesheetEntry) Reject(note string) {
+	now := time.Now()
+	te.Status = TimesheetStatusRejected
+	te.ReviewedAt = &now
+	if note != "" {
+		te.ReviewNote = &note
+	}
+}
+
+// BeforeCreate is a GORM hook that runs before creating a timesheet entry
+func (te *TimesheetEntry) BeforeCreate(tx *gorm.DB) (err error) {
+	// Generate UUID if not set
+	if te.UUID == "" {
+		te.UUID = uuid.New().String()
+	}
+	if te.EndedAt != nil && te.Hours == 0 {
+		te.Hours = hoursBetween(te.StartedAt, *te.EndedAt)
+	}
+	return nil
+}
+
+// hoursBetween returns the hours between two times rounded to two decimals
+func hoursBetween(start, end time.Time) float64 {
+	return math.Round(end.Sub(start).Hours()*100) / 100
+}
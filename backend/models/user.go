@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math"
 	"time"
 
 	"gorm.io/datatypes"
@@ -41,6 +42,8 @@ type User struct {
 	AvatarURL                *string                    `gorm:"type:varchar(512)" json:"avatar_url"`
 	Bio                      *string                    `gorm:"type:text" json:"bio"`
 	Location                 *string                    `gorm:"type:varchar(255)" json:"location"`
+	Latitude                 *float64                   `gorm:"type:decimal(10,7);index:idx_users_geo" json:"latitude"`
+	Longitude                *float64                   `gorm:"type:decimal(10,7);index:idx_users_geo" json:"longitude"`
 	HourlyRate               *float64                   `gorm:"type:decimal(10,2)" json:"hourly_rate"`
 	PhoneVerifiedAt          *time.Time                 `json:"phone_verified_at"`
 	EmailVerifiedAt          *time.Time                 `json:"email_verified_at"`
@@ -52,6 +55,9 @@ type User struct {
 	DeletedAt                gorm.DeletedAt             `gorm:"index" json:"-"`
 	RealName                 *string                    `gorm:"type:varchar(50)" json:"real_name,omitempty"`
 	IDCard                   *string                    `gorm:"type:varchar(18)" json:"id_card,omitempty"`
+	ReliabilityScore         int                        `gorm:"not null;default:100" json:"reliability_score"`
+	QuitCount                uint                       `gorm:"not null;default:0" json:"quit_count"`
+	PrivacySettings          PrivacySettings            `gorm:"embedded;embeddedPrefix:privacy_" json:"privacy_settings"`
 
 	// Relations
 	Skills        []Skill           `gorm:"many2many:user_skills;" json:"skills,omitempty"`
@@ -62,6 +68,65 @@ type User struct {
 	FavoriteTasks []Task            `gorm:"many2many:user_favorites;" json:"favorite_tasks,omitempty"`
 }
 
+// PrivacySettings controls what other users can see of a profile
+type PrivacySettings struct {
+	ProfileVisibility bool `gorm:"not null;default:true" json:"profile_visibility"`
+	ShowHourlyRate    bool `gorm:"not null;default:true" json:"show_hourly_rate"`
+	ShowContactInfo   bool `gorm:"not null;default:false" json:"show_contact_info"`
+	ShowLocation      bool `gorm:"not null;default:true" json:"show_location"`
+}
+
+// Apply updates the settings from a partial map as sent by the settings page, ignoring
+// unknown keys
+func (p *PrivacySettings) Apply(settings map[string]bool) {
+	if v, ok := settings["profile_visibility"]; ok {
+		p.ProfileVisibility = v
+	}
+	if v, ok := settings["show_hourly_rate"]; ok {
+		p.ShowHourlyRate = v
+	}
+	if v, ok := settings["show_contact_info"]; ok {
+		p.ShowContactInfo = v
+	}
+	if v, ok := settings["show_location"]; ok {
+		p.ShowLocation = v
+	}
+}
+
+// profileCoordinatePrecision is the number of decimals kept of profile coordinates, about
+// 1 km, so distances shown to other users cannot pinpoint where someone lives
+const profileCoordinatePrecision = 100
+
+// SetLocation stores profile coordinates rounded to about 1 km
+func (u *User) SetLocation(lat, lng float64) {
+	lat = math.Round(lat*profileCoordinatePrecision) / profileCoordinatePrecision
+	lng = math.Round(lng*profileCoordinatePrecision) / profileCoordinatePrecision
+	u.Latitude, u.Longitude = &lat, &lng
+}
+
+// DisplayName returns the name shown to other users, falling back to the username
+func (u *User) DisplayName() string {
+	if u.Name != nil && *u.Name != "" {
+		return *u.Name
+	}
+	if u.Username != nil && *u.Username != "" {
+		return *u.Username
+	}
+	return "用户"
+}
+
+// MinReliabilityScore is the floor a worker's reliability score can drop to
+const MinReliabilityScore = 0
+
+// ApplyQuitPenalty records a quit against the worker and lowers their reliability score
+func (u *User) ApplyQuitPenalty(points int) {
+	u.QuitCount++
+	u.ReliabilityScore -= points
+	if u.ReliabilityScore < MinReliabilityScore {
+		u.ReliabilityScore = MinReliabilityScore
+	}
+}
+
 // UserSkill represents the user_skills pivot table
 type UserSkill struct {
 	UserID  uint `gorm:"primaryKey" json:"user_id"`
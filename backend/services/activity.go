@@ -0,0 +1,61 @@
+// Package services holds business logic shared by the HTTP handlers and background jobs
+package services
+
+import (
+	"encoding/json"
+	"log"
+
+	"zhlg/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// RecordActivity writes an activity log entry so the target user can see what happened
+// to an entity they are involved in, adds it to their notification center and publishes
+// it as an event for real-time delivery. Inside a transaction started with BeginTx or
+// Transaction the event is published only after the commit. Failures are logged but never
+// abort the caller.
+func RecordActivity(tx *gorm.DB, actorID, targetUserID uint, entityType, entityUUID, action, description string, details map[string]interface{}) {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		log.Printf("[RecordActivity] 序列化活动详情失败: %v", err)
+		detailsJSON = []byte("{}")
+	}
+
+	activity := models.ActivityLog{
+		TargetUserID:     &targetUserID,
+		TargetEntityType: &entityType,
+		TargetEntityID:   &entityUUID,
+		ActionType:       action,
+		Description:      &description,
+		Details:          string(detailsJSON),
+	}
+	if actorID != 0 {
+		activity.UserID = &actorID
+	}
+	if err := tx.Create(&activity).Error; err != nil {
+		log.Printf("[RecordActivity] 记录活动失败: action=%s, target=%d, err=%v", action, targetUserID, err)
+		return
+	}
+
+	event := Event{
+		Type:       action,
+		UserID:     targetUserID,
+		ActorID:    actorID,
+		EntityType: entityType,
+		EntityUUID: entityUUID,
+		Message:    description,
+		Data:       details,
+		CreatedAt:  activity.CreatedAt,
+	}
+	notification, err := createNotification(tx, actorID, targetUserID, entityType, entityUUID, action, description, detailsJSON)
+	if err != nil {
+		log.Printf("[RecordActivity] 创建通知失败: action=%s, target=%d, err=%v", action, targetUserID, err)
+	} else if notification != nil {
+		event.NotificationUUID = notification.UUID
+	}
+	publishAfterCommit(tx, event)
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"log"
+
+	"zhlg/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// EnsureAssignmentContracts fills in the contract of assignments made before contracts were
+// stored at hiring time. Fixed-price assignments get an equal share of the budget unless an
+// amount was already agreed; hourly and daily assignments get the budget as their rate, or
+// the accepted bid that used to be kept as the amount, and the task's budget ceiling as
+// their contracted amount.
+func EnsureAssignmentContracts(db *gorm.DB) {
+	var assignments []models.TaskAssignment
+	if err := db.Preload("Task").
+		Joins("JOIN tasks ON tasks.id = task_assignments.task_id").
+		Where("(tasks.payment_type = ? AND task_assignments.contracted_amount IS NULL) OR (tasks.payment_type <> ? AND task_assignments.contracted_rate IS NULL)",
+			models.PaymentTypeFixed, models.PaymentTypeFixed).
+		Find(&assignments).Error; err != nil {
+		log.Printf("[EnsureAssignmentContracts] 查询任务分配失败: %v", err)
+		return
+	}
+
+	for _, a := range assignments {
+		amount, rate := a.Task.DefaultContract()
+		if a.Task.PaymentType != models.PaymentTypeFixed && a.ContractedAmount != nil {
+			rate = a.ContractedAmount
+		}
+		if err := db.Model(&models.TaskAssignment{}).Where("id = ?", a.ID).Updates(map[string]interface{}{
+			"contracted_amount": amount,
+			"contracted_rate":   rate,
+		}).Error; err != nil {
+			log.Printf("[EnsureAssignmentContracts] 更新任务分配失败: assignment=%d, err=%v", a.ID, err)
+		}
+	}
+}
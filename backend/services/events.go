@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Event is something that happened to a user, such as an application being accepted or a
+// payment arriving. Handlers publish events without knowing who consumes them; the
+// real-time push hub is one subscriber.
+type Event struct {
+	Type       string                 `json:"type"`
+	UserID     uint                   `json:"-"`
+	ActorID    uint                   `json:"-"`
+	EntityType string                 `json:"entity_type"`
+	EntityUUID string                 `json:"entity_uuid"`
+	Message    string                 `json:"message"`
+	Data       map[string]interface{} `json:"data"`
+	CreatedAt  time.Time              `json:"created_at"`
+
+	// NotificationUUID is set when the event also created a notification
+	NotificationUUID string `json:"notification_uuid,omitempty"`
+}
+
+var (
+	eventSubscribersMu sync.RWMutex
+	eventSubscribers   []func(Event)
+)
+
+// SubscribeEvents registers a function that is called with every published event.
+// Subscribers run on the publisher's goroutine and must not block.
+func SubscribeEvents(fn func(Event)) {
+	eventSubscribersMu.Lock()
+	defer eventSubscribersMu.Unlock()
+	eventSubscribers = append(eventSubscribers, fn)
+}
+
+// PublishEvent hands the event to every subscriber
+func PublishEvent(e Event) {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	eventSubscribersMu.RLock()
+	defer eventSubscribersMu.RUnlock()
+	for _, fn := range eventSubscribers {
+		fn(e)
+	}
+}
+
+// pendingEventsKey is the context key of the events held back by a transaction
+type pendingEventsKey struct{}
+
+// pendingEvents collects the events recorded inside a transaction until it commits
+type pendingEvents struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// withPendingEvents returns db with a context that holds back recorded events. ok is false
+// when db is already inside such a transaction, whose owner publishes the events.
+func withPendingEvents(db *gorm.DB) (*gorm.DB, *pendingEvents, bool) {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, nested := ctx.Value(pendingEventsKey{}).(*pendingEvents); nested {
+		return db, nil, false
+	}
+	pending := &pendingEvents{}
+	return db.WithContext(context.WithValue(ctx, pendingEventsKey{}, pending)), pending, true
+}
+
+// publish hands the collected events to the subscribers
+func (p *pendingEvents) publish() {
+	p.mu.Lock()
+	events := p.events
+	p.events = nil
+	p.mu.Unlock()
+	for _, e := range events {
+		PublishEvent(e)
+	}
+}
+
+// BeginTx starts a transaction whose recorded events are published by CommitTx once it has
+// committed, and dropped if it is rolled back
+func BeginTx(db *gorm.DB) *gorm.DB {
+	db, _, _ = withPendingEvents(db)
+	return db.Begin()
+}
+
+// CommitTx commits a transaction started by BeginTx and then publishes its events
+func CommitTx(tx *gorm.DB) error {
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	if pending, ok := tx.Statement.Context.Value(pendingEventsKey{}).(*pendingEvents); ok {
+		pending.publish()
+	}
+	return nil
+}
+
+// Transaction runs fn in a transaction like gorm's Transaction and publishes the events it
+// records once the transaction has committed
+func Transaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	db, pending, owned := withPendingEvents(db)
+	if err := db.Transaction(fn); err != nil {
+		return err
+	}
+	if owned {
+		pending.publish()
+	}
+	return nil
+}
+
+// publishAfterCommit holds the event back until the transaction started by BeginTx or
+// Transaction that db belongs to commits, and publishes it right away otherwise
+func publishAfterCommit(db *gorm.DB, e Event) {
+	if db.Statement.Context != nil {
+		if pending, ok := db.Statement.Context.Value(pendingEventsKey{}).(*pendingEvents); ok {
+			pending.mu.Lock()
+			pending.events = append(pending.events, e)
+			pending.mu.Unlock()
+			return
+		}
+	}
+	PublishEvent(e)
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"zhlg/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// kmPerDegreeLatitude is the approximate length of one degree of latitude
+const kmPerDegreeLatitude = 111.32
+
+// geoPointColumn is the stored POINT column kept in sync with a table's latitude and
+// longitude. Rows without coordinates get POINT(0 0) since a spatial index needs NOT NULL.
+const geoPointColumn = "geo_point"
+
+// geoIndexReady lists the tables whose geo_point column and spatial index exist. Radius
+// searches on other tables fall back to the (latitude, longitude) B-tree index.
+var geoIndexReady = map[string]bool{}
+
+// EnsureGeoIndex adds the generated geo_point column and its spatial index to the tasks and
+// users tables if they are missing
+func EnsureGeoIndex(db *gorm.DB) {
+	for _, table := range []string{"tasks", "users"} {
+		var count int64
+		db.Raw("SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?", table, geoPointColumn).Count(&count)
+		if count == 0 {
+			log.Printf("[EnsureGeoIndex] 为 %s 表添加坐标列", table)
+			if err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s POINT GENERATED ALWAYS AS "+
+				"(ST_SRID(POINT(COALESCE(longitude, 0), COALESCE(latitude, 0)), 4326)) STORED NOT NULL SRID 4326",
+				table, geoPointColumn)).Error; err != nil {
+				log.Printf("[EnsureGeoIndex] 添加坐标列失败，附近搜索将使用经纬度索引: %v", err)
+				continue
+			}
+		}
+
+		index := "sp_" + table + "_geo_point"
+		db.Raw("SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?", table, index).Count(&count)
+		if count == 0 {
+			log.Printf("[EnsureGeoIndex] 创建空间索引 %s", index)
+			if err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD SPATIAL INDEX %s (%s)", table, index, geoPointColumn)).Error; err != nil {
+				log.Printf("[EnsureGeoIndex] 创建空间索引失败，附近搜索将使用经纬度索引: %v", err)
+				continue
+			}
+		}
+		geoIndexReady[table] = true
+	}
+}
+
+// distanceSQL computes the distance in meters from a point to the coordinates of a row
+func distanceSQL(table string) string {
+	return "ST_Distance_Sphere(POINT(" + table + ".longitude, " + table + ".latitude), POINT(?, ?))"
+}
+
+// withinRadius restricts the query to rows of the table whose coordinates are within
+// radiusKm of the point. A bounding box narrows the candidates before the exact spherical
+// distance is checked, through the spatial index when it exists and the (latitude,
+// longitude) index otherwise.
+func withinRadius(query *gorm.DB, table string, lat, lng, radiusKm float64) *gorm.DB {
+	dLat := radiusKm / kmPerDegreeLatitude
+	dLng := radiusKm / (kmPerDegreeLatitude * math.Max(math.Cos(lat*math.Pi/180), 0.01))
+
+	if geoIndexReady[table] {
+		query = query.Where(table+".latitude IS NOT NULL").
+			Where("MBRContains(ST_SRID(ST_MakeEnvelope(POINT(?, ?), POINT(?, ?)), 4326), "+table+"."+geoPointColumn+")",
+				lng-dLng, lat-dLat, lng+dLng, lat+dLat)
+	} else {
+		query = query.Where(table+".latitude BETWEEN ? AND ? AND "+table+".longitude BETWEEN ? AND ?", lat-dLat, lat+dLat, lng-dLng, lng+dLng)
+	}
+	return query.Where(distanceSQL(table)+" <= ?", lng, lat, radiusKm*1000)
+}
+
+// orderByDistance sorts rows of the table nearest first
+func orderByDistance(query *gorm.DB, table string, lat, lng float64) *gorm.DB {
+	return query.Clauses(clause.OrderBy{Expression: clause.Expr{
+		SQL:                distanceSQL(table) + " ASC, " + table + ".created_at DESC",
+		Vars:               []interface{}{lng, lat},
+		WithoutParentheses: true,
+	}})
+}
+
+// NearTasks restricts the query to offline tasks within radiusKm of the point
+func NearTasks(query *gorm.DB, lat, lng, radiusKm float64) *gorm.DB {
+	query = query.Where("tasks.location_type = ?", models.LocationTypeOffline)
+	return withinRadius(query, "tasks", lat, lng, radiusKm)
+}
+
+// OrderTasksByDistance sorts tasks nearest first
+func OrderTasksByDistance(query *gorm.DB, lat, lng float64) *gorm.DB {
+	return orderByDistance(query, "tasks", lat, lng)
+}
+
+// NearUsers restricts the query to users whose saved location is within radiusKm of the point
+func NearUsers(query *gorm.DB, lat, lng, radiusKm float64) *gorm.DB {
+	return withinRadius(query, "users", lat, lng, radiusKm)
+}
+
+// OrderUsersByDistance sorts users nearest first
+func OrderUsersByDistance(query *gorm.DB, lat, lng float64) *gorm.DB {
+	return orderByDistance(query, "users", lat, lng)
+}
+
+// CoarsenUserLocations rounds profile coordinates saved before they were stored at about
+// 1 km precision
+func CoarsenUserLocations(db *gorm.DB) {
+	if err := db.Exec("UPDATE users SET latitude = ROUND(latitude, 2), longitude = ROUND(longitude, 2) " +
+		"WHERE latitude <> ROUND(latitude, 2) OR longitude <> ROUND(longitude, 2)").Error; err != nil {
+		log.Printf("[CoarsenUserLocations] 处理用户坐标失败: %v", err)
+	}
+}
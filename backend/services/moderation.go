@@ -0,0 +1,73 @@
+package services
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// ModerationVerdict is the outcome of checking a piece of user-written text
+type ModerationVerdict int
+
+// Moderation verdicts, from most to least permissive
+const (
+	ModerationAllow ModerationVerdict = iota
+	ModerationReview
+	ModerationReject
+)
+
+// Moderator checks user-written text of the given kind, such as "task_question" or
+// "task_answer", and decides whether it can be published
+type Moderator func(kind, text string) ModerationVerdict
+
+var (
+	moderatorsMu sync.RWMutex
+	moderators   = []Moderator{blockedWordsModerator}
+)
+
+// RegisterModerator adds a moderator that every piece of user-written text is checked by,
+// for example a call to an external content review service
+func RegisterModerator(m Moderator) {
+	moderatorsMu.Lock()
+	defer moderatorsMu.Unlock()
+	moderators = append(moderators, m)
+}
+
+// ModerateText runs the text through all registered moderators and returns the strictest
+// verdict
+func ModerateText(kind, text string) ModerationVerdict {
+	moderatorsMu.RLock()
+	defer moderatorsMu.RUnlock()
+
+	verdict := ModerationAllow
+	for _, m := range moderators {
+		if v := m(kind, text); v > verdict {
+			verdict = v
+		}
+	}
+	return verdict
+}
+
+// blockedWordsModerator rejects text containing any of the comma-separated words in
+// MODERATION_BLOCKED_WORDS and holds text containing MODERATION_REVIEW_WORDS for review
+func blockedWordsModerator(kind, text string) ModerationVerdict {
+	text = strings.ToLower(text)
+	if containsAnyWord(text, os.Getenv("MODERATION_BLOCKED_WORDS")) {
+		return ModerationReject
+	}
+	if containsAnyWord(text, os.Getenv("MODERATION_REVIEW_WORDS")) {
+		return ModerationReview
+	}
+	return ModerationAllow
+}
+
+// containsAnyWord reports whether the lower-cased text contains one of the comma-separated words
+func containsAnyWord(text, words string) bool {
+	for _, w := range strings.Split(words, ",") {
+		w = strings.ToLower(strings.TrimSpace(w))
+		if w != "" && strings.Contains(text, w) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"unicode/utf8"
+
+	"zhlg/backend/models"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// maxNotificationMessageLength is the length of the notifications.message column, in characters
+const maxNotificationMessageLength = 500
+
+// notificationEnabled reports whether the user wants notifications of the given type.
+// Every type is on until the user turns it off.
+func notificationEnabled(tx *gorm.DB, userID uint, notificationType string) (bool, error) {
+	var pref models.NotificationPreference
+	err := tx.Where("user_id = ? AND type = ?", userID, notificationType).First(&pref).Error
+	if err == gorm.ErrRecordNotFound {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return pref.Enabled, nil
+}
+
+// createNotification adds an entry to the target user's notification center for an
+// activity, unless the user turned that type of notification off. It returns nil when no
+// notification was created.
+func createNotification(tx *gorm.DB, actorID, userID uint, entityType, entityUUID, action, description string, detailsJSON []byte) (*models.Notification, error) {
+	enabled, err := notificationEnabled(tx, userID, action)
+	if err != nil || !enabled {
+		return nil, err
+	}
+
+	if utf8.RuneCountInString(description) > maxNotificationMessageLength {
+		description = string([]rune(description)[:maxNotificationMessageLength])
+	}
+	notification := models.Notification{
+		UserID:     userID,
+		Type:       action,
+		Message:    description,
+		EntityType: entityType,
+		EntityUUID: entityUUID,
+		Payload:    datatypes.JSON(detailsJSON),
+	}
+	if actorID != 0 {
+		notification.ActorID = &actorID
+	}
+	if err := tx.Create(&notification).Error; err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
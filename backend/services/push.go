@@ -0,0 +1,62 @@
+package services
+
+import (
+	"log"
+	"sync"
+)
+
+// pushBufferSize is how many events a connection can fall behind before events are dropped
+const pushBufferSize = 32
+
+// pushHub keeps the open real-time connections of each user and forwards published events
+// to the connections of the user they are for
+type pushHub struct {
+	mu      sync.RWMutex
+	clients map[uint]map[chan Event]struct{}
+}
+
+var hub = &pushHub{clients: make(map[uint]map[chan Event]struct{})}
+
+func init() {
+	SubscribeEvents(hub.deliver)
+}
+
+// SubscribePush opens a real-time connection for the user. The returned channel receives
+// the user's events until the returned function is called.
+func SubscribePush(userID uint) (<-chan Event, func()) {
+	ch := make(chan Event, pushBufferSize)
+
+	hub.mu.Lock()
+	if hub.clients[userID] == nil {
+		hub.clients[userID] = make(map[chan Event]struct{})
+	}
+	hub.clients[userID][ch] = struct{}{}
+	hub.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			hub.mu.Lock()
+			delete(hub.clients[userID], ch)
+			if len(hub.clients[userID]) == 0 {
+				delete(hub.clients, userID)
+			}
+			hub.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// deliver forwards an event to every connection of its user. A connection that is not
+// keeping up misses the event rather than holding up the publisher.
+func (h *pushHub) deliver(e Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.clients[e.UserID] {
+		select {
+		case ch <- e:
+		default:
+			log.Printf("[pushHub] 连接缓冲已满，丢弃事件: user=%d, type=%s", e.UserID, e.Type)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"zhlg/backend/models"
+	"zhlg/backend/utils"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Weights of each signal in a recommendation score. They add up to 1 and the final score
+// is scaled to 0-100.
+const (
+	weightSkillOverlap = 0.50
+	weightRateFit      = 0.20
+	weightLocation     = 0.15
+	weightEmployer     = 0.10
+	weightHistory      = 0.05
+)
+
+// Tuning for the recommendation feed
+const (
+	// RecommendationFeedSize is how many tasks are precomputed per worker
+	RecommendationFeedSize = 50
+	// recommendationCandidateLimit caps how many open tasks are scored per worker
+	recommendationCandidateLimit = 500
+	// localTaskRadiusKm is the distance at which an offline task stops scoring for location
+	localTaskRadiusKm = 30
+	// hoursPerWorkDay converts daily rates to hourly rates
+	hoursPerWorkDay = 8
+	// neutralFit is used for a signal when there is not enough data to judge it
+	neutralFit = 0.5
+)
+
+// ScoredTask is an open task ranked for a worker with the reasons behind its score
+type ScoredTask struct {
+	TaskID  uint
+	Score   float64
+	Reasons []string
+}
+
+// RecommendTasks scores open public tasks for the worker by skill overlap, pay against the
+// worker's hourly rate, distance, the employer's ratings and similarity to tasks the worker
+// applied to before. Tasks the worker already applied to are skipped.
+func RecommendTasks(db *gorm.DB, workerID uint, limit int) ([]ScoredTask, error) {
+	var worker models.User
+	if err := db.Preload("Skills").First(&worker, workerID).Error; err != nil {
+		return nil, err
+	}
+
+	workerSkills := make(map[uint]bool, len(worker.Skills))
+	for _, s := range worker.Skills {
+		workerSkills[s.ID] = true
+	}
+
+	var historyIDs []uint
+	if err := db.Table("task_skills").
+		Joins("JOIN task_applications ON task_applications.task_id = task_skills.task_id").
+		Where("task_applications.worker_id = ?", workerID).
+		Distinct().Pluck("task_skills.skill_id", &historyIDs).Error; err != nil {
+		return nil, err
+	}
+	historySkills := make(map[uint]bool, len(historyIDs))
+	for _, id := range historyIDs {
+		historySkills[id] = true
+	}
+
+	var tasks []models.Task
+	if err := db.Preload("Skills").
+		Where("status = ? AND is_public = ? AND employer_id <> ?", models.TaskStatusRecruiting, true, workerID).
+		Where("id NOT IN (SELECT task_id FROM task_applications WHERE worker_id = ?)", workerID).
+		Order("created_at DESC").Limit(recommendationCandidateLimit).
+		Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return []ScoredTask{}, nil
+	}
+
+	employerIDs := make([]uint, 0, len(tasks))
+	for _, t := range tasks {
+		employerIDs = append(employerIDs, t.EmployerID)
+	}
+	var ratingRows []struct {
+		RevieweeID uint
+		Average    float64
+	}
+	if err := db.Model(&models.Review{}).Select("reviewee_id, AVG(rating) AS average").
+		Where("review_type = ? AND reviewee_id IN ?", models.ReviewTypeWorkerToEmployer, employerIDs).
+		Group("reviewee_id").Scan(&ratingRows).Error; err != nil {
+		return nil, err
+	}
+	employerRatings := make(map[uint]float64, len(ratingRows))
+	for _, r := range ratingRows {
+		employerRatings[r.RevieweeID] = r.Average
+	}
+
+	scored := make([]ScoredTask, 0, len(tasks))
+	for i := range tasks {
+		task := &tasks[i]
+		reasons := make([]string, 0)
+
+		// 技能匹配
+		skillFit := 0.0
+		historyFit := 0.0
+		if len(task.Skills) > 0 {
+			matched := make([]string, 0)
+			similar := 0
+			for _, s := range task.Skills {
+				if workerSkills[s.ID] {
+					matched = append(matched, s.Name)
+				}
+				if historySkills[s.ID] {
+					similar++
+				}
+			}
+			skillFit = float64(len(matched)) / float64(len(task.Skills))
+			historyFit = float64(similar) / float64(len(task.Skills))
+			if len(matched) > 0 {
+				reasons = append(reasons, "匹配技能："+strings.Join(matched, "、"))
+			}
+			if similar > 0 {
+				reasons = append(reasons, "与您申请过的任务相似")
+			}
+		}
+
+		// 报酬与期望时薪
+		rateFit := neutralFit
+		if taskRate, ok := effectiveHourlyRate(task); ok && worker.HourlyRate != nil && *worker.HourlyRate > 0 {
+			rateFit = math.Min(1, taskRate / *worker.HourlyRate)
+			if rateFit >= 1 {
+				reasons = append(reasons, "报酬不低于您的期望时薪")
+			}
+		}
+
+		// 工作地点
+		locationFit := 1.0
+		if task.LocationType == models.LocationTypeOffline {
+			locationFit = 0.3
+			if task.Latitude != nil && task.Longitude != nil && worker.Latitude != nil && worker.Longitude != nil {
+				km := utils.DistanceMeters(*worker.Latitude, *worker.Longitude, *task.Latitude, *task.Longitude) / 1000
+				locationFit = math.Max(0, 1-km/localTaskRadiusKm)
+				if locationFit > 0 {
+					reasons = append(reasons, fmt.Sprintf("距离您约%.1f公里", km))
+				}
+			}
+		} else {
+			reasons = append(reasons, "线上远程")
+		}
+
+		// 雇主评分
+		employerFit := neutralFit
+		if rating, ok := employerRatings[task.EmployerID]; ok {
+			employerFit = rating / 5
+			if rating >= 4.5 {
+				reasons = append(reasons, fmt.Sprintf("雇主评分%.1f", rating))
+			}
+		}
+
+		score := weightSkillOverlap*skillFit +
+			weightRateFit*rateFit +
+			weightLocation*locationFit +
+			weightEmployer*employerFit +
+			weightHistory*historyFit
+		scored = append(scored, ScoredTask{
+			TaskID:  task.ID,
+			Score:   math.Round(score*10000) / 100,
+			Reasons: reasons,
+		})
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].Score > scored[j].Score })
+	if limit > 0 && len(scored) > limit {
+		scored = scored[:limit]
+	}
+	return scored, nil
+}
+
+// effectiveHourlyRate converts a task's budget to an hourly rate for comparison with the
+// worker's expected rate. Fixed-price tasks without an hour estimate cannot be compared.
+func effectiveHourlyRate(task *models.Task) (float64, bool) {
+	switch task.PaymentType {
+	case models.PaymentTypeHourly:
+		return task.BudgetAmount, true
+	case models.PaymentTypeDaily:
+		return task.BudgetAmount / hoursPerWorkDay, true
+	default:
+		if task.EstimatedHours > 0 {
+			return task.BudgetAmount / task.EstimatedHours, true
+		}
+		return 0, false
+	}
+}
+
+// RefreshRecommendations recomputes and stores the worker's recommendation feed
+func RefreshRecommendations(db *gorm.DB, workerID uint) error {
+	scored, err := RecommendTasks(db, workerID, RecommendationFeedSize)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	rows := make([]models.TaskRecommendation, 0, len(scored))
+	for _, s := range scored {
+		reasonsJSON, err := json.Marshal(s.Reasons)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, models.TaskRecommendation{
+			WorkerID:   workerID,
+			TaskID:     s.TaskID,
+			Score:      s.Score,
+			Reasons:    datatypes.JSON(reasonsJSON),
+			ComputedAt: now,
+		})
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("worker_id = ?", workerID).Delete(&models.TaskRecommendation{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	})
+}
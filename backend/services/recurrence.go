@@ -0,0 +1,299 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"zhlg/backend/models"
+	"zhlg/backend/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// recurrenceInvitationDays is how long an automatic invitation to a new occurrence stays
+// open, unless the occurrence starts sooner
+const recurrenceInvitationDays = 3
+
+// CopyTask creates a new task with the source task's content on new dates. The source
+// must have its Skills, Milestones and ScreeningQuestions loaded. Milestone due dates move
+// with the start date and are clamped to the new end date.
+func CopyTask(db *gorm.DB, source *models.Task, start, end time.Time, status models.TaskStatus) (*models.Task, error) {
+	task := models.Task{
+		UUID:            uuid.New().String(),
+		EmployerID:      source.EmployerID,
+		Title:           source.Title,
+		Description:     source.Description,
+		LocationType:    source.LocationType,
+		LocationDetails: source.LocationDetails,
+		Latitude:        source.Latitude,
+		Longitude:       source.Longitude,
+		GeoApproximate:  source.GeoApproximate,
+		CheckInRadius:   source.CheckInRadius,
+		StartDate:       start,
+		EndDate:         end,
+		PaymentType:     source.PaymentType,
+		BudgetAmount:    source.BudgetAmount,
+		Currency:        source.Currency,
+		Headcount:       source.Headcount,
+		EstimatedHours:  source.EstimatedHours,
+		BudgetCeiling:   source.BudgetCeiling,
+		Status:          status,
+		IsPublic:        source.IsPublic,
+		IsUrgent:        source.IsUrgent,
+		BiddingEnabled:  source.BiddingEnabled,
+	}
+	if status != models.TaskStatusDraft {
+		now := time.Now()
+		task.PublishedAt = &now
+	}
+
+	for _, q := range source.ScreeningQuestions {
+		task.ScreeningQuestions = append(task.ScreeningQuestions, models.TaskScreeningQuestion{
+			UUID:            uuid.New().String(),
+			Question:        q.Question,
+			Type:            q.Type,
+			Options:         q.Options,
+			Required:        q.Required,
+			KnockoutAnswers: q.KnockoutAnswers,
+			SortOrder:       q.SortOrder,
+		})
+	}
+
+	shift := start.Sub(source.StartDate)
+	milestones := make([]models.TaskMilestone, 0, len(source.Milestones))
+	for _, m := range source.Milestones {
+		milestone := models.TaskMilestone{
+			UUID:        uuid.New().String(),
+			Title:       m.Title,
+			Description: m.Description,
+			Amount:      m.Amount,
+			SortOrder:   m.SortOrder,
+			Status:      models.MilestoneStatusPending,
+		}
+		if m.DueDate != nil {
+			due := m.DueDate.Add(shift)
+			if due.After(end) {
+				due = end
+			}
+			milestone.DueDate = &due
+		}
+		milestones = append(milestones, milestone)
+	}
+
+	return &task, CreateTaskWithParts(db, &task, milestones, source.Skills)
+}
+
+// TemplateTaskBuilder validates a template on the given dates and turns it into a published
+// task with its milestones and skill names
+type TemplateTaskBuilder func(tmpl *models.TaskTemplate, start, end time.Time) (models.Task, []models.TaskMilestone, []string, error)
+
+// templateTaskBuilder is registered by the HTTP handlers so scheduled tasks are built and
+// checked exactly like tasks an employer creates from a template
+var templateTaskBuilder TemplateTaskBuilder
+
+// RegisterTemplateTaskBuilder sets how TaskFromTemplate builds tasks
+func RegisterTemplateTaskBuilder(b TemplateTaskBuilder) {
+	templateTaskBuilder = b
+}
+
+// TaskFromTemplate creates a published task from a template on the given dates. The
+// template must have its Skills loaded.
+func TaskFromTemplate(db *gorm.DB, tmpl *models.TaskTemplate, start, end time.Time) (*models.Task, error) {
+	if templateTaskBuilder == nil {
+		return nil, fmt.Errorf("未注册模板任务构建器")
+	}
+	task, milestones, skillNames, err := templateTaskBuilder(tmpl, start, end)
+	if err != nil {
+		return nil, err
+	}
+	skills, err := ResolveSkills(db, skillNames)
+	if err != nil {
+		return nil, err
+	}
+	return &task, CreateTaskWithParts(db, &task, milestones, skills)
+}
+
+// CreateTaskWithParts saves a task together with its milestones and skill tags in one
+// transaction
+func CreateTaskWithParts(db *gorm.DB, task *models.Task, milestones []models.TaskMilestone, skills []models.Skill) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Skills").Create(task).Error; err != nil {
+			return err
+		}
+		if len(milestones) > 0 {
+			for i := range milestones {
+				milestones[i].TaskID = task.ID
+			}
+			if err := tx.Create(&milestones).Error; err != nil {
+				return err
+			}
+		}
+		if len(skills) > 0 {
+			if err := tx.Model(task).Association("Skills").Append(skills); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MaterializeRecurrences creates the upcoming tasks of every active series whose start
+// dates fall within the series' lead window, and ends series that have run their course
+func MaterializeRecurrences(db *gorm.DB, now time.Time) error {
+	var series []models.TaskRecurrence
+	if err := db.Where("status = ?", models.RecurrenceStatusActive).Find(&series).Error; err != nil {
+		return err
+	}
+	for i := range series {
+		if err := MaterializeRecurrence(db, &series[i], now); err != nil {
+			log.Printf("[MaterializeRecurrences] 生成重复任务失败: recurrence=%s, err=%v", series[i].UUID, err)
+		}
+	}
+	return nil
+}
+
+// MaterializeRecurrence creates the due occurrences of one series and ends it once it has
+// run its course
+func MaterializeRecurrence(db *gorm.DB, series *models.TaskRecurrence, now time.Time) error {
+	rule, err := utils.ParseRRule(series.RRule)
+	if err != nil {
+		return err
+	}
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	occurrences := rule.Occurrences(series.StartDate, today.AddDate(0, 0, series.LeadDays))
+
+	for _, date := range occurrences {
+		if series.ReachedLimit() {
+			break
+		}
+		// 已生成的和已经过去的日期跳过
+		if date.Before(today) || (series.LastOccurrenceDate != nil && !date.After(*series.LastOccurrenceDate)) {
+			continue
+		}
+		task, err := createOccurrence(db, series, date)
+		if err != nil {
+			return err
+		}
+		log.Printf("[MaterializeRecurrences] 已生成重复任务: recurrence=%s, task=%s, date=%s", series.UUID, task.UUID, date.Format("2006-01-02"))
+	}
+
+	ended := series.ReachedLimit()
+	if rule.Until != nil && today.After(*rule.Until) {
+		ended = true
+	}
+	if rule.Count > 0 && len(occurrences) == rule.Count {
+		last := occurrences[len(occurrences)-1]
+		if last.Before(today) || (series.LastOccurrenceDate != nil && !series.LastOccurrenceDate.Before(last)) {
+			ended = true
+		}
+	}
+	if ended {
+		series.Status = models.RecurrenceStatusEnded
+		return db.Model(series).Update("status", series.Status).Error
+	}
+	return nil
+}
+
+// createOccurrence creates the task of one occurrence, advances the series and invites
+// the previous occurrence's workers when the series asks for it
+func createOccurrence(db *gorm.DB, series *models.TaskRecurrence, date time.Time) (*models.Task, error) {
+	start := date
+	end := date.AddDate(0, 0, series.DurationDays)
+
+	// 任务和系列进度一起写入，避免下次运行重复生成同一期
+	var task *models.Task
+	occurrencesCreated := series.OccurrencesCreated + 1
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		switch {
+		case series.TemplateID != nil:
+			var tmpl models.TaskTemplate
+			if err := tx.Preload("Skills").First(&tmpl, *series.TemplateID).Error; err != nil {
+				return fmt.Errorf("模板不存在: %w", err)
+			}
+			task, err = TaskFromTemplate(tx, &tmpl, start, end)
+		case series.SourceTaskID != nil:
+			var source models.Task
+			if err := tx.Preload("Skills").Preload("Milestones").Preload("ScreeningQuestions").
+				First(&source, *series.SourceTaskID).Error; err != nil {
+				return fmt.Errorf("源任务不存在: %w", err)
+			}
+			task, err = CopyTask(tx, &source, start, end, models.TaskStatusRecruiting)
+		default:
+			return fmt.Errorf("重复任务缺少模板或源任务")
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(task).Update("recurrence_id", series.ID).Error; err != nil {
+			return err
+		}
+		return tx.Model(series).Updates(map[string]interface{}{
+			"occurrences_created":  occurrencesCreated,
+			"last_occurrence_date": date,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	series.OccurrencesCreated = occurrencesCreated
+	series.LastOccurrenceDate = &date
+
+	if series.AutoInvite {
+		invitePreviousWorkers(db, series, task)
+	}
+	if err := MatchSavedSearches(db, task.ID); err != nil {
+		log.Printf("[createOccurrence] 匹配保存的搜索失败: %v", err)
+	}
+	return task, nil
+}
+
+// invitePreviousWorkers invites everyone who worked on the previous occurrence of the
+// series, and did not quit, to apply to the new one
+func invitePreviousWorkers(db *gorm.DB, series *models.TaskRecurrence, task *models.Task) {
+	var previous models.Task
+	if err := db.Where("recurrence_id = ? AND id <> ? AND start_date < ?", series.ID, task.ID, task.StartDate).
+		Order("start_date DESC").First(&previous).Error; err != nil {
+		return
+	}
+
+	var workerIDs []uint
+	if err := db.Model(&models.TaskAssignment{}).
+		Where("task_id = ? AND worker_status <> ?", previous.ID, models.WorkerStatusQuit).
+		Distinct().Pluck("worker_id", &workerIDs).Error; err != nil {
+		log.Printf("[invitePreviousWorkers] 查询上一期零工失败: %v", err)
+		return
+	}
+
+	expiresAt := time.Now().AddDate(0, 0, recurrenceInvitationDays)
+	if startDay := task.StartDate.AddDate(0, 0, 1); startDay.Before(expiresAt) {
+		expiresAt = startDay
+	}
+	message := fmt.Sprintf("您参与过上一期的「%s」，欢迎继续报名本期（%s）。", previous.Title, task.StartDate.Format("2006-01-02"))
+	for _, workerID := range workerIDs {
+		invitation := models.TaskInvitation{
+			UUID:       uuid.New().String(),
+			TaskID:     task.ID,
+			EmployerID: task.EmployerID,
+			WorkerID:   workerID,
+			Message:    &message,
+			Status:     models.InvitationStatusPending,
+			ExpiresAt:  expiresAt,
+		}
+		if err := db.Create(&invitation).Error; err != nil {
+			log.Printf("[invitePreviousWorkers] 创建邀请失败: worker=%d, err=%v", workerID, err)
+			continue
+		}
+		RecordActivity(db, task.EmployerID, workerID, "task_invitation", invitation.UUID, "invitation_received",
+			fmt.Sprintf("雇主邀请您参与任务：%s", task.Title), map[string]interface{}{
+				"task_uuid":   task.UUID,
+				"direct_hire": false,
+				"expires_at":  invitation.ExpiresAt.Format(time.RFC3339),
+				"recurring":   true,
+			})
+	}
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"zhlg/backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// savedSearchDigestInterval is the minimum time between two digests for a daily search
+const savedSearchDigestInterval = 24 * time.Hour
+
+// maxDigestTasks caps how many tasks are listed in one digest
+const maxDigestTasks = 20
+
+// matchesSavedSearch reports whether the task passes the saved search's filters. The
+// filters are applied in SQL with the same helpers as the task list so an alert never
+// disagrees with what the user sees when opening the search.
+func matchesSavedSearch(db *gorm.DB, search *models.SavedSearch, taskID uint) (bool, error) {
+	query := db.Model(&models.Task{}).Where("tasks.id = ?", taskID)
+	if text := strings.TrimSpace(search.SearchQuery); text != "" {
+		query, _ = SearchTasks(query, text)
+	}
+	if search.Skills != "" {
+		skillIDs, err := SkillFilterIDs(db, search.Skills)
+		if err != nil {
+			return false, err
+		}
+		query = query.Where("tasks.id IN (SELECT task_id FROM task_skills WHERE skill_id IN ?)", skillIDs)
+	}
+	if search.LocationType != "" {
+		query = query.Where("tasks.location_type = ?", search.LocationType)
+	}
+	if search.HasNear() {
+		query = NearTasks(query, *search.NearLatitude, *search.NearLongitude, search.Radius)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// MatchSavedSearches checks a newly published task against every active saved search.
+// Owners of instant searches are notified right away; matches for daily searches are kept
+// for the next digest.
+func MatchSavedSearches(db *gorm.DB, taskID uint) error {
+	var task models.Task
+	if err := db.First(&task, taskID).Error; err != nil {
+		return err
+	}
+	if task.Status != models.TaskStatusRecruiting || !task.IsPublic {
+		return nil
+	}
+
+	var searches []models.SavedSearch
+	if err := db.Preload("User").
+		Where("frequency <> ? AND user_id <> ?", models.AlertFrequencyNever, task.EmployerID).
+		Where("location_type = '' OR location_type = ?", task.LocationType).
+		Find(&searches).Error; err != nil {
+		return err
+	}
+
+	for i := range searches {
+		search := &searches[i]
+		matched, err := matchesSavedSearch(db, search, task.ID)
+		if err != nil {
+			log.Printf("[MatchSavedSearches] 匹配保存的搜索失败: search=%s, err=%v", search.UUID, err)
+			continue
+		}
+		if !matched {
+			continue
+		}
+
+		match := models.SavedSearchMatch{SavedSearchID: search.ID, TaskID: task.ID, MatchedAt: time.Now()}
+		result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&match)
+		if result.Error != nil {
+			log.Printf("[MatchSavedSearches] 记录匹配失败: search=%s, err=%v", search.UUID, result.Error)
+			continue
+		}
+		// 已匹配过的任务不重复提醒
+		if result.RowsAffected == 0 {
+			continue
+		}
+		if search.Frequency == models.AlertFrequencyInstant {
+			notifySavedSearch(db, search, []models.Task{task}, []uint{match.ID})
+		}
+	}
+	return nil
+}
+
+// SendSavedSearchDigests sends one digest per daily search that has unsent matches and was
+// last notified at least a day ago. Tasks that stopped recruiting in the meantime are
+// dropped from the digest.
+func SendSavedSearchDigests(db *gorm.DB) error {
+	var searches []models.SavedSearch
+	if err := db.Preload("User").
+		Where("frequency = ? AND (last_notified_at IS NULL OR last_notified_at <= ?)", models.AlertFrequencyDaily, time.Now().Add(-savedSearchDigestInterval)).
+		Where("EXISTS (SELECT 1 FROM saved_search_matches m WHERE m.saved_search_id = saved_searches.id AND m.notified_at IS NULL)").
+		Find(&searches).Error; err != nil {
+		return err
+	}
+
+	for i := range searches {
+		search := &searches[i]
+		var matches []models.SavedSearchMatch
+		if err := db.Preload("Task").Where("saved_search_id = ? AND notified_at IS NULL", search.ID).
+			Order("matched_at DESC").Find(&matches).Error; err != nil {
+			log.Printf("[SendSavedSearchDigests] 查询匹配失败: search=%s, err=%v", search.UUID, err)
+			continue
+		}
+
+		matchIDs := make([]uint, 0, len(matches))
+		tasks := make([]models.Task, 0, len(matches))
+		for _, m := range matches {
+			matchIDs = append(matchIDs, m.ID)
+			if m.Task.Status == models.TaskStatusRecruiting && len(tasks) < maxDigestTasks {
+				tasks = append(tasks, m.Task)
+			}
+		}
+		notifySavedSearch(db, search, tasks, matchIDs)
+	}
+	return nil
+}
+
+// notifySavedSearch tells the owner of a saved search about matching tasks in-app and, if
+// they opted in, by email, then marks the matches as sent
+func notifySavedSearch(db *gorm.DB, search *models.SavedSearch, tasks []models.Task, matchIDs []uint) {
+	now := time.Now()
+	if len(tasks) > 0 {
+		taskUUIDs := make([]string, 0, len(tasks))
+		lines := make([]string, 0, len(tasks))
+		for _, t := range tasks {
+			taskUUIDs = append(taskUUIDs, t.UUID)
+			lines = append(lines, fmt.Sprintf("- %s（%s）", t.Title, t.BudgetDisplay()))
+		}
+
+		description := fmt.Sprintf("新任务符合您保存的搜索「%s」：%s", search.Name, tasks[0].Title)
+		if len(tasks) > 1 {
+			description = fmt.Sprintf("有%d个新任务符合您保存的搜索「%s」", len(tasks), search.Name)
+		}
+		RecordActivity(db, 0, search.UserID, "saved_search", search.UUID, "saved_search_match", description, map[string]interface{}{
+			"task_uuids": taskUUIDs,
+			"frequency":  search.Frequency,
+		})
+
+		if search.NotifyEmail && search.User.Email != nil && *search.User.Email != "" {
+			body := description + "\n\n" + strings.Join(lines, "\n")
+			if err := SendMail(*search.User.Email, "新任务提醒："+search.Name, body); err != nil {
+				log.Printf("[notifySavedSearch] %v", err)
+			}
+		}
+
+		if err := db.Model(search).Update("last_notified_at", now).Error; err != nil {
+			log.Printf("[notifySavedSearch] 更新提醒时间失败: search=%s, err=%v", search.UUID, err)
+		}
+	}
+
+	if err := db.Model(&models.SavedSearchMatch{}).Where("id IN ?", matchIDs).Update("notified_at", now).Error; err != nil {
+		log.Printf("[notifySavedSearch] 更新匹配状态失败: search=%s, err=%v", search.UUID, err)
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"zhlg/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// PublishScheduledTasks publishes the drafts whose scheduled publish time has come. A
+// draft whose start date has already passed is not published; its schedule is cleared and
+// the employer is told to fix it.
+func PublishScheduledTasks(db *gorm.DB, now time.Time) error {
+	var tasks []models.Task
+	if err := db.Where("status = ? AND scheduled_publish_at <= ?", models.TaskStatusDraft, now).
+		Find(&tasks).Error; err != nil {
+		return err
+	}
+
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	for _, task := range tasks {
+		if task.StartDate.Before(today) || len(task.DraftData) > 0 {
+			if err := db.Model(&task).Update("scheduled_publish_at", nil).Error; err != nil {
+				log.Printf("[PublishScheduledTasks] 取消定时发布失败: task=%s, err=%v", task.UUID, err)
+				continue
+			}
+			RecordActivity(db, 0, task.EmployerID, "task", task.UUID, "scheduled_publish_failed",
+				fmt.Sprintf("任务「%s」定时发布失败，请修改后重新发布", task.Title), map[string]interface{}{
+					"start_date": task.StartDate.Format("2006-01-02"),
+				})
+			continue
+		}
+
+		// 仍为草稿时才发布，避免与雇主手动发布重复
+		result := db.Model(&models.Task{}).
+			Where("id = ? AND status = ?", task.ID, models.TaskStatusDraft).
+			Updates(map[string]interface{}{
+				"status":               models.TaskStatusRecruiting,
+				"published_at":         now,
+				"scheduled_publish_at": nil,
+			})
+		if result.Error != nil {
+			log.Printf("[PublishScheduledTasks] 发布任务失败: task=%s, err=%v", task.UUID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		log.Printf("[PublishScheduledTasks] 已定时发布任务: task=%s", task.UUID)
+		RecordActivity(db, 0, task.EmployerID, "task", task.UUID, "task_published",
+			fmt.Sprintf("您的任务「%s」已按计划发布", task.Title), nil)
+		if err := MatchSavedSearches(db, task.ID); err != nil {
+			log.Printf("[PublishScheduledTasks] 匹配保存的搜索失败: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Full-text indexes on the tasks table. The ngram parser splits text into two-character
+// tokens, which segments Chinese without a dictionary and lets misspelled queries still
+// match on the fragments they share with the indexed text.
+const (
+	taskTitleIndex    = "ft_tasks_title"
+	taskFullTextIndex = "ft_tasks_title_description"
+)
+
+// minFullTextQueryRunes is the shortest query the ngram parser can tokenize
+const minFullTextQueryRunes = 2
+
+// taskFullTextReady is set once the full-text indexes are known to exist. Searches fall
+// back to LIKE matching when it is false, e.g. on a MySQL build without the ngram parser.
+var taskFullTextReady bool
+
+// EnsureTaskSearchIndex creates the full-text indexes used by task search if they are missing
+func EnsureTaskSearchIndex(db *gorm.DB) {
+	indexes := []struct {
+		name    string
+		columns string
+	}{
+		{taskTitleIndex, "title"},
+		{taskFullTextIndex, "title, description"},
+	}
+
+	for _, idx := range indexes {
+		var count int64
+		db.Raw("SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = 'tasks' AND index_name = ?", idx.name).Count(&count)
+		if count > 0 {
+			continue
+		}
+		log.Printf("[EnsureTaskSearchIndex] 创建全文索引 %s", idx.name)
+		if err := db.Exec("ALTER TABLE tasks ADD FULLTEXT INDEX " + idx.name + " (" + idx.columns + ") WITH PARSER ngram").Error; err != nil {
+			log.Printf("[EnsureTaskSearchIndex] 创建全文索引失败，搜索将退化为模糊匹配: %v", err)
+			return
+		}
+	}
+	taskFullTextReady = true
+}
+
+// SearchTasks restricts the query to tasks matching the search text and reports whether
+// results can be ordered by relevance
+func SearchTasks(query *gorm.DB, text string) (*gorm.DB, bool) {
+	text = strings.TrimSpace(text)
+	if !taskFullTextReady || utf8.RuneCountInString(text) < minFullTextQueryRunes {
+		like := "%" + text + "%"
+		return query.Where("tasks.title LIKE ? OR tasks.description LIKE ?", like, like), false
+	}
+	return query.Where("MATCH(tasks.title, tasks.description) AGAINST(? IN NATURAL LANGUAGE MODE)", text), true
+}
+
+// OrderTasksByRelevance sorts search results with title matches weighted above matches in
+// the description, newest first among equally relevant tasks
+func OrderTasksByRelevance(query *gorm.DB, text string) *gorm.DB {
+	text = strings.TrimSpace(text)
+	return query.Clauses(clause.OrderBy{Expression: clause.Expr{
+		SQL:                "MATCH(tasks.title) AGAINST(? IN NATURAL LANGUAGE MODE) * 2 + MATCH(tasks.title, tasks.description) AGAINST(? IN NATURAL LANGUAGE MODE) DESC, tasks.created_at DESC",
+		Vars:               []interface{}{text, text},
+		WithoutParentheses: true,
+	}})
+}
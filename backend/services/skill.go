@@ -0,0 +1,277 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"zhlg/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// NormalizeSkillName folds a skill name to the key used for matching: full-width
+// characters become half-width, letters are lower-cased and whitespace is dropped, so
+// "Go", " GO " and "ＧＯ" all share one key.
+func NormalizeSkillName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r == 0x3000:
+			continue
+		case r >= 0xFF01 && r <= 0xFF5E:
+			r -= 0xFEE0
+		}
+		if unicode.IsSpace(r) {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// EnsureSkillNormalization fills in the normalized name of skills created before it existed
+func EnsureSkillNormalization(db *gorm.DB) {
+	var skills []models.Skill
+	if err := db.Select("id", "name").Where("normalized_name = ''").Find(&skills).Error; err != nil {
+		log.Printf("[EnsureSkillNormalization] 查询技能失败: %v", err)
+		return
+	}
+	for _, s := range skills {
+		if err := db.Model(&models.Skill{}).Where("id = ?", s.ID).Update("normalized_name", NormalizeSkillName(s.Name)).Error; err != nil {
+			log.Printf("[EnsureSkillNormalization] 更新技能失败: skill=%d, err=%v", s.ID, err)
+		}
+	}
+}
+
+// lookupSkill finds the canonical skill for a name through its synonyms or its own
+// normalized name. It returns nil without an error when the name is unknown.
+func lookupSkill(db *gorm.DB, key string) (*models.Skill, error) {
+	var synonym models.SkillSynonym
+	err := db.Where("normalized_name = ?", key).First(&synonym).Error
+	if err == nil {
+		var skill models.Skill
+		if err := db.First(&skill, synonym.SkillID).Error; err != nil {
+			return nil, err
+		}
+		return &skill, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	var skill models.Skill
+	err = db.Where("normalized_name = ?", key).Order("id ASC").First(&skill).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &skill, nil
+}
+
+// ResolveSkills maps free-text skill names to canonical skills, creating a skill only when
+// no existing skill or synonym matches. Blank names and names resolving to the same skill
+// are dropped.
+func ResolveSkills(db *gorm.DB, names []string) ([]models.Skill, error) {
+	skills := make([]models.Skill, 0, len(names))
+	seen := make(map[uint]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		key := NormalizeSkillName(name)
+		if key == "" {
+			continue
+		}
+
+		skill, err := lookupSkill(db, key)
+		if err != nil {
+			return nil, err
+		}
+		if skill == nil {
+			skill = &models.Skill{Name: name, NormalizedName: key}
+			if err := db.Create(skill).Error; err != nil {
+				return nil, err
+			}
+		}
+		if !seen[skill.ID] {
+			seen[skill.ID] = true
+			skills = append(skills, *skill)
+		}
+	}
+	return skills, nil
+}
+
+// SkillIDsForFilter resolves skill names from a list filter to skill IDs, following
+// synonyms and including every narrower skill below each match, so filtering by "Go" also
+// finds tasks tagged "golang" or "Gin". Unknown names are ignored.
+func SkillIDsForFilter(db *gorm.DB, names []string) ([]uint, error) {
+	ids := make([]uint, 0, len(names))
+	seen := make(map[uint]bool)
+	for _, name := range names {
+		key := NormalizeSkillName(name)
+		if key == "" {
+			continue
+		}
+		skill, err := lookupSkill(db, key)
+		if err != nil {
+			return nil, err
+		}
+		if skill != nil && !seen[skill.ID] {
+			seen[skill.ID] = true
+			ids = append(ids, skill.ID)
+		}
+	}
+
+	return SkillsWithDescendants(db, ids)
+}
+
+// SkillsWithDescendants returns the given skills followed by every skill below them in
+// the parent hierarchy
+func SkillsWithDescendants(db *gorm.DB, ids []uint) ([]uint, error) {
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	frontier := ids
+	for len(frontier) > 0 {
+		var children []uint
+		if err := db.Model(&models.Skill{}).Where("parent_id IN ?", frontier).Pluck("id", &children).Error; err != nil {
+			return nil, err
+		}
+		frontier = make([]uint, 0, len(children))
+		for _, id := range children {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+				frontier = append(frontier, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// SkillFilterIDs is SkillIDsForFilter for a comma-separated list. A filter that matches no
+// known skill yields a single impossible ID so the filtered query returns nothing instead
+// of ignoring the filter.
+func SkillFilterIDs(db *gorm.DB, list string) ([]uint, error) {
+	ids, err := SkillIDsForFilter(db, strings.Split(list, ","))
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []uint{0}, nil
+	}
+	return ids, nil
+}
+
+// AddSkillSynonym maps another spelling to the skill. A spelling that already names a
+// different skill or synonym is rejected; those skills should be merged instead.
+func AddSkillSynonym(db *gorm.DB, skillID uint, name string) (*models.SkillSynonym, error) {
+	name = strings.TrimSpace(name)
+	key := NormalizeSkillName(name)
+	if key == "" {
+		return nil, fmt.Errorf("同义词不能为空")
+	}
+	existing, err := lookupSkill(db, key)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.ID == skillID {
+			return nil, fmt.Errorf("同义词已指向该技能")
+		}
+		return nil, fmt.Errorf("“%s”已对应技能“%s”，请使用合并", name, existing.Name)
+	}
+
+	synonym := models.SkillSynonym{SkillID: skillID, Name: name, NormalizedName: key}
+	if err := db.Create(&synonym).Error; err != nil {
+		return nil, err
+	}
+	return &synonym, nil
+}
+
+// MergeSkills folds the source skills into the target: task and user tags are rewritten
+// to the target without creating duplicate pairs, synonyms and narrower skills move over,
+// the source names become synonyms of the target and the source skills are deleted. It
+// should be run in a transaction.
+func MergeSkills(tx *gorm.DB, targetID uint, sourceIDs []uint) error {
+	var target models.Skill
+	if err := tx.First(&target, targetID).Error; err != nil {
+		return fmt.Errorf("技能不存在: %d", targetID)
+	}
+
+	for _, sourceID := range sourceIDs {
+		if sourceID == targetID {
+			return fmt.Errorf("不能将技能合并到自身")
+		}
+
+		var source models.Skill
+		if err := tx.First(&source, sourceID).Error; err != nil {
+			return fmt.Errorf("技能不存在: %d", sourceID)
+		}
+
+		// 目标是来源技能的直接下级时可以上移，更深的下级合并后会形成循环
+		if target.ParentID == nil || *target.ParentID != sourceID {
+			descendants, err := SkillsWithDescendants(tx, []uint{sourceID})
+			if err != nil {
+				return err
+			}
+			for _, id := range descendants {
+				if id == targetID {
+					return fmt.Errorf("不能将技能“%s”合并到其下级技能“%s”", source.Name, target.Name)
+				}
+			}
+		}
+
+		for _, pivot := range []string{"task_skills", "user_skills"} {
+			owner := "task_id"
+			if pivot == "user_skills" {
+				owner = "user_id"
+			}
+			if err := tx.Exec("INSERT IGNORE INTO "+pivot+" ("+owner+", skill_id) SELECT "+owner+", ? FROM "+pivot+" WHERE skill_id = ?", targetID, sourceID).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("DELETE FROM "+pivot+" WHERE skill_id = ?", sourceID).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.SkillSynonym{}).Where("skill_id = ?", sourceID).Update("skill_id", targetID).Error; err != nil {
+			return err
+		}
+		// 目标原本挂在来源技能下时，改为挂到来源技能的上级
+		if target.ParentID != nil && *target.ParentID == sourceID {
+			target.ParentID = source.ParentID
+			if err := tx.Model(&target).Update("parent_id", target.ParentID).Error; err != nil {
+				return err
+			}
+		}
+		if target.CategoryID == nil && source.CategoryID != nil {
+			target.CategoryID = source.CategoryID
+			if err := tx.Model(&target).Update("category_id", target.CategoryID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.Skill{}).Where("parent_id = ?", sourceID).Update("parent_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Skill{}, sourceID).Error; err != nil {
+			return err
+		}
+
+		key := source.NormalizedName
+		if key == "" {
+			key = NormalizeSkillName(source.Name)
+		}
+		var count int64
+		tx.Model(&models.SkillSynonym{}).Where("normalized_name = ?", key).Count(&count)
+		if count == 0 && key != target.NormalizedName {
+			if err := tx.Create(&models.SkillSynonym{SkillID: targetID, Name: source.Name, NormalizedName: key}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"zhlg/backend/models"
+
+	"gorm.io/gorm"
+)
+
+// ExpireStaleTasks stops recruiting on tasks whose recruiting deadline or end date has
+// passed. Tasks nobody was hired for are closed; tasks that already have workers move on
+// to in progress. Pending applications are rejected, pending invitations expire and the
+// employer and rejected applicants are notified.
+//
+// Tasks are paid out per assignment when work is confirmed and employers fund nothing up
+// front, so a closed task has no escrowed balance to refund.
+func ExpireStaleTasks(db *gorm.DB, now time.Time) error {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+
+	var tasks []models.Task
+	if err := db.Where("status = ?", models.TaskStatusRecruiting).
+		Where("end_date < ? OR (recruiting_deadline IS NOT NULL AND recruiting_deadline <= ?)", today, now).
+		Find(&tasks).Error; err != nil {
+		return err
+	}
+
+	for i := range tasks {
+		if err := expireTask(db, &tasks[i], today); err != nil {
+			log.Printf("[ExpireStaleTasks] 关闭过期任务失败: task=%s, err=%v", tasks[i].UUID, err)
+		}
+	}
+	return nil
+}
+
+// expireTask stops recruiting on one task inside a transaction
+func expireTask(db *gorm.DB, task *models.Task, today time.Time) error {
+	reason := "招募已截止"
+	if task.EndDate.Before(today) {
+		reason = "任务结束日期已过"
+	}
+
+	return Transaction(db, func(tx *gorm.DB) error {
+		var active int64
+		if err := tx.Model(&models.TaskAssignment{}).
+			Where("task_id = ? AND worker_status IN ?", task.ID, []models.WorkerStatus{models.WorkerStatusWorking, models.WorkerStatusSubmitted}).
+			Count(&active).Error; err != nil {
+			return err
+		}
+		status := models.TaskStatusClosed
+		if active > 0 {
+			status = models.TaskStatusInProgress
+		}
+
+		// 状态仍为招募中时才处理，避免与雇主的操作冲突
+		result := tx.Model(&models.Task{}).Where("id = ? AND status = ?", task.ID, models.TaskStatusRecruiting).
+			Update("status", status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		task.Status = status
+
+		var applications []models.TaskApplication
+		if err := tx.Where("task_id = ? AND status = ?", task.ID, models.ApplicationStatusPending).
+			Find(&applications).Error; err != nil {
+			return err
+		}
+		if len(applications) > 0 {
+			if err := tx.Model(&models.TaskApplication{}).
+				Where("task_id = ? AND status = ?", task.ID, models.ApplicationStatusPending).
+				Updates(map[string]interface{}{
+					"status":     models.ApplicationStatusRejected,
+					"updated_at": time.Now(),
+				}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.TaskInvitation{}).
+			Where("task_id = ? AND status = ?", task.ID, models.InvitationStatusPending).
+			Update("status", models.InvitationStatusExpired).Error; err != nil {
+			return err
+		}
+
+		for _, application := range applications {
+			RecordActivity(tx, 0, application.WorkerID, "task_application", application.UUID, "application_rejected",
+				fmt.Sprintf("您申请的任务「%s」%s，申请未被录用", task.Title, reason), map[string]interface{}{
+					"task_uuid": task.UUID,
+					"reason":    reason,
+				})
+		}
+		description := fmt.Sprintf("您的任务「%s」%s，已自动关闭", task.Title, reason)
+		if status == models.TaskStatusInProgress {
+			description = fmt.Sprintf("您的任务「%s」%s，已停止招募", task.Title, reason)
+		}
+		RecordActivity(tx, 0, task.EmployerID, "task", task.UUID, "task_expired", description, map[string]interface{}{
+			"reason":                reason,
+			"status":                status,
+			"rejected_applications": len(applications),
+		})
+
+		log.Printf("[ExpireStaleTasks] 任务已停止招募: task=%s, status=%s, rejected=%d", task.UUID, status, len(applications))
+		return nil
+	})
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// gazetteerCSV lists city and district center points used for offline geocoding
+//
+//go:embed gazetteer.csv
+var gazetteerCSV string
+
+// Place is a named location resolved from the gazetteer
+type Place struct {
+	City      string  `json:"city"`
+	District  string  `json:"district,omitempty"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Name returns the full display name of the place
+func (p Place) Name() string {
+	return p.City + p.District
+}
+
+var (
+	gazetteerOnce   sync.Once
+	gazetteerCities map[string]Place
+	gazetteerAreas  map[string]Place
+)
+
+// loadGazetteer indexes every place under its full name and the name without the
+// administrative suffix, e.g. both "杭州市" and "杭州". Districts are also indexed together
+// with their city so that "杭州西湖区" is preferred over a bare "西湖区".
+func loadGazetteer() {
+	gazetteerCities = make(map[string]Place)
+	gazetteerAreas = make(map[string]Place)
+
+	reader := csv.NewReader(strings.NewReader(gazetteerCSV))
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		log.Printf("[loadGazetteer] 解析地名库失败: %v", err)
+		return
+	}
+
+	for _, r := range records {
+		if len(r) != 4 {
+			continue
+		}
+		lat, errLat := strconv.ParseFloat(r[2], 64)
+		lng, errLng := strconv.ParseFloat(r[3], 64)
+		if errLat != nil || errLng != nil {
+			continue
+		}
+		place := Place{City: r[0], District: r[1], Latitude: lat, Longitude: lng}
+		city := strings.TrimSuffix(place.City, "市")
+
+		if place.District == "" {
+			gazetteerCities[place.City] = place
+			gazetteerCities[city] = place
+			continue
+		}
+		for _, prefix := range []string{place.City, city} {
+			gazetteerAreas[prefix+place.District] = place
+		}
+		// 同名区县只保留地名库中第一个
+		if _, ok := gazetteerAreas[place.District]; !ok {
+			gazetteerAreas[place.District] = place
+		}
+	}
+}
+
+// longestContained returns the place whose key is the longest substring of text
+func longestContained(text string, index map[string]Place) (Place, bool) {
+	var best Place
+	bestLen := 0
+	for key, place := range index {
+		if len(key) > bestLen && strings.Contains(text, key) {
+			best, bestLen = place, len(key)
+		}
+	}
+	return best, bestLen > 0
+}
+
+// Geocode resolves a free-text Chinese address such as "浙江省杭州市西湖区文三路" to the
+// center of the most specific city or district it mentions. It works entirely offline
+// from the embedded gazetteer, so street-level precision is not available.
+func Geocode(text string) (Place, bool) {
+	gazetteerOnce.Do(loadGazetteer)
+
+	text = strings.Join(strings.Fields(text), "")
+	if text == "" {
+		return Place{}, false
+	}
+
+	city, cityFound := longestContained(text, gazetteerCities)
+	area, areaFound := longestContained(text, gazetteerAreas)
+
+	switch {
+	case areaFound && (!cityFound || area.City == city.City):
+		return area, true
+	case cityFound:
+		// 区县名属于其他城市（如 "福州鼓楼区"），退回到城市中心
+		return city, true
+	}
+	return Place{}, false
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Highlight marks the parts of text matching the search query with <em> tags and returns
+// an HTML-escaped snippet of at most maxRunes characters around the first match. Whole
+// terms are matched first; when none are found, two-character fragments of the terms are
+// used so that misspelled queries still show why a result matched. A maxRunes of 0 keeps
+// the whole text.
+func Highlight(text, query string, maxRunes int) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	terms := make([][]rune, 0)
+	for _, term := range strings.Fields(query) {
+		terms = append(terms, []rune(strings.ToLower(term)))
+	}
+
+	marks := make([]bool, len(runes))
+	matched := markTerms(lower, terms, marks)
+	if !matched {
+		fragments := make([][]rune, 0)
+		for _, term := range terms {
+			for i := 0; i+2 <= len(term); i++ {
+				fragments = append(fragments, term[i:i+2])
+			}
+		}
+		markTerms(lower, fragments, marks)
+	}
+
+	start, end := 0, len(runes)
+	if maxRunes > 0 && len(runes) > maxRunes {
+		first := 0
+		for i, m := range marks {
+			if m {
+				first = i
+				break
+			}
+		}
+		start = first - maxRunes/4
+		if start < 0 {
+			start = 0
+		}
+		end = start + maxRunes
+		if end > len(runes) {
+			end = len(runes)
+			start = end - maxRunes
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
+	}
+	inMark := false
+	segment := start
+	for i := start; i <= end; i++ {
+		if i < end && marks[i] == inMark {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[segment:i])))
+		if i < end {
+			if inMark {
+				b.WriteString("</em>")
+			} else {
+				b.WriteString("<em>")
+			}
+			inMark = !inMark
+			segment = i
+		}
+	}
+	if inMark {
+		b.WriteString("</em>")
+	}
+	if end < len(runes) {
+		b.WriteString("...")
+	}
+	return b.String()
+}
+
+// markTerms flags every position of text covered by one of the terms and reports whether
+// anything matched
+func markTerms(text []rune, terms [][]rune, marks []bool) bool {
+	matched := false
+	for _, term := range terms {
+		if len(term) == 0 {
+			continue
+		}
+		for i := 0; i+len(term) <= len(text); i++ {
+			if string(text[i:i+len(term)]) != string(term) {
+				continue
+			}
+			for j := i; j < i+len(term); j++ {
+				marks[j] = true
+			}
+			matched = true
+		}
+	}
+	return matched
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence is the subset of an iCalendar RRULE (RFC 5545) supported for recurring
+// tasks: FREQ=DAILY|WEEKLY|MONTHLY with INTERVAL, BYDAY (weekly only), COUNT and UNTIL.
+// Occurrences are whole days.
+type Recurrence struct {
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday
+	Count    int
+	Until    *time.Time
+}
+
+// maxRecurrenceScanDays bounds how far occurrences are searched for
+const maxRecurrenceScanDays = 3660
+
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// ParseRRule parses a rule such as "FREQ=WEEKLY;BYDAY=SA;COUNT=10". A leading "RRULE:"
+// is accepted and unsupported parts are rejected rather than ignored.
+func ParseRRule(rule string) (*Recurrence, error) {
+	rule = strings.TrimPrefix(strings.TrimSpace(strings.ToUpper(rule)), "RRULE:")
+	if rule == "" {
+		return nil, fmt.Errorf("重复规则不能为空")
+	}
+
+	r := &Recurrence{Interval: 1}
+	for _, part := range strings.Split(rule, ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("无法解析的重复规则: %s", part)
+		}
+		key, value := kv[0], kv[1]
+		switch key {
+		case "FREQ":
+			if value != "DAILY" && value != "WEEKLY" && value != "MONTHLY" {
+				return nil, fmt.Errorf("仅支持按天、按周或按月重复")
+			}
+			r.Freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("INTERVAL必须是正整数")
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("COUNT必须是正整数")
+			}
+			r.Count = n
+		case "UNTIL":
+			layout := "20060102"
+			if len(value) > len(layout) {
+				layout = "20060102T150405Z"
+			}
+			until, err := time.Parse(layout, value)
+			if err != nil {
+				return nil, fmt.Errorf("UNTIL格式不正确")
+			}
+			r.Until = &until
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := rruleWeekdays[d]
+				if !ok {
+					return nil, fmt.Errorf("无法识别的星期: %s", d)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		default:
+			return nil, fmt.Errorf("不支持的重复规则: %s", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("重复规则缺少FREQ")
+	}
+	if len(r.ByDay) > 0 && r.Freq != "WEEKLY" {
+		return nil, fmt.Errorf("BYDAY仅支持按周重复")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return nil, fmt.Errorf("COUNT和UNTIL不能同时使用")
+	}
+	return r, nil
+}
+
+// matches reports whether the day is an occurrence of a series starting on dtstart,
+// ignoring COUNT and UNTIL
+func (r *Recurrence) matches(dtstart, day time.Time) bool {
+	days := int(day.Sub(dtstart).Hours()/24 + 0.5)
+	switch r.Freq {
+	case "DAILY":
+		return days%r.Interval == 0
+	case "WEEKLY":
+		weekdays := r.ByDay
+		if len(weekdays) == 0 {
+			weekdays = []time.Weekday{dtstart.Weekday()}
+		}
+		found := false
+		for _, wd := range weekdays {
+			if day.Weekday() == wd {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+		// 按周一开始的自然周计算间隔
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		return ((days+offset)/7)%r.Interval == 0
+	default:
+		months := (day.Year()-dtstart.Year())*12 + int(day.Month()-dtstart.Month())
+		return day.Day() == dtstart.Day() && months%r.Interval == 0
+	}
+}
+
+// Occurrences returns the occurrence dates of a series starting on dtstart, up to and
+// including the through date. The result is at most COUNT long and ends at UNTIL.
+func (r *Recurrence) Occurrences(dtstart, through time.Time) []time.Time {
+	y, m, d := dtstart.Date()
+	dtstart = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	y, m, d = through.Date()
+	through = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	if r.Until != nil {
+		y, m, d = r.Until.Date()
+		if until := time.Date(y, m, d, 0, 0, 0, 0, time.UTC); until.Before(through) {
+			through = until
+		}
+	}
+
+	occurrences := make([]time.Time, 0)
+	for i, day := 0, dtstart; !day.After(through) && i < maxRecurrenceScanDays; i, day = i+1, day.AddDate(0, 0, 1) {
+		if !r.matches(dtstart, day) {
+			continue
+		}
+		occurrences = append(occurrences, day)
+		if r.Count > 0 && len(occurrences) >= r.Count {
+			break
+		}
+	}
+	return occurrences
+}
+
+// Finite reports whether the series ends on its own through COUNT or UNTIL
+func (r *Recurrence) Finite() bool {
+	return r.Count > 0 || r.Until != nil
+}
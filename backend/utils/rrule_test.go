@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		wantErr bool
+		check   func(*Recurrence) bool
+	}{
+		{rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=SA", check: func(r *Recurrence) bool {
+			return r.Freq == "WEEKLY" && r.Interval == 2 && len(r.ByDay) == 1 && r.ByDay[0] == time.Saturday
+		}},
+		{rule: "rrule:freq=daily;count=3", check: func(r *Recurrence) bool {
+			return r.Freq == "DAILY" && r.Interval == 1 && r.Count == 3 && r.Finite()
+		}},
+		{rule: "FREQ=MONTHLY;UNTIL=20260630", check: func(r *Recurrence) bool {
+			return r.Until != nil && r.Until.Equal(date("2026-06-30")) && r.Finite()
+		}},
+		{rule: "FREQ=DAILY;UNTIL=20260630T120000Z", check: func(r *Recurrence) bool {
+			return r.Until != nil && r.Until.Equal(time.Date(2026, 6, 30, 12, 0, 0, 0, time.UTC))
+		}},
+		{rule: "FREQ=WEEKLY", check: func(r *Recurrence) bool { return !r.Finite() }},
+		{rule: "", wantErr: true},
+		{rule: "INTERVAL=2", wantErr: true},
+		{rule: "FREQ=YEARLY", wantErr: true},
+		{rule: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=-1", wantErr: true},
+		{rule: "FREQ=DAILY;UNTIL=2026-06-30", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{rule: "FREQ=DAILY;BYDAY=MO", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=3;UNTIL=20260630", wantErr: true},
+		{rule: "FREQ=DAILY;BYMONTH=1", wantErr: true},
+		{rule: "FREQ", wantErr: true},
+	}
+	for _, tt := range tests {
+		r, err := ParseRRule(tt.rule)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseRRule(%q) expected an error", tt.rule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRRule(%q) unexpected error: %v", tt.rule, err)
+			continue
+		}
+		if !tt.check(r) {
+			t.Errorf("ParseRRule(%q) = %+v", tt.rule, r)
+		}
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart string
+		through string
+		want    []string
+	}{
+		{
+			name:    "daily with interval",
+			rule:    "FREQ=DAILY;INTERVAL=3",
+			dtstart: "2026-01-01",
+			through: "2026-01-10",
+			want:    []string{"2026-01-01", "2026-01-04", "2026-01-07", "2026-01-10"},
+		},
+		{
+			name:    "every other saturday",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;BYDAY=SA",
+			dtstart: "2026-01-03",
+			through: "2026-02-14",
+			want:    []string{"2026-01-03", "2026-01-17", "2026-01-31", "2026-02-14"},
+		},
+		{
+			name:    "every other weekend counts weeks from monday",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;BYDAY=SA,SU",
+			dtstart: "2026-01-03",
+			through: "2026-01-31",
+			want:    []string{"2026-01-03", "2026-01-04", "2026-01-17", "2026-01-18", "2026-01-31"},
+		},
+		{
+			name:    "weekly starting mid week",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+			dtstart: "2026-01-07",
+			through: "2026-01-26",
+			want:    []string{"2026-01-09", "2026-01-19", "2026-01-23"},
+		},
+		{
+			name:    "weekly defaults to start weekday",
+			rule:    "FREQ=WEEKLY",
+			dtstart: "2026-01-06",
+			through: "2026-01-27",
+			want:    []string{"2026-01-06", "2026-01-13", "2026-01-20", "2026-01-27"},
+		},
+		{
+			name:    "count stops the series",
+			rule:    "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=3",
+			dtstart: "2026-01-06",
+			through: "2026-03-01",
+			want:    []string{"2026-01-06", "2026-01-08", "2026-01-13"},
+		},
+		{
+			name:    "until is inclusive",
+			rule:    "FREQ=DAILY;INTERVAL=2;UNTIL=20260107",
+			dtstart: "2026-01-01",
+			through: "2026-02-01",
+			want:    []string{"2026-01-01", "2026-01-03", "2026-01-05", "2026-01-07"},
+		},
+		{
+			name:    "until with time keeps the whole day",
+			rule:    "FREQ=DAILY;UNTIL=20260103T080000Z",
+			dtstart: "2026-01-01",
+			through: "2026-02-01",
+			want:    []string{"2026-01-01", "2026-01-02", "2026-01-03"},
+		},
+		{
+			name:    "monthly on the start day",
+			rule:    "FREQ=MONTHLY",
+			dtstart: "2026-01-15",
+			through: "2026-04-14",
+			want:    []string{"2026-01-15", "2026-02-15", "2026-03-15"},
+		},
+		{
+			name:    "month end start skips short months",
+			rule:    "FREQ=MONTHLY",
+			dtstart: "2026-01-31",
+			through: "2026-08-31",
+			want:    []string{"2026-01-31", "2026-03-31", "2026-05-31", "2026-07-31", "2026-08-31"},
+		},
+		{
+			name:    "leap day start recurs only in leap years",
+			rule:    "FREQ=MONTHLY;INTERVAL=12",
+			dtstart: "2024-02-29",
+			through: "2028-03-01",
+			want:    []string{"2024-02-29", "2028-02-29"},
+		},
+		{
+			name:    "monthly interval across a year end",
+			rule:    "FREQ=MONTHLY;INTERVAL=2",
+			dtstart: "2025-11-30",
+			through: "2026-05-31",
+			want:    []string{"2025-11-30", "2026-01-30", "2026-03-30", "2026-05-30"},
+		},
+		{
+			name:    "through before start",
+			rule:    "FREQ=DAILY",
+			dtstart: "2026-01-10",
+			through: "2026-01-09",
+			want:    []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRRule(%q): %v", tt.rule, err)
+			}
+			got := r.Occurrences(date(tt.dtstart), date(tt.through))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences %v, want %v", len(got), got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(date(tt.want[i])) {
+					t.Errorf("occurrence %d = %s, want %s", i, got[i].Format("2006-01-02"), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOccurrencesIgnoresTimeOfDay(t *testing.T) {
+	r, err := ParseRRule("FREQ=DAILY;COUNT=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 1, 18, 30, 0, 0, time.FixedZone("CST", 8*3600))
+	got := r.Occurrences(start, start.AddDate(0, 0, 5))
+	if len(got) != 2 || !got[0].Equal(date("2026-03-01")) || !got[1].Equal(date("2026-03-02")) {
+		t.Errorf("got %v", got)
+	}
+}